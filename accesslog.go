@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"classroom-api/client"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+)
+
+// requestIDHeader はリクエストIDを受け渡すヘッダーです。
+// サーバーはこのヘッダーでリクエストIDを返し、同じIDをClassroom APIの呼び出しにも付けます。
+const requestIDHeader = client.RequestIDHeader
+
+// validRequestID はクライアントから受け取ったリクエストIDとして使える形式です。
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// withRequestID は ctx にリクエストIDを付けます。APIクライアントはこのIDをヘッダーに付けて呼び出します。
+func withRequestID(ctx context.Context, id string) context.Context {
+	return client.WithRequestID(ctx, id)
+}
+
+// requestID は ctx のリクエストIDを返します。なければ空です。
+func requestID(ctx context.Context) string {
+	return client.RequestID(ctx)
+}
+
+// newRequestID は新しいリクエストIDを作ります。
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder はアクセスログのためにレスポンスの状態とサイズを記録します。
+// /events と /ws のために http.Flusher と http.Hijacker も実装します。
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("接続を引き継げません")
+	}
+	// WebSocketに切り替わったリクエストは 101 として記録する
+	w.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// accessLogMiddleware はリクエストにIDを付け、cfg.Server.AccessLog が true の場合は
+// 1リクエストにつき1行のJSONを標準エラー出力に書きます。
+func (s *server) accessLogMiddleware(next http.Handler) http.Handler {
+	logger := newAccessLogger(s.cfg)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		r = r.WithContext(withRequestID(r.Context(), id))
+		if logger == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("id", id),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", max(rec.status, http.StatusOK)),
+			slog.Int("bytes", rec.bytes),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("user", s.requestUser(r)),
+			slog.String("remote", r.RemoteAddr),
+		)
+	})
+}
+
+// newAccessLogger はアクセスログを書くロガーを返します。cfg.Server.AccessLog が false の場合は nil です。
+func newAccessLogger(cfg *Config) *slog.Logger {
+	if !cfg.Server.AccessLog {
+		return nil
+	}
+	return slog.New(slog.NewJSONHandler(os.Stderr, nil))
+}
+
+// requestUser はアクセスログに書くリクエストの利用者です。
+// 複数ユーザーモードのセッションならメールアドレス、APIキーなら "key:" とキーの名前、どちらでもなければ空です。
+func (s *server) requestUser(r *http.Request) string {
+	if u := s.sessionUser(r); u != nil {
+		return u.Email
+	}
+	if token := requestAPIKey(r); token != "" {
+		if k := s.keys.lookup(token, time.Now()); k != nil {
+			return "key:" + k.Name
+		}
+	}
+	return ""
+}
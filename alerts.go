@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// remindersStateFile は送ったリマインダーと毎日のまとめを記録するファイルです。再起動しても同じ通知を送り直さないために使います。
+const remindersStateFile = "reminders.json"
+
+// alerter は締切の前のリマインダー (due_soon) と毎日のまとめ (daily_digest) を送る時期を決めます。
+// serve (server.notify が有効な場合)、watch、daemon が課題を取得するたびに呼び出します。
+type alerter struct {
+	cfg *Config
+	// offsets は締切のどれだけ前にリマインダーを送るかです。コースごとの設定がある場合はそちらを使います。
+	offsets []time.Duration
+	// digestAt は0時からまとめを送る時刻までの時間です。負の場合はまとめを送りません。
+	digestAt time.Duration
+	// path は状態を保存するファイルです。空の場合は保存しません。
+	path  string
+	state alertState
+}
+
+// alertState は送った通知の記録です。
+type alertState struct {
+	// Sent は課題ごとに送ったリマインダーです。
+	Sent map[string]*sentReminders `json:"sent"`
+	// Digested は最後にまとめを送った日 (2006-01-02) です。
+	Digested string `json:"digested,omitempty"`
+}
+
+// sentReminders は1つの課題について送ったリマインダーです。締切が変わった場合は記録を消して送り直します。
+type sentReminders struct {
+	Due     string     `json:"due"`
+	Offsets []Duration `json:"offsets"`
+}
+
+// newAlerter は設定から alerter を作ります。リマインダーもまとめも設定されていない場合は nil を返します。
+// stateFile は DataDir の中の状態を保存するファイルの名前で、空の場合は状態を保存しません。
+func newAlerter(cfg *Config, stateFile string) (*alerter, error) {
+	nc := cfg.Notify
+	al := &alerter{cfg: cfg, digestAt: -1, state: alertState{Sent: map[string]*sentReminders{}}}
+	offsets := nc.Reminders
+	if nc.DueSoon > 0 {
+		offsets = append(slices.Clone(offsets), nc.DueSoon)
+	}
+	var err error
+	if al.offsets, err = reminderOffsets(offsets); err != nil {
+		return nil, fmt.Errorf("notify.reminders: %w", err)
+	}
+	enabled := len(al.offsets) > 0
+	for id, cc := range cfg.Courses {
+		if cc == nil || len(cc.Reminders) == 0 {
+			continue
+		}
+		if _, err := reminderOffsets(cc.Reminders); err != nil {
+			return nil, fmt.Errorf("courses.%s.reminders: %w", id, err)
+		}
+		enabled = true
+	}
+	if nc.DailyDigest != "" {
+		t, err := time.Parse("15:04", nc.DailyDigest)
+		if err != nil {
+			return nil, fmt.Errorf("notify.dailyDigest の時刻を解析できませんでした: %s", nc.DailyDigest)
+		}
+		al.digestAt = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if !enabled && al.digestAt < 0 {
+		return nil, nil
+	}
+	if stateFile != "" {
+		al.path = cfg.dataPath(stateFile)
+		if err := readJSONFile(al.path, &al.state); err != nil {
+			return nil, fmt.Errorf("%s を読み取れませんでした: %w", stateFile, err)
+		}
+		if al.state.Sent == nil {
+			al.state.Sent = map[string]*sentReminders{}
+		}
+	}
+	return al, nil
+}
+
+// reminderOffsets は設定のリマインダーの時間を重複のない長い順に並べます。
+func reminderOffsets(ds []Duration) ([]time.Duration, error) {
+	var offsets []time.Duration
+	for _, d := range ds {
+		if d <= 0 {
+			return nil, fmt.Errorf("締切の前の時間は正の値にしてください: %s", time.Duration(d))
+		}
+		if !slices.Contains(offsets, time.Duration(d)) {
+			offsets = append(offsets, time.Duration(d))
+		}
+	}
+	slices.SortFunc(offsets, func(a, b time.Duration) int { return int(b - a) })
+	return offsets, nil
+}
+
+// courseOffsets はコースの課題にリマインダーを送る時間を返します。
+func (al *alerter) courseOffsets(courseID string) []time.Duration {
+	if cc := al.cfg.course(courseID); len(cc.Reminders) > 0 {
+		// 設定の誤りは newAlerter で確かめている
+		offsets, _ := reminderOffsets(cc.Reminders)
+		return offsets
+	}
+	return al.offsets
+}
+
+// alertEvent は送る前の通知です。record は送れたときに送ったことを記録します。
+type alertEvent struct {
+	*Event
+	record func()
+}
+
+// deliver は items から今送るべき通知を ns に送り、すべての送信先に送れた通知だけを記録して保存します。
+// 送れなかった通知は記録しないため、次に課題を取得したときに送り直します。
+func (al *alerter) deliver(ctx context.Context, ns []Notifier, items []*Assignment, now time.Time) {
+	events, changed := al.events(items, now)
+	for _, ev := range events {
+		if err := notifyAll(ctx, ns, ev.Event); err != nil {
+			continue
+		}
+		ev.record()
+		changed = true
+	}
+	if changed {
+		al.save()
+	}
+}
+
+// events は items から今送るべき通知を返します。送ったことは返した通知の record で記録します。
+// スヌーズ中の課題はリマインダーもまとめも送らず、スヌーズが明けてから送ります。
+// changed は締切を過ぎた課題の記録を消したかどうかです。
+func (al *alerter) events(items []*Assignment, now time.Time) (events []alertEvent, changed bool) {
+	meta := loadSnoozes(al.cfg)
+	pending := slices.DeleteFunc(pendingAssignments(items, now), func(a *Assignment) bool {
+		return meta.snoozed(a, now)
+	})
+	for _, a := range pending {
+		due, ok := a.Due()
+		if !ok || a.External() || due.Before(now) {
+			continue
+		}
+		if ev, ok := al.reminder(a, due, now); ok {
+			events = append(events, ev)
+		}
+	}
+	// 締切を過ぎた課題の記録はもう使わないため消す
+	for key, s := range al.state.Sent {
+		if due, err := time.Parse(time.RFC3339, s.Due); err != nil || due.Before(now) {
+			delete(al.state.Sent, key)
+			changed = true
+		}
+	}
+	if ev, ok := al.digest(pending, now); ok {
+		events = append(events, ev)
+	}
+	return events, changed
+}
+
+// reminder は締切までの時間がまだ送っていないリマインダーの時間を切った場合に通知を返します。
+// 同時に複数の時間を切った場合 (締切の近い課題が出された場合など) は、最も短い時間の1件だけを送ります。
+func (al *alerter) reminder(a *Assignment, due, now time.Time) (alertEvent, bool) {
+	key := assignmentKey(a)
+	s := al.state.Sent[key]
+	if s == nil || s.Due != dueString(a.CourseWork) {
+		s = &sentReminders{Due: dueString(a.CourseWork)}
+	}
+	var crossed []time.Duration
+	for _, o := range al.courseOffsets(a.Course.Id) {
+		if due.Sub(now) <= o && !slices.Contains(s.Offsets, Duration(o)) {
+			crossed = append(crossed, o)
+		}
+	}
+	if len(crossed) == 0 {
+		return alertEvent{}, false
+	}
+	record := func() {
+		for _, o := range crossed {
+			s.Offsets = append(s.Offsets, Duration(o))
+		}
+		al.state.Sent[key] = s
+	}
+	return alertEvent{record: record, Event: &Event{
+		Type:       "due_soon",
+		Assignment: a,
+		Key:        fmt.Sprintf("due_soon/%s/%s/%s", key, s.Due, crossed[len(crossed)-1]),
+		Title:      fmt.Sprintf("締切が近い課題: %s", a.CourseWork.Title),
+		Body:       fmt.Sprintf("%s\n締切: %s (%s)\n%s", a.Course.Name, formatDue(a), formatRemaining(due.Sub(now)), a.CourseWork.AlternateLink),
+		Time:       now,
+	}}, true
+}
+
+// digest はその日のまとめを送る時刻を過ぎていて、まだ送っていない場合にまとめを返します。
+func (al *alerter) digest(pending []*Assignment, now time.Time) (alertEvent, bool) {
+	if al.digestAt < 0 {
+		return alertEvent{}, false
+	}
+	y, m, d := now.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	if now.Before(day.Add(al.digestAt)) || al.state.Digested == day.Format("2006-01-02") {
+		return alertEvent{}, false
+	}
+	var lines []string
+	var items []*Assignment
+	for _, a := range pending {
+		if a.External() {
+			continue
+		}
+		items = append(items, a)
+		line := fmt.Sprintf("・%s「%s」締切: %s", a.Course.Name, a.CourseWork.Title, formatDue(a))
+		if due, ok := a.Due(); ok {
+			line += " (" + formatRemaining(due.Sub(now)) + ")"
+		}
+		lines = append(lines, line)
+	}
+	body := "未提出の課題はありません。"
+	if len(lines) > 0 {
+		body = strings.Join(lines, "\n")
+	}
+	record := func() { al.state.Digested = day.Format("2006-01-02") }
+	return alertEvent{record: record, Event: &Event{
+		Type:  "daily_digest",
+		Items: items,
+		Title: fmt.Sprintf("%s の未提出の課題 (%d件)", now.Format("1/2"), len(lines)),
+		Body:  body,
+		Time:  now,
+	}}, true
+}
+
+// save は状態をファイルに保存します。保存できなくても通知は送れるため、ログに書くだけにします。
+func (al *alerter) save() {
+	if al.path == "" || al.cfg.dryRun {
+		return
+	}
+	if err := writeJSONFile(al.path, &al.state); err != nil {
+		log.Printf("リマインダーの状態を保存できませんでした: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/api/classroom/v1"
+	"testing"
+	"time"
+)
+
+func TestAlerterDeliverRetry(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir()}
+	cfg.Notify.DueSoon = Duration(24 * time.Hour)
+	cfg.Notify.DailyDigest = "7:00"
+	al, err := newAlerter(cfg, remindersStateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	course := &classroom.Course{Id: "c1", Name: "数学"}
+	work := &classroom.CourseWork{Id: "w1", CourseId: "c1", Title: "課題", WorkType: "ASSIGNMENT", DueDate: &classroom.Date{Year: 2026, Month: 10, Day: 16}}
+	items := []*Assignment{{Course: course, CourseWork: work}}
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+
+	rec := &flakyNotifier{err: errors.New("失敗")}
+	al.deliver(ctx, []Notifier{rec}, items, now)
+	// 送れなかったリマインダーとまとめは記録せず、次の取得で送り直す
+	rec.err = nil
+	al.deliver(ctx, []Notifier{rec}, items, now.Add(time.Minute))
+	if len(rec.events) != 2 {
+		t.Fatalf("送り直した通知 = %v, want リマインダーとまとめ", eventTitles(rec.events))
+	}
+	// 送れた通知は再起動しても送らない
+	al, err = newAlerter(cfg, remindersStateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	al.deliver(ctx, []Notifier{rec}, items, now.Add(2*time.Minute))
+	if len(rec.events) != 2 {
+		t.Errorf("送った通知をもう一度送りました: %v", eventTitles(rec.events[2:]))
+	}
+}
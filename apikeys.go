@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// apiKeysFile はローカルAPIのAPIキーを記録するファイルです。キーそのものは保存せず、ハッシュだけを保存します。
+const apiKeysFile = "apikeys.json"
+
+// apiScopes はAPIキーに与えられる権限とその説明です。
+var apiScopes = map[string]string{
+	"count":      "未提出の課題の件数だけ (バッジ向け)",
+	"titles":     "未提出の課題のタイトルと締切、コースの一覧",
+	"coursework": "未提出の課題のすべての項目",
+	"calendar":   "締切のiCalendarフィード (/calendar.ics) だけ (カレンダーアプリの購読URL向け)",
+	"keys":       "APIキーの発行",
+	"debug":      "プロファイルの取得 (/debug/pprof)",
+}
+
+// scopeImplies は上位の権限が含む権限です。
+var scopeImplies = map[string][]string{
+	"coursework": {"titles", "count", "calendar"},
+	"titles":     {"count"},
+}
+
+// apiKey は発行したAPIキーです。
+type apiKey struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Hash    string    `json:"hash"`
+	Scopes  []string  `json:"scopes"`
+	Created time.Time `json:"created"`
+	// Expires は有効期限です。ゼロ値の場合は期限がありません。
+	Expires time.Time `json:"expires,omitempty"`
+	// User は複数ユーザーモードでキーが課題を返すユーザー (IDまたはメールアドレス) です。
+	User string `json:"user,omitempty"`
+}
+
+// allows はキーが scope の権限を持つかどうかを返します。
+func (k *apiKey) allows(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || contains(scopeImplies[s], scope) {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *apiKey) expired(now time.Time) bool {
+	return !k.Expires.IsZero() && !now.Before(k.Expires)
+}
+
+// ConfigAPIKey は設定ファイルの server.apiKeys に書くAPIキーです。
+type ConfigAPIKey struct {
+	// Name はキーの用途です (例: "react-frontend")。
+	Name string `json:"name"`
+	// Key はキーの文字列です。推測されないよう16文字以上にしてください。
+	Key string `json:"key"`
+	// Scopes は権限です。省略した場合は "coursework" です。
+	Scopes []string `json:"scopes,omitempty"`
+	// User は複数ユーザーモードでキーが課題を返すユーザー (IDまたはメールアドレス) です。
+	User string `json:"user,omitempty"`
+}
+
+// minConfigAPIKeyLength は設定ファイルに書くAPIキーの最短の長さです。
+const minConfigAPIKeyLength = 16
+
+// apiKeyStore は apikeys.json に保存したAPIキーと、設定ファイルに書いたAPIキーです。
+type apiKeyStore struct {
+	path string
+	mu   sync.Mutex
+	Keys []*apiKey `json:"keys"`
+	// configured は設定ファイルのキーです。apikeys.json には保存しません。
+	configured []*apiKey
+}
+
+func loadAPIKeys(cfg *Config) (*apiKeyStore, error) {
+	s := &apiKeyStore{path: cfg.dataPath(apiKeysFile)}
+	if err := readJSONFile(s.path, s); err != nil {
+		return nil, fmt.Errorf("APIキーを読み込めませんでした: %w", err)
+	}
+	for i, ck := range cfg.Server.APIKeys {
+		k, err := ck.apiKey()
+		if err != nil {
+			return nil, fmt.Errorf("server.apiKeys[%d] (%s): %w", i, ck.Name, err)
+		}
+		s.configured = append(s.configured, k)
+	}
+	return s, nil
+}
+
+// apiKey は設定ファイルのキーを発行したキーと同じ形にします。キーの文字列はハッシュにして持ちます。
+func (ck *ConfigAPIKey) apiKey() (*apiKey, error) {
+	if len(ck.Key) < minConfigAPIKeyLength {
+		return nil, fmt.Errorf("キーは%d文字以上にしてください", minConfigAPIKeyLength)
+	}
+	scopes := ck.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"coursework"}
+	}
+	for _, sc := range scopes {
+		if _, ok := apiScopes[sc]; !ok {
+			return nil, fmt.Errorf("不明な権限です: %s", sc)
+		}
+	}
+	return &apiKey{ID: "config", Name: ck.Name, Hash: hashAPIKey(ck.Key), Scopes: scopes, User: ck.User}, nil
+}
+
+// requireAPIKey はAPIキーを必須にするかどうかを返します。
+func (c *ServerConfig) requireAPIKey() bool {
+	return c.RequireAPIKey || len(c.APIKeys) > 0
+}
+
+// mint は新しいキーを発行して保存し、キーの文字列を返します。キーの文字列は再表示できません。
+// user は複数ユーザーモードでキーを使うユーザーで、1人で使う場合は空です。
+func (s *apiKeyStore) mint(name, user string, scopes []string, ttl time.Duration, now time.Time) (string, *apiKey, error) {
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("権限を1つ以上指定してください")
+	}
+	for _, sc := range scopes {
+		if _, ok := apiScopes[sc]; !ok {
+			return "", nil, fmt.Errorf("不明な権限です: %s", sc)
+		}
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := "cra_" + hex.EncodeToString(b)
+	k := &apiKey{ID: token[4:12], Name: name, Hash: hashAPIKey(token), Scopes: scopes, Created: now, User: user}
+	if ttl > 0 {
+		k.Expires = now.Add(ttl)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Keys = append(s.Keys, k)
+	if err := writeJSONFile(s.path, s); err != nil {
+		return "", nil, fmt.Errorf("APIキーを保存できませんでした: %w", err)
+	}
+	return token, k, nil
+}
+
+// revoke はIDが id のキーを削除します。
+func (s *apiKeyStore) revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, k := range s.Keys {
+		if k.ID == id {
+			s.Keys = append(s.Keys[:i], s.Keys[i+1:]...)
+			return writeJSONFile(s.path, s)
+		}
+	}
+	return fmt.Errorf("APIキーが見つかりません: %s", id)
+}
+
+// all は設定ファイルのキーと発行したキーを返します。
+func (s *apiKeyStore) all() []*apiKey {
+	keys := make([]*apiKey, 0, len(s.configured)+len(s.Keys))
+	keys = append(keys, s.configured...)
+	return append(keys, s.Keys...)
+}
+
+// lookup は有効なキーを探します。
+func (s *apiKeyStore) lookup(token string, now time.Time) *apiKey {
+	h := hashAPIKey(token)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found *apiKey
+	// 一致するキーの位置が処理時間から分からないよう、見つかった後もすべてのキーと比べる
+	for _, k := range s.all() {
+		if subtle.ConstantTimeCompare([]byte(k.Hash), []byte(h)) == 1 && !k.expired(now) && found == nil {
+			found = k
+		}
+	}
+	return found
+}
+
+func hashAPIKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// requestAPIKey はリクエストのAPIキーを返します。
+// ヘッダーを設定できないウィジェットのために、クエリパラメーター key でも受け付けます。
+func requestAPIKey(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.URL.Query().Get("key")
+}
+
+// requireScope は scope の権限を持つAPIキーのリクエストだけをハンドラーに渡します。
+// server.requireApiKey が false で server.apiKeys もない場合と、複数ユーザーモードでログインしている場合は確認しません。
+func (s *server) requireScope(scope string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.checkScope(r, scope); err != nil {
+			err.write(w)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// scopeError は権限の確認に失敗した理由です。status はHTTPの状態です。
+type scopeError struct {
+	status    int
+	msg       string
+	challenge string
+}
+
+func (e *scopeError) write(w http.ResponseWriter) {
+	if e.status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", e.challenge)
+	}
+	http.Error(w, e.msg, e.status)
+}
+
+// checkScope はリクエストが scope の権限を持つかどうかを確かめます。
+func (s *server) checkScope(r *http.Request, scope string) *scopeError {
+	// 複数ユーザーモードでログインしたセッションは、自分の課題についてすべての権限を持つ
+	if !s.cfg.Server.requireAPIKey() || s.sessionUser(r) != nil {
+		return nil
+	}
+	return s.checkAPIKey(r, scope)
+}
+
+// checkAPIKey はリクエストのAPIキーが scope の権限を持つかどうかを、設定やセッションにかかわらず確かめます。
+func (s *server) checkAPIKey(r *http.Request, scope string) *scopeError {
+	token := requestAPIKey(r)
+	if token == "" {
+		return &scopeError{http.StatusUnauthorized, "APIキーが必要です", `Bearer realm="classroom-api"`}
+	}
+	k := s.keys.lookup(token, time.Now())
+	if k == nil {
+		return &scopeError{http.StatusUnauthorized, "APIキーが無効か期限切れです", `Bearer realm="classroom-api", error="invalid_token"`}
+	}
+	if !k.allows(scope) {
+		return &scopeError{status: http.StatusForbidden, msg: fmt.Sprintf("このAPIキーには %s の権限がありません", scope)}
+	}
+	return nil
+}
+
+func (s *server) handleMintKey(w http.ResponseWriter, r *http.Request) {
+	// キーを確認しない設定では、誰でもキーを発行できてしまうため受け付けない
+	if !s.cfg.Server.requireAPIKey() {
+		http.Error(w, "server.requireApiKey が無効で server.apiKeys もないためAPIキーを発行できません", http.StatusForbidden)
+		return
+	}
+	var req ApiMintKeyRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var user string
+	if s.users != nil {
+		// 複数ユーザーモードでは、キーを発行したユーザーの課題だけを返すキーにする
+		u := s.sessionUser(r)
+		if u == nil {
+			http.Error(w, "ログインしたユーザーだけがAPIキーを発行できます", http.StatusForbidden)
+			return
+		}
+		user = u.ID
+	}
+	if status, msg := s.checkMintScopes(r, req.Scopes); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	token, k, err := s.keys.mint(req.Name, user, req.Scopes, time.Duration(req.Ttl), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusCreated, &ApiMintKeyResponse{Id: k.ID, Key: token, Scopes: k.Scopes, Expires: k.Expires})
+}
+
+// unmintableScopes はHTTPでは発行しない権限です。keys はキーを増やし続けられ、
+// debug (プロファイル) はすべてのユーザーの処理を含むため、keys create か server.apiKeys で作成します。
+var unmintableScopes = []string{"keys", "debug"}
+
+// checkMintScopes は POST /api/keys で発行を求められた権限を確かめます。
+// 呼び出したAPIキーが持たない権限のキーは発行しません。問題がなければ status は 0 です。
+func (s *server) checkMintScopes(r *http.Request, scopes []string) (status int, msg string) {
+	var caller *apiKey
+	if token := requestAPIKey(r); token != "" {
+		caller = s.keys.lookup(token, time.Now())
+	}
+	for _, sc := range scopes {
+		if slices.Contains(unmintableScopes, sc) {
+			return http.StatusForbidden, sc + " の権限は keys create か server.apiKeys で作成してください"
+		}
+		// 複数ユーザーモードでログインしたセッションは自分の課題についてすべての権限を持つ
+		if caller != nil && !caller.allows(sc) {
+			return http.StatusForbidden, fmt.Sprintf("このAPIキーには %s の権限がないため、%s の権限のキーは発行できません", sc, sc)
+		}
+	}
+	return 0, ""
+}
+
+const keysUsageText = `使い方: classroom-api keys <サブコマンド> [引数]
+
+サブコマンド:
+  create -name 名前 -scope 権限 [-ttl 期間] [-user メール]   APIキーを発行します
+  list                                                      発行したAPIキーを一覧表示します
+  revoke <ID>                                               APIキーを無効にします
+
+権限:
+`
+
+func runKeys(ctx context.Context, cfg *Config, args []string) {
+	if len(args) == 0 {
+		printKeysUsage()
+		os.Exit(2)
+	}
+	store, err := loadAPIKeys(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("keys create", flag.ExitOnError)
+		name := fs.String("name", "", "キーの用途 (例: 玄関のディスプレイ)")
+		scope := fs.String("scope", "count", "カンマ区切りの権限")
+		ttl := fs.Duration("ttl", 0, "有効期間 (例: 720h)。0 の場合は期限なし")
+		user := fs.String("user", "", "複数ユーザーモードでキーが課題を返すユーザーのメールアドレス")
+		fs.Parse(args[1:])
+		token, k, err := store.mint(*name, *user, splitList(*scope), *ttl, time.Now())
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(token)
+		fmt.Fprintf(os.Stderr, "ID %s のAPIキーを発行しました。このキーは再表示できません。\n", k.ID)
+	case "list":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\t名前\t権限\t有効期限\tユーザー")
+		now := time.Now()
+		for _, k := range store.all() {
+			expires := "なし"
+			if !k.Expires.IsZero() {
+				expires = formatTime(k.Expires)
+				if k.expired(now) {
+					expires += " (期限切れ)"
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, strings.Join(k.Scopes, ","), expires, k.User)
+		}
+		tw.Flush()
+	case "revoke":
+		if len(args) != 2 {
+			log.Fatal("無効にするAPIキーのIDを指定してください")
+		}
+		if err := store.revoke(args[1]); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "不明なサブコマンドです: %s\n\n", args[0])
+		printKeysUsage()
+		os.Exit(2)
+	}
+}
+
+func printKeysUsage() {
+	fmt.Fprint(os.Stderr, keysUsageText)
+	var names []string
+	for name := range apiScopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-11s %s\n", name, apiScopes[name])
+	}
+}
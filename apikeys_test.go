@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMintKeyScopes(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir()}
+	cfg.Server.APIKeys = []ConfigAPIKey{
+		{Name: "admin", Key: "keys-only-0123456789", Scopes: []string{"keys"}},
+		{Name: "full", Key: "keys-coursework-0123456789", Scopes: []string{"keys", "coursework"}},
+	}
+	keys, err := loadAPIKeys(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{cfg: cfg, keys: keys}
+	tests := []struct {
+		key, body string
+		want      int
+	}{
+		{"keys-only-0123456789", `{"name":"x","scopes":["coursework"]}`, http.StatusForbidden},
+		{"keys-only-0123456789", `{"name":"x","scopes":["keys"]}`, http.StatusForbidden},
+		{"keys-coursework-0123456789", `{"name":"x","scopes":["debug"]}`, http.StatusForbidden},
+		// coursework は titles と count を含む
+		{"keys-coursework-0123456789", `{"name":"x","scopes":["titles"]}`, http.StatusCreated},
+		{"keys-coursework-0123456789", `{"name":"x","scopes":["coursework"]}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/api/keys", strings.NewReader(tt.body))
+		r.Header.Set("Authorization", "Bearer "+tt.key)
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		s.handleMintKey(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s で %s: status = %d, want %d (%s)", tt.key, tt.body, w.Code, tt.want, strings.TrimSpace(w.Body.String()))
+		}
+	}
+}
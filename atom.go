@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// atomFeed はAtom (RFC 4287) のフィードです。
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID         string    `xml:"id"`
+	Title      string    `xml:"title"`
+	Updated    string    `xml:"updated"`
+	Published  string    `xml:"published,omitempty"`
+	Link       atomLink  `xml:"link"`
+	Categories []atomCat `xml:"category"`
+	Summary    atomText  `xml:"summary"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomCat struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func runExportAtom(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export atom", flag.ExitOnError)
+	out := fs.String("out", "", "出力ファイル (省略時は標準出力)")
+	recent := fs.Duration("recent", 7*24*time.Hour, "未提出の課題に加えて、この期間に投稿された課題も載せます")
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	now := time.Now()
+	var entries []*Assignment
+	for _, a := range filter.apply(items) {
+		if isCourseworkVisible(a, now) || postedWithin(a, now, *recent) {
+			entries = append(entries, a)
+		}
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("出力ファイルを作成できませんでした: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeAtom(w, entries, now); err != nil {
+		log.Fatalf("フィードを書き込めませんでした: %v", err)
+	}
+}
+
+// postedWithin は課題が now から d 以内に投稿されたかどうかを返します。
+func postedWithin(a *Assignment, now time.Time, d time.Duration) bool {
+	t, err := time.Parse(time.RFC3339, a.CourseWork.CreationTime)
+	return err == nil && now.Sub(t) <= d
+}
+
+// writeAtom は課題をAtomのフィードとして書き出します。
+// エントリーのIDには課題のIDと更新日時を使うため、課題が更新されるとフィードリーダーで新しいエントリーになります。
+func writeAtom(w io.Writer, items []*Assignment, now time.Time) error {
+	feed := &atomFeed{
+		ID:     "urn:classroom-api:feed",
+		Title:  "Classroom の課題",
+		Author: atomPerson{Name: "Google Classroom"},
+	}
+	var latest time.Time
+	for _, a := range items {
+		c := a.CourseWork
+		updated, err := time.Parse(time.RFC3339, c.UpdateTime)
+		if err != nil {
+			updated = now
+		}
+		if updated.After(latest) {
+			latest = updated
+		}
+		e := atomEntry{
+			ID:         fmt.Sprintf("urn:classroom-api:coursework:%s:%s", c.Id, c.UpdateTime),
+			Title:      fmt.Sprintf("[%s] %s", a.Course.Name, c.Title),
+			Updated:    updated.UTC().Format(time.RFC3339),
+			Link:       atomLink{Href: c.AlternateLink, Rel: "alternate"},
+			Categories: []atomCat{{Term: a.Course.Name}, {Term: workTypeLabel(c.WorkType)}},
+			Summary:    atomText{Type: "text", Body: icalDescription(a)},
+		}
+		if due, ok := a.EffectiveDue(); ok {
+			e.Title += " (締切 " + due.Format("1/2 15:04") + ")"
+		}
+		if t, err := time.Parse(time.RFC3339, c.CreationTime); err == nil {
+			e.Published = t.UTC().Format(time.RFC3339)
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+	if latest.IsZero() {
+		latest = now
+	}
+	feed.Updated = latest.UTC().Format(time.RFC3339)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
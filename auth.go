@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/classroom/v1"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+	"google.golang.org/api/sheets/v4"
+	"google.golang.org/api/tasks/v1"
+	"log"
+	"net/http"
+	"os"
+)
+
+// scopes はアプリケーションが要求するOAuthスコープです。
+// これらのスコープを変更する場合、以前に保存した token.json を削除してください。
+var scopes = []string{
+	classroom.ClassroomCoursesReadonlyScope,
+	classroom.ClassroomCourseworkMeReadonlyScope,
+	classroom.ClassroomCourseworkStudentsReadonlyScope,
+	classroom.ClassroomCourseworkmaterialsReadonlyScope,
+	classroom.ClassroomTopicsReadonlyScope,
+	classroom.ClassroomPushNotificationsScope,
+	pubsub.PubsubScope,
+	drive.DriveReadonlyScope,
+	drive.DriveAppdataScope,
+	calendar.CalendarScope,
+	tasks.TasksScope,
+	sheets.SpreadsheetsScope,
+	gmail.GmailSendScope,
+}
+
+// oauthConfig は資格情報ファイルからOAuthの設定を読み込みます。
+func oauthConfig(cfg *Config) (*oauth2.Config, error) {
+	return oauthConfigWithScopes(cfg.CredentialsFile, scopes...)
+}
+
+// oauthConfigWithScopes は資格情報ファイルから指定したスコープのOAuthの設定を読み込みます。
+func oauthConfigWithScopes(credentialsFile string, scopes ...string) (*oauth2.Config, error) {
+	b, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("資格情報ファイルを読み取れませんでした: %w", err)
+	}
+	config, err := google.ConfigFromJSON(b, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("クライアントシークレットファイルを構成に解析できませんでした: %w", err)
+	}
+	return config, nil
+}
+
+// newHTTPClient は認証済みのHTTPクライアントを返します。
+// オフラインモードではすべてのリクエストが失敗するクライアントを返します。
+func newHTTPClient(cfg *Config) *http.Client {
+	if cfg.Offline {
+		return &http.Client{Transport: offlineTransport}
+	}
+	config, err := oauthConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := getClient(config, cfg.TokenFile)
+	client.Transport = newClientTransport(client.Transport, cfg.Client)
+	return client
+}
+
+// savedHTTPClient は保存済みのトークンで認証したHTTPクライアントを返します。
+// newHTTPClient と異なり、トークンがない場合もブラウザでの認証を始めずにエラーを返します。
+func savedHTTPClient(cfg *Config) (*http.Client, error) {
+	if cfg.Offline {
+		return &http.Client{Transport: offlineTransport}, nil
+	}
+	config, err := oauthConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := tokenFromFile(cfg.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("トークンを読み取れませんでした: %w", err)
+	}
+	client := config.Client(context.Background(), tok)
+	client.Transport = newClientTransport(client.Transport, cfg.Client)
+	return client, nil
+}
+
+// newClassroomService は認証済みのClassroomクライアントを返します。
+func newClassroomService(ctx context.Context, client *http.Client) *classroom.Service {
+	srv, err := classroom.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		log.Fatalf("Classroomクライアントを作成できませんでした: %v", err)
+	}
+	return srv
+}
+
+// トークンを取得し、トークンを保存して、生成されたクライアントを返します。
+func getClient(config *oauth2.Config, tokFile string) *http.Client {
+	// トークンファイルには、ユーザーのアクセスおよびリフレッシュトークンが保存されます。
+	// これは、認証フローが初めて完了したときに自動的に作成されます。
+	tok, err := tokenFromFile(tokFile)
+	if err != nil {
+		tok = getTokenFromWeb(config)
+		saveToken(tokFile, tok)
+	}
+	return config.Client(context.Background(), tok)
+}
+
+// Webからトークンをリクエストし、取得したトークンを返します。
+func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
+	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
+	fmt.Printf("ブラウザで次のリンクにアクセスし、認証コードを入力してください: \n%v\n", authURL)
+
+	var authCode string
+	if _, err := fmt.Scan(&authCode); err != nil {
+		log.Fatalf("認証コードを読み取れませんでした: %v", err)
+	}
+
+	tok, err := config.Exchange(context.TODO(), authCode)
+	if err != nil {
+		log.Fatalf("Webからトークンを取得できませんでした: %v", err)
+	}
+	return tok
+}
+
+// ローカルファイルからトークンを取得します。
+func tokenFromFile(file string) (*oauth2.Token, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tok := &oauth2.Token{}
+	err = json.NewDecoder(f).Decode(tok)
+	return tok, err
+}
+
+// トークンをファイルパスに保存します。
+func saveToken(path string, token *oauth2.Token) {
+	fmt.Printf("資格情報ファイルを次の場所に保存しています: %s\n", path)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		log.Fatalf("OAuthトークンをキャッシュできませんでした: %v", err)
+	}
+	defer f.Close()
+	json.NewEncoder(f).Encode(token)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// boardTemplate は朝のホームルームなどで投影するための、文字の大きな締切表です。
+// PDFが必要な場合はブラウザの印刷機能でPDFとして保存します (印刷用のスタイルを含みます)。
+var boardTemplate = template.Must(template.New("board").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, "Hiragino Sans", "Yu Gothic UI", sans-serif; margin: 2rem 3rem; font-size: 1.6rem; color: #111; }
+h1 { font-size: 2.4rem; margin-bottom: .2rem; }
+.generated { color: #555; font-size: 1.1rem; }
+h2 { font-size: 1.9rem; border-bottom: 3px solid #333; margin-top: 2rem; }
+h2.today { color: #b00020; border-color: #b00020; }
+table { border-collapse: collapse; width: 100%; }
+td { padding: .4rem .8rem; border-bottom: 1px solid #ccc; vertical-align: top; }
+td.time { width: 5em; white-space: nowrap; font-variant-numeric: tabular-nums; }
+td.course { width: 30%; color: #333; }
+.empty { color: #555; }
+@media print { body { margin: 1cm; font-size: 14pt; } h2 { break-after: avoid; } tr { break-inside: avoid; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="generated">{{.Generated}} 時点</p>
+{{range .Days}}
+<h2{{if .Today}} class="today"{{end}}>{{.Label}}</h2>
+<table>
+{{range .Rows}}<tr><td class="time">{{.Time}}</td><td class="course">{{.Course}}</td><td>{{.Title}}</td></tr>
+{{end}}</table>
+{{else}}<p class="empty">この期間に締切の課題はありません。</p>
+{{end}}
+</body>
+</html>
+`))
+
+type boardPage struct {
+	Title     string
+	Generated string
+	Days      []*boardDay
+}
+
+type boardDay struct {
+	Label string
+	Today bool
+	Rows  []*boardRow
+}
+
+type boardRow struct {
+	Time   string
+	Course string
+	Title  string
+}
+
+func runExportBoard(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export board", flag.ExitOnError)
+	out := fs.String("out", "", "出力するHTMLファイル (必須)")
+	days := fs.Int("days", 7, "今日から何日分の締切を載せるか")
+	title := fs.String("title", "今週の提出物", "見出し")
+	fs.Parse(args)
+
+	if *out == "" {
+		log.Fatal("-out で出力するファイルを指定してください")
+	}
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	// 同僚のコースは config.json の courseIds で指定する。指定がなければ担当しているコースが対象になる
+	courses, err := listTaughtCourses(ctx, srv, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	items, err := listPublishedCourseWork(ctx, srv, courses)
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("出力ファイルを作成できませんでした: %v", err)
+	}
+	defer f.Close()
+	if err := writeBoard(f, cfg, *title, items, *days, time.Now()); err != nil {
+		log.Fatalf("HTMLを書き込めませんでした: %v", err)
+	}
+}
+
+// listPublishedCourseWork はコースの公開済みの課題を取得します。提出物は取得しません。
+func listPublishedCourseWork(ctx context.Context, srv *classroom.Service, courses []*classroom.Course) ([]*Assignment, error) {
+	var items []*Assignment
+	for _, course := range courses {
+		pages, errc := streamCourseWork(ctx, srv, course.Id)
+		for page := range pages {
+			for _, c := range page {
+				if c.State == "PUBLISHED" {
+					items = append(items, &Assignment{Course: course, CourseWork: c})
+				}
+			}
+		}
+		if err := <-errc; err != nil {
+			return nil, fmt.Errorf("課題を取得できませんでした (%s): %w", course.Name, err)
+		}
+	}
+	sortAssignments(items)
+	return items, nil
+}
+
+// writeBoard は今日から days 日分の締切を日ごとにまとめたHTMLを書き出します。
+func writeBoard(w io.Writer, cfg *Config, title string, items []*Assignment, days int, now time.Time) error {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	end := today.AddDate(0, 0, days)
+	page := &boardPage{Title: title, Generated: formatTime(now)}
+	index := map[string]*boardDay{}
+	for _, a := range items {
+		due, ok := a.Due()
+		if !ok || due.Before(today) || !due.Before(end) {
+			continue
+		}
+		key := due.Format("2006-01-02")
+		day, ok := index[key]
+		if !ok {
+			day = &boardDay{
+				Label: fmt.Sprintf("%s (%s)", due.Format("1月2日"), weekdays[due.Weekday()]),
+				Today: key == today.Format("2006-01-02"),
+			}
+			index[key] = day
+			page.Days = append(page.Days, day)
+		}
+		day.Rows = append(day.Rows, &boardRow{
+			Time:   due.Format("15:04"),
+			Course: cfg.courseAlias(a.Course.Id, a.Course.Name),
+			Title:  a.CourseWork.Title,
+		})
+	}
+	return boardTemplate.Execute(w, page)
+}
@@ -0,0 +1,489 @@
+package main
+
+import (
+	"classroom-api/client"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/api/classroom/v1"
+	"log"
+	"net/http"
+	"time"
+)
+
+// cacheFile は sync で取得したコース、課題、提出物を保存するSQLiteのデータベースです。
+const cacheFile = "cache.db"
+
+// CacheConfig はローカルのキャッシュの設定です。
+type CacheConfig struct {
+	// MaxAge が 0 でない場合、最後の sync からこの時間が経っていなければ
+	// 一覧などのコマンドはAPIを呼ばずにキャッシュから課題を読み取ります。
+	MaxAge Duration `json:"maxAge,omitempty"`
+}
+
+const cacheSchema = `
+CREATE TABLE IF NOT EXISTS meta (key TEXT PRIMARY KEY, value TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS courses (id TEXT PRIMARY KEY, data TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS coursework (
+	id TEXT PRIMARY KEY,
+	course_id TEXT NOT NULL REFERENCES courses(id),
+	topic TEXT NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS history (
+	coursework_id TEXT NOT NULL,
+	course TEXT NOT NULL,
+	title TEXT NOT NULL,
+	field TEXT NOT NULL,
+	old TEXT NOT NULL,
+	new TEXT NOT NULL,
+	changed TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS submissions (
+	coursework_id TEXT PRIMARY KEY REFERENCES coursework(id),
+	state TEXT NOT NULL,
+	data TEXT NOT NULL
+);
+`
+
+// openCache はキャッシュのデータベースを開き、テーブルがなければ作ります。
+func openCache(cfg *Config) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", cfg.dataPath(cacheFile))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(cacheSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("キャッシュを開けませんでした: %w", err)
+	}
+	return db, nil
+}
+
+// writeCache はキャッシュの内容を courses と items で置き換えます。
+func writeCache(ctx context.Context, db *sql.DB, courses []*classroom.Course, items []*Assignment, now time.Time) error {
+	return updateCache(ctx, db, courses, items, true, true, now)
+}
+
+// restoreCache はキャッシュの内容を courses と items で置き換えます。
+// 過去のスナップショットを読み込むときに使い、今のキャッシュとの差を変更履歴に記録しません。
+func restoreCache(ctx context.Context, db *sql.DB, courses []*classroom.Course, items []*Assignment, now time.Time) error {
+	return updateCache(ctx, db, courses, items, true, false, now)
+}
+
+// mergeCache は courses と items をキャッシュに追加・更新します。
+// courses にないコースとその課題はキャッシュから削除します。
+func mergeCache(ctx context.Context, db *sql.DB, courses []*classroom.Course, items []*Assignment, now time.Time) error {
+	return updateCache(ctx, db, courses, items, false, true, now)
+}
+
+// updateCache はキャッシュに courses と items を保存します。
+// replace の場合は今のキャッシュを置き換え、history の場合は課題の変更を変更履歴に記録します。
+func updateCache(ctx context.Context, db *sql.DB, courses []*classroom.Course, items []*Assignment, replace, history bool, now time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if history {
+		if err := recordChanges(ctx, tx, items, now); err != nil {
+			return fmt.Errorf("変更履歴を記録できませんでした: %w", err)
+		}
+	}
+	if replace {
+		for _, query := range []string{"DELETE FROM submissions", "DELETE FROM coursework", "DELETE FROM courses", "DELETE FROM meta WHERE key LIKE 'updated:%'"} {
+			if _, err := tx.ExecContext(ctx, query); err != nil {
+				return err
+			}
+		}
+	}
+	upsert := func(query string, v any, args ...any) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, query, append(args, string(b))...)
+		return err
+	}
+	keep := map[string]bool{}
+	for _, c := range courses {
+		keep[c.Id] = true
+		if err := upsert("INSERT OR REPLACE INTO courses (id, data) VALUES (?, ?)", c, c.Id); err != nil {
+			return err
+		}
+	}
+	if err := removeCachedCourses(ctx, tx, keep); err != nil {
+		return err
+	}
+	newest := map[string]string{}
+	for _, a := range items {
+		c := a.CourseWork
+		if err := upsert("INSERT OR REPLACE INTO coursework (id, course_id, topic, data) VALUES (?, ?, ?, ?)", c, c.Id, a.Course.Id, a.Topic); err != nil {
+			return err
+		}
+		if laterTimestamp(c.UpdateTime, newest[a.Course.Id]) {
+			newest[a.Course.Id] = c.UpdateTime
+		}
+		if a.Submission == nil {
+			// 提出物が一覧からなくなった課題では、前回の提出物を残さない
+			if _, err := tx.ExecContext(ctx, "DELETE FROM submissions WHERE coursework_id = ?", c.Id); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := upsert("INSERT OR REPLACE INTO submissions (coursework_id, state, data) VALUES (?, ?, ?)", a.Submission, c.Id, a.Submission.State); err != nil {
+			return err
+		}
+	}
+	for courseID, t := range newest {
+		prev, err := cacheMeta(ctx, tx, "updated:"+courseID)
+		if err != nil {
+			return err
+		}
+		if !laterTimestamp(t, prev) {
+			continue
+		}
+		if err := setCacheMeta(ctx, tx, "updated:"+courseID, t); err != nil {
+			return err
+		}
+	}
+	if err := setCacheMeta(ctx, tx, "synced_at", now.Format(time.RFC3339)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// removeCachedCourses は keep にないコースとその課題・提出物をキャッシュから削除します。
+func removeCachedCourses(ctx context.Context, tx *sql.Tx, keep map[string]bool) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM courses")
+	if err != nil {
+		return err
+	}
+	var remove []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		if !keep[id] {
+			remove = append(remove, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range remove {
+		for _, query := range []string{
+			"DELETE FROM submissions WHERE coursework_id IN (SELECT id FROM coursework WHERE course_id = ?)",
+			"DELETE FROM coursework WHERE course_id = ?",
+			"DELETE FROM courses WHERE id = ?",
+			"DELETE FROM meta WHERE key = 'updated:' || ?",
+		} {
+			if _, err := tx.ExecContext(ctx, query, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// queryer は *sql.DB と *sql.Tx に共通の問い合わせのメソッドです。
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// cacheMeta はキャッシュの meta テーブルの値を返します。ない場合は空文字列を返します。
+func cacheMeta(ctx context.Context, q queryer, key string) (string, error) {
+	var value string
+	err := q.QueryRowContext(ctx, "SELECT value FROM meta WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+func setCacheMeta(ctx context.Context, tx *sql.Tx, key, value string) error {
+	_, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO meta (key, value) VALUES (?, ?)", key, value)
+	return err
+}
+
+// laterTimestamp は RFC 3339 形式の a が b より後かどうかを返します。b が空の場合は true を返します。
+func laterTimestamp(a, b string) bool {
+	if b == "" {
+		return a != ""
+	}
+	ta, err := time.Parse(time.RFC3339Nano, a)
+	if err != nil {
+		return false
+	}
+	tb, err := time.Parse(time.RFC3339Nano, b)
+	return err != nil || ta.After(tb)
+}
+
+// errCacheEmpty は一度も sync していないことを表します。
+var errCacheEmpty = errors.New("キャッシュがありません。先に sync を実行してください")
+
+// readCache はキャッシュからコースと課題、最後に sync した時刻を読み取ります。
+func readCache(ctx context.Context, db *sql.DB) ([]*classroom.Course, []*Assignment, time.Time, error) {
+	var synced time.Time
+	value, err := cacheMeta(ctx, db, "synced_at")
+	if err != nil {
+		return nil, nil, synced, err
+	}
+	if value == "" {
+		return nil, nil, synced, errCacheEmpty
+	}
+	if synced, err = time.Parse(time.RFC3339, value); err != nil {
+		return nil, nil, synced, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT data FROM courses")
+	if err != nil {
+		return nil, nil, synced, err
+	}
+	var courses []*classroom.Course
+	byID := map[string]*classroom.Course{}
+	for rows.Next() {
+		c := &classroom.Course{}
+		if err := scanJSON(rows, c); err != nil {
+			rows.Close()
+			return nil, nil, synced, err
+		}
+		courses = append(courses, c)
+		byID[c.Id] = c
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, synced, err
+	}
+
+	rows, err = db.QueryContext(ctx, `SELECT w.course_id, w.topic, w.data, s.data FROM coursework w LEFT JOIN submissions s ON s.coursework_id = w.id`)
+	if err != nil {
+		return nil, nil, synced, err
+	}
+	defer rows.Close()
+	var items []*Assignment
+	for rows.Next() {
+		var courseID, topic, work string
+		var sub sql.NullString
+		if err := rows.Scan(&courseID, &topic, &work, &sub); err != nil {
+			return nil, nil, synced, err
+		}
+		a := &Assignment{Course: byID[courseID], CourseWork: &classroom.CourseWork{}, Topic: topic}
+		if err := json.Unmarshal([]byte(work), a.CourseWork); err != nil {
+			return nil, nil, synced, err
+		}
+		if sub.Valid {
+			a.Submission = &classroom.StudentSubmission{}
+			if err := json.Unmarshal([]byte(sub.String), a.Submission); err != nil {
+				return nil, nil, synced, err
+			}
+		}
+		if a.Course != nil {
+			items = append(items, a)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, synced, err
+	}
+	sortAssignments(items)
+	return courses, items, synced, nil
+}
+
+// scanJSON は1列のJSONの行を v に読み取ります。
+func scanJSON(rows *sql.Rows, v any) error {
+	var s string
+	if err := rows.Scan(&s); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(s), v)
+}
+
+// loadCachedAssignments は最後の sync が cfg.Cache.MaxAge 以内であればキャッシュの課題を返します。
+// オフラインモードでは経過時間にかかわらずキャッシュを使い、キャッシュがなければエラーを返します。
+// キャッシュを使わない場合は ok が false になります。
+func loadCachedAssignments(ctx context.Context, cfg *Config) (items []*Assignment, ok bool, err error) {
+	if cfg.Cache.MaxAge <= 0 && !cfg.Offline {
+		return nil, false, nil
+	}
+	db, err := openCache(cfg)
+	if err != nil {
+		return nil, false, err
+	}
+	defer db.Close()
+	_, items, synced, err := readCache(ctx, db)
+	if err != nil {
+		if errors.Is(err, errCacheEmpty) && !cfg.Offline {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	age := time.Since(synced)
+	if !cfg.Offline && age > time.Duration(cfg.Cache.MaxAge) {
+		return nil, false, nil
+	}
+	label := "キャッシュ"
+	if cfg.Offline {
+		label = "オフライン"
+	}
+	log.Printf("[%s] 最終同期 %s (%s前) の課題を表示しています", label, formatTime(synced), formatDuration(age))
+	return items, true, nil
+}
+
+// errOffline はオフラインモードでAPIを呼び出そうとしたことを表します。
+var errOffline = errors.New("オフラインモードではAPIを呼び出せません")
+
+// offlineTransport はすべてのリクエストを errOffline で失敗させます。
+var offlineTransport = client.RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+	return nil, errOffline
+})
+
+func runSync(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	interval := fs.Duration("interval", 0, "指定した間隔で同期を繰り返します (例: 30m)")
+	full := fs.Bool("full", false, "前回から更新された課題だけでなく、すべての課題を取得し直します (削除された課題も反映されます)")
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	db, err := openCache(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	for {
+		if err := syncCache(ctx, cfg, srv, db, *full, time.Now()); err != nil {
+			log.Printf("キャッシュを同期できませんでした: %v", err)
+		}
+		if *interval <= 0 {
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// syncCache はClassroomのデータをキャッシュに保存します。
+// full が false の場合、一度取得したコースでは前回の同期以降に更新された課題と、
+// すべての課題の提出物だけを取得し直します。削除された課題は full で同期するまで残ります。
+func syncCache(ctx context.Context, cfg *Config, srv *classroom.Service, db *sql.DB, full bool, now time.Time) error {
+	courses, err := listCourses(ctx, srv, cfg)
+	if err != nil {
+		return err
+	}
+	return syncCourses(ctx, srv, db, courses, courses, full, now)
+}
+
+// syncCourses は在籍中のコース courses のうち due の課題をキャッシュに保存します。
+// ほかのコースの課題はキャッシュに残し、courses にないコースはキャッシュから消します。
+// full の場合は due によらずすべてのコースを取得し直します。
+func syncCourses(ctx context.Context, srv *classroom.Service, db *sql.DB, courses, due []*classroom.Course, full bool, now time.Time) error {
+	if full {
+		items, err := fetchAssignments(ctx, srv, courses)
+		if err != nil {
+			return err
+		}
+		if err := writeCache(ctx, db, courses, items, now); err != nil {
+			return err
+		}
+		log.Printf("%d件のコースと%d件の課題をキャッシュに保存しました", len(courses), len(items))
+		return nil
+	}
+
+	_, cached, _, err := readCache(ctx, db)
+	if err != nil && !errors.Is(err, errCacheEmpty) {
+		return err
+	}
+	var fresh, updated []*Assignment
+	var unseen []*classroom.Course
+	listed := map[string]bool{}
+	for _, c := range due {
+		listed[c.Id] = true
+		since, err := cacheMeta(ctx, db, "updated:"+c.Id)
+		if err != nil {
+			return err
+		}
+		if since == "" {
+			unseen = append(unseen, c)
+			continue
+		}
+		items, err := fetchUpdatedAssignments(ctx, srv, c, since)
+		if err != nil {
+			return err
+		}
+		updated = append(updated, items...)
+	}
+	if len(unseen) > 0 {
+		if fresh, err = fetchAssignments(ctx, srv, unseen); err != nil {
+			return err
+		}
+	}
+	// 更新されていない課題でも、提出・取り消し・返却で提出物が変わるため取得し直す。
+	// 提出物はコースごとに1回でまとめて取得するため、提出済みの課題を除いても呼び出しは減らない
+	done := map[string]bool{}
+	for _, a := range updated {
+		done[a.CourseWork.Id] = true
+	}
+	var refreshed int
+	subs := map[string]map[string]*classroom.StudentSubmission{}
+	for _, a := range cached {
+		if done[a.CourseWork.Id] || !listed[a.Course.Id] {
+			continue
+		}
+		cs, ok := subs[a.Course.Id]
+		if !ok {
+			if cs, err = mySubmissions(ctx, srv, a.Course.Id); err != nil {
+				return fmt.Errorf("%s: %w", a.Course.Name, err)
+			}
+			subs[a.Course.Id] = cs
+		}
+		a.Submission = cs[a.CourseWork.Id]
+		updated = append(updated, a)
+		refreshed++
+	}
+	if err := mergeCache(ctx, db, courses, append(fresh, updated...), now); err != nil {
+		return err
+	}
+	log.Printf("%d件のコースを同期しました (新しいコースの課題 %d件、更新された課題 %d件、提出物の再取得 %d件)", len(due), len(fresh), len(updated)-refreshed, refreshed)
+	return nil
+}
+
+// errStopPaging はページの取得を途中でやめるために使います。
+var errStopPaging = errors.New("stop paging")
+
+// fetchUpdatedAssignments は since より後に更新されたコースの課題とその提出物を取得します。
+// 課題を更新日時の新しい順に取得し、since 以前の課題に達したところで取得をやめます。
+func fetchUpdatedAssignments(ctx context.Context, srv *classroom.Service, course *classroom.Course, since string) ([]*Assignment, error) {
+	var works []*classroom.CourseWork
+	err := srv.Courses.CourseWork.List(course.Id).OrderBy("updateTime desc").Pages(ctx, func(r *classroom.ListCourseWorkResponse) error {
+		for _, c := range r.CourseWork {
+			if !laterTimestamp(c.UpdateTime, since) {
+				return errStopPaging
+			}
+			works = append(works, c)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopPaging) {
+		return nil, fmt.Errorf("課題を取得できませんでした (%s): %w", course.Name, err)
+	}
+	if len(works) == 0 {
+		return nil, nil
+	}
+	topics, err := listTopics(ctx, srv, course.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", course.Name, err)
+	}
+	subs, err := mySubmissions(ctx, srv, course.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", course.Name, err)
+	}
+	items := make([]*Assignment, 0, len(works))
+	for _, c := range works {
+		items = append(items, &Assignment{Course: course, CourseWork: c, Submission: subs[c.Id], Topic: topics[c.TopicId]})
+	}
+	return items, nil
+}
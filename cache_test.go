@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"google.golang.org/api/option"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeCacheRemovesSubmission(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir()}
+	db, err := openCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	course := &classroom.Course{Id: "c1", Name: "数学"}
+	work := &classroom.CourseWork{Id: "w1", CourseId: "c1", Title: "課題", UpdateTime: "2026-10-16T00:00:00Z"}
+	sub := &classroom.StudentSubmission{CourseWorkId: "w1", State: "CREATED"}
+	courses := []*classroom.Course{course}
+	if err := writeCache(ctx, db, courses, []*Assignment{{Course: course, CourseWork: work, Submission: sub}}, now); err != nil {
+		t.Fatal(err)
+	}
+	// 提出物が一覧からなくなった課題を同期する
+	if err := mergeCache(ctx, db, courses, []*Assignment{{Course: course, CourseWork: work}}, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	_, items, _, err := readCache(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("キャッシュの課題 = %d件, want 1件", len(items))
+	}
+	if items[0].Submission != nil {
+		t.Errorf("一覧からなくなった提出物 (%s) がキャッシュに残っています", items[0].Submission.State)
+	}
+}
+
+func TestSyncCoursesOnlyDue(t *testing.T) {
+	var paths []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		fmt.Fprint(w, `{}`)
+	}))
+	defer api.Close()
+	ctx := context.Background()
+	srv, err := classroom.NewService(ctx, option.WithEndpoint(api.URL), option.WithHTTPClient(api.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{DataDir: t.TempDir()}
+	db, err := openCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	c1 := &classroom.Course{Id: "c1", Name: "数学"}
+	c2 := &classroom.Course{Id: "c2", Name: "英語"}
+	items := []*Assignment{
+		{Course: c1, CourseWork: &classroom.CourseWork{Id: "w1", CourseId: "c1", UpdateTime: "2026-10-16T00:00:00Z"}, Submission: &classroom.StudentSubmission{CourseWorkId: "w1", State: "CREATED"}},
+		{Course: c2, CourseWork: &classroom.CourseWork{Id: "w2", CourseId: "c2", UpdateTime: "2026-10-16T00:00:00Z"}, Submission: &classroom.StudentSubmission{CourseWorkId: "w2", State: "CREATED"}},
+	}
+	courses := []*classroom.Course{c1, c2}
+	if err := writeCache(ctx, db, courses, items, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncCourses(ctx, srv, db, courses, []*classroom.Course{c2}, false, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		if strings.Contains(p, "/c1/") {
+			t.Errorf("取得の時刻になっていないコースを取得しました: %s", p)
+		}
+	}
+	_, cached, _, err := readCache(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 2 {
+		t.Fatalf("キャッシュの課題 = %d件, want 2件", len(cached))
+	}
+	for _, a := range cached {
+		// 取得したコースだけ提出物が更新される
+		if got, want := a.Submission != nil, a.Course.Id == "c1"; got != want {
+			t.Errorf("%s の提出物があるかどうか = %v, want %v", a.CourseWork.Id, got, want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+	"log"
+	"time"
+)
+
+// CalendarConfig はGoogleカレンダーとの同期の設定です。
+type CalendarConfig struct {
+	// Name は締切を書き込むカレンダーの名前です。なければ作成します。
+	Name string `json:"name,omitempty"`
+}
+
+const defaultCalendarName = "Classroom deadlines"
+
+// 同期した予定には課題のキーと変更検出用の値を非公開の拡張プロパティとして持たせます。
+// カレンダーの予定そのものを同期の状態として使うため、状態ファイルは持ちません。
+const (
+	calendarKeyProp         = "classroomKey"
+	calendarFingerprintProp = "classroomFingerprint"
+)
+
+func runCalendar(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("calendar", flag.ExitOnError)
+	interval := fs.Duration("interval", 0, "指定した間隔で同期を繰り返します (例: 30m)")
+	dryRun := fs.Bool("dry-run", false, "カレンダーを変更せずに、行う変更だけを表示します")
+	fs.Parse(args)
+
+	name := cfg.Calendar.Name
+	if name == "" {
+		name = defaultCalendarName
+	}
+	client := newHTTPClient(cfg)
+	srv := newClassroomService(ctx, client)
+	csrv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		log.Fatalf("Calendarクライアントを作成できませんでした: %v", err)
+	}
+	calendarId, err := ensureCalendar(ctx, csrv, name, *dryRun)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for {
+		items, err := loadAssignments(ctx, cfg, srv)
+		if err == nil {
+			err = syncCalendar(ctx, csrv, calendarId, pendingAssignments(items, time.Now()), *dryRun)
+		}
+		if err != nil {
+			log.Printf("カレンダーの同期に失敗しました: %v", err)
+		}
+		if *interval <= 0 {
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// ensureCalendar は名前が name のカレンダーのIDを返します。なければ作成します。
+func ensureCalendar(ctx context.Context, csrv *calendar.Service, name string, dryRun bool) (string, error) {
+	var id string
+	err := csrv.CalendarList.List().Pages(ctx, func(r *calendar.CalendarList) error {
+		for _, c := range r.Items {
+			if c.Summary == name && id == "" {
+				id = c.Id
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("カレンダーの一覧を取得できませんでした: %w", err)
+	}
+	if id != "" || dryRun {
+		return id, nil
+	}
+	c, err := csrv.Calendars.Insert(&calendar.Calendar{
+		Summary:     name,
+		Description: "Google Classroom の未提出の課題の締切です。classroom-api が自動で更新します。",
+		TimeZone:    calendarTimeZone(),
+	}).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("カレンダーを作成できませんでした: %w", err)
+	}
+	log.Printf("カレンダー「%s」を作成しました", name)
+	return c.Id, nil
+}
+
+// calendarTimeZone はローカルのタイムゾーン名を返します。IANA名が分からない場合は空文字列です。
+func calendarTimeZone() string {
+	if name := time.Local.String(); name != "Local" {
+		return name
+	}
+	return ""
+}
+
+// syncCalendar は未提出の課題の締切をカレンダーの予定に反映します。
+// 締切が変わった課題の予定は更新し、提出済みなどで一覧から消えた課題の予定は削除します。
+// 予定をカレンダーで直接編集した場合、課題が変更されるまでその編集を残します。
+func syncCalendar(ctx context.Context, csrv *calendar.Service, calendarId string, pending []*Assignment, dryRun bool) error {
+	existing := map[string]*calendar.Event{}
+	if calendarId != "" {
+		err := csrv.Events.List(calendarId).Pages(ctx, func(r *calendar.Events) error {
+			for _, ev := range r.Items {
+				if ev.ExtendedProperties == nil {
+					continue
+				}
+				if key := ev.ExtendedProperties.Private[calendarKeyProp]; key != "" {
+					existing[key] = ev
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("予定を取得できませんでした: %w", err)
+		}
+	}
+
+	var created, updated, deleted int
+	for _, a := range pending {
+		ev, ok := calendarEvent(a)
+		if !ok {
+			continue
+		}
+		key := assignmentKey(a)
+		old, found := existing[key]
+		delete(existing, key)
+		switch {
+		case !found:
+			fmt.Printf("追加: %s\n", a.CourseWork.Title)
+			if !dryRun {
+				if _, err := csrv.Events.Insert(calendarId, ev).Context(ctx).Do(); err != nil {
+					return fmt.Errorf("予定を追加できませんでした (%s): %w", a.CourseWork.Title, err)
+				}
+			}
+			created++
+		case old.ExtendedProperties.Private[calendarFingerprintProp] != ev.ExtendedProperties.Private[calendarFingerprintProp]:
+			fmt.Printf("更新: %s\n", a.CourseWork.Title)
+			if !dryRun {
+				if _, err := csrv.Events.Update(calendarId, old.Id, ev).Context(ctx).Do(); err != nil {
+					return fmt.Errorf("予定を更新できませんでした (%s): %w", a.CourseWork.Title, err)
+				}
+			}
+			updated++
+		}
+	}
+	for _, ev := range existing {
+		fmt.Printf("削除: %s\n", ev.Summary)
+		if !dryRun {
+			if err := csrv.Events.Delete(calendarId, ev.Id).Context(ctx).Do(); err != nil {
+				return fmt.Errorf("予定を削除できませんでした (%s): %w", ev.Summary, err)
+			}
+		}
+		deleted++
+	}
+	log.Printf("カレンダーを同期しました (追加 %d件, 更新 %d件, 削除 %d件)", created, updated, deleted)
+	return nil
+}
+
+// calendarEvent は課題の締切の予定を返します。締切がない課題は false を返します。
+func calendarEvent(a *Assignment) (*calendar.Event, bool) {
+	due, ok := a.Due()
+	if !ok {
+		return nil, false
+	}
+	c := a.CourseWork
+	at := &calendar.EventDateTime{DateTime: due.Format(time.RFC3339)}
+	return &calendar.Event{
+		Summary:     "【締切】" + c.Title,
+		Description: icalDescription(a),
+		Start:       at,
+		End:         at,
+		Source:      &calendar.EventSource{Title: a.Course.Name, Url: c.AlternateLink},
+		ExtendedProperties: &calendar.EventExtendedProperties{
+			Private: map[string]string{
+				calendarKeyProp:         assignmentKey(a),
+				calendarFingerprintProp: c.UpdateTime + "|" + due.UTC().Format(time.RFC3339),
+			},
+		},
+	}, true
+}
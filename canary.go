@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// canaryResult はひとつの設定で同期した結果です。
+type canaryResult struct {
+	pending map[string]*Assignment
+	// reminders は remind 1回で送る通知の数、digests は1週間に授業後のまとめとして送る通知の数です。
+	reminders int
+	digests   int
+}
+
+// runCanary は現在の設定と候補の設定で同じデータを同期し、候補の設定で何が変わるかを表示します。
+// どちらの同期も状態ファイルを書き込まず、通知も送りません。
+func runCanary(ctx context.Context, cfg *Config, candidatePath string) {
+	candidate, err := loadConfig(candidatePath)
+	if err != nil {
+		log.Fatalf("候補の設定: %v", err)
+	}
+	cfg.dryRun, candidate.dryRun = true, true
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	current, err := listCourses(ctx, srv, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	next, err := listCourses(ctx, srv, candidate)
+	if err != nil {
+		log.Fatalf("候補の設定: %v", err)
+	}
+	// 両方の設定のコースをまとめて1回だけ取得する
+	var courses []*classroom.Course
+	seen := map[string]bool{}
+	for _, c := range append(current, next...) {
+		if !seen[c.Id] {
+			seen[c.Id] = true
+			courses = append(courses, c)
+		}
+	}
+	items, err := fetchAssignments(ctx, srv, courses)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	now := time.Now()
+	before, err := canarySync(cfg, current, items, now)
+	if err != nil {
+		log.Fatal(err)
+	}
+	after, err := canarySync(candidate, next, items, now)
+	if err != nil {
+		log.Fatalf("候補の設定: %v", err)
+	}
+	printCanaryDiff(os.Stdout, before, after)
+}
+
+// canarySync は取得済みの課題に設定を適用した結果を返します。
+// 課題は設定ごとに別のスラッグや猶予期間を持つため、コピーしてから設定を適用します。
+func canarySync(cfg *Config, courses []*classroom.Course, items []*Assignment, now time.Time) (*canaryResult, error) {
+	inCourse := map[string]bool{}
+	for _, c := range courses {
+		inCourse[c.Id] = true
+	}
+	var own []*Assignment
+	for _, a := range items {
+		if inCourse[a.Course.Id] {
+			copied := *a
+			own = append(own, &copied)
+		}
+	}
+	if err := applyCourseConfig(cfg, own); err != nil {
+		return nil, err
+	}
+	pending := pendingAssignments(own, now)
+	r := &canaryResult{pending: map[string]*Assignment{}}
+	for _, a := range pending {
+		r.pending[assignmentKey(a)] = a
+	}
+	notifiers := len(newNotifiers(cfg))
+	if len(pending) > 0 {
+		r.reminders = notifiers
+	}
+	for i := range cfg.Timetable {
+		if lessonDigest(cfg.Timetable, &cfg.Timetable[i], pending, now) != nil {
+			r.digests += notifiers
+		}
+	}
+	return r, nil
+}
+
+// printCanaryDiff は現在の設定と候補の設定の結果の違いを表示します。
+func printCanaryDiff(w io.Writer, before, after *canaryResult) {
+	var o *outputOptions // 締切は日時で表示する
+	var added, removed, changed []string
+	for key, a := range after.pending {
+		old, ok := before.pending[key]
+		switch {
+		case !ok:
+			added = append(added, fmt.Sprintf("+ %s (%s) 締切 %s", a.CourseWork.Title, a.Slug, o.dueLabel(a)))
+		case old.Slug != a.Slug || !sameDue(old, a):
+			changed = append(changed, fmt.Sprintf("~ %s: %s 締切 %s → %s 締切 %s", a.CourseWork.Title,
+				old.Slug, o.dueLabel(old), a.Slug, o.dueLabel(a)))
+		}
+	}
+	for key, a := range before.pending {
+		if _, ok := after.pending[key]; !ok {
+			removed = append(removed, fmt.Sprintf("- %s (%s) 締切 %s", a.CourseWork.Title, a.Slug, o.dueLabel(a)))
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+
+	fmt.Fprintf(w, "未提出の課題: %d件 → %d件\n", len(before.pending), len(after.pending))
+	if len(added)+len(removed)+len(changed) == 0 {
+		fmt.Fprintln(w, "一覧に変化はありません")
+	}
+	for _, lines := range [][]string{added, removed, changed} {
+		for _, l := range lines {
+			fmt.Fprintln(w, l)
+		}
+	}
+	fmt.Fprintf(w, "\n通知の数 (送信先ごと):\n")
+	fmt.Fprintf(w, "  remind 1回あたり:         %d件 → %d件\n", before.reminders, after.reminders)
+	fmt.Fprintf(w, "  授業後のまとめ (1週間):    %d件 → %d件\n", before.digests, after.digests)
+}
+
+func sameDue(a, b *Assignment) bool {
+	da, oka := a.EffectiveDue()
+	db, okb := b.EffectiveDue()
+	return oka == okb && da.Equal(db)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CelebrateConfig は今週の課題をすべて提出したときのお祝いの設定です。
+type CelebrateConfig struct {
+	// Enabled が true の場合にお祝いを通知します。
+	Enabled bool `json:"enabled,omitempty"`
+	// Message は通知の文面です。省略時は既定の文面を使います。
+	Message string `json:"message,omitempty"`
+	// Command は通知の代わりに実行するコマンドです (notify.command と同じ形式)。
+	Command string `json:"command,omitempty"`
+}
+
+const defaultCelebrateMessage = "🎉 今週締切の課題をすべて提出しました！"
+
+// stateTransition は同期の間に起きた提出物の状態の変化です。
+type stateTransition struct {
+	Assignment *Assignment
+	From, To   string
+}
+
+// stateTracker は同期ごとの提出物の状態を覚えておき、変化を検出します。
+type stateTracker struct {
+	states map[string]string
+}
+
+// update は前回の同期からの状態の変化を返します。最初の同期では何も返しません。
+func (t *stateTracker) update(items []*Assignment) []stateTransition {
+	cur := make(map[string]string, len(items))
+	var changes []stateTransition
+	for _, a := range items {
+		key := assignmentKey(a)
+		cur[key] = a.State()
+		if old, ok := t.states[key]; ok && old != cur[key] {
+			changes = append(changes, stateTransition{Assignment: a, From: old, To: cur[key]})
+		}
+	}
+	t.states = cur
+	return changes
+}
+
+// weekRange は now を含む週 (月曜日から日曜日) の範囲を返します。
+func weekRange(now time.Time) (start, end time.Time) {
+	y, m, d := now.Date()
+	offset := (int(now.Weekday()) + 6) % 7 // 月曜日からの日数
+	start = time.Date(y, m, d, 0, 0, 0, 0, now.Location()).AddDate(0, 0, -offset)
+	return start, start.AddDate(0, 0, 7)
+}
+
+// dueThisWeek は課題の締切が now と同じ週かどうかを返します。
+func dueThisWeek(a *Assignment, now time.Time) bool {
+	due, ok := a.EffectiveDue()
+	if !ok {
+		return false
+	}
+	start, end := weekRange(now)
+	return !due.Before(start) && due.Before(end)
+}
+
+// weekCleared は今回の同期で今週締切の最後の課題が提出された場合に、そのときに提出した課題を返します。
+func weekCleared(items []*Assignment, changes []stateTransition, now time.Time) []*Assignment {
+	var turnedIn []*Assignment
+	for _, c := range changes {
+		if c.To == "TURNED_IN" && dueThisWeek(c.Assignment, now) {
+			turnedIn = append(turnedIn, c.Assignment)
+		}
+	}
+	if len(turnedIn) == 0 {
+		return nil
+	}
+	for _, a := range items {
+		// 外部のツールの課題は提出状況が分からないため数えない
+		if dueThisWeek(a, now) && !a.External() && a.State() != "TURNED_IN" && a.State() != "RETURNED" {
+			return nil
+		}
+	}
+	return turnedIn
+}
+
+// celebrate はお祝いを通知します。コマンドが設定されていればそれを実行します。
+func celebrate(ctx context.Context, cfg *Config, ns []Notifier, turnedIn []*Assignment, now time.Time) error {
+	cc := cfg.Notify.Celebrate
+	msg := cc.Message
+	if msg == "" {
+		msg = defaultCelebrateMessage
+	}
+	titles := make([]string, len(turnedIn))
+	for i, a := range turnedIn {
+		titles[i] = "・" + a.CourseWork.Title
+	}
+	ev := &Event{
+		Type:  "celebration",
+		Title: msg,
+		Body:  fmt.Sprintf("最後に提出した課題:\n%s", strings.Join(titles, "\n")),
+		Time:  now,
+	}
+	if len(turnedIn) == 1 {
+		ev.Assignment = turnedIn[0]
+	}
+	if cc.Command != "" {
+		ns = []Notifier{commandNotifier(cc.Command)}
+	}
+	return notifyAll(ctx, ns, ev)
+}
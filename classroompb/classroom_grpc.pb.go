@@ -0,0 +1,216 @@
+// classroom-api serve が -grpc-listen で公開する gRPC サービスです。
+// コードを生成し直すときはリポジトリのルートで go generate を実行してください。
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: classroom.proto
+
+package classroompb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CourseWorkService_ListCourses_FullMethodName           = "/classroomapi.v1.CourseWorkService/ListCourses"
+	CourseWorkService_ListPendingCourseWork_FullMethodName = "/classroomapi.v1.CourseWorkService/ListPendingCourseWork"
+	CourseWorkService_WatchCourseWork_FullMethodName       = "/classroomapi.v1.CourseWorkService/WatchCourseWork"
+)
+
+// CourseWorkServiceClient is the client API for CourseWorkService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CourseWorkService はサーバーが取得した未提出の課題を返します。
+// 認証は HTTP の API と同じで、メタデータ authorization に "Bearer <APIキー>" を指定します。
+type CourseWorkServiceClient interface {
+	// ListCourses はコースの一覧を返します。権限 titles が必要です。
+	ListCourses(ctx context.Context, in *ListCoursesRequest, opts ...grpc.CallOption) (*ListCoursesResponse, error)
+	// ListPendingCourseWork は未提出の課題を返します。権限 coursework が必要です。
+	ListPendingCourseWork(ctx context.Context, in *ListPendingCourseWorkRequest, opts ...grpc.CallOption) (*ListPendingCourseWorkResponse, error)
+	// WatchCourseWork は未提出の課題の変更を送り続けます。権限 coursework が必要です。
+	WatchCourseWork(ctx context.Context, in *WatchCourseWorkRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CourseWorkEvent], error)
+}
+
+type courseWorkServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCourseWorkServiceClient(cc grpc.ClientConnInterface) CourseWorkServiceClient {
+	return &courseWorkServiceClient{cc}
+}
+
+func (c *courseWorkServiceClient) ListCourses(ctx context.Context, in *ListCoursesRequest, opts ...grpc.CallOption) (*ListCoursesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCoursesResponse)
+	err := c.cc.Invoke(ctx, CourseWorkService_ListCourses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *courseWorkServiceClient) ListPendingCourseWork(ctx context.Context, in *ListPendingCourseWorkRequest, opts ...grpc.CallOption) (*ListPendingCourseWorkResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPendingCourseWorkResponse)
+	err := c.cc.Invoke(ctx, CourseWorkService_ListPendingCourseWork_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *courseWorkServiceClient) WatchCourseWork(ctx context.Context, in *WatchCourseWorkRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CourseWorkEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CourseWorkService_ServiceDesc.Streams[0], CourseWorkService_WatchCourseWork_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchCourseWorkRequest, CourseWorkEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CourseWorkService_WatchCourseWorkClient = grpc.ServerStreamingClient[CourseWorkEvent]
+
+// CourseWorkServiceServer is the server API for CourseWorkService service.
+// All implementations must embed UnimplementedCourseWorkServiceServer
+// for forward compatibility.
+//
+// CourseWorkService はサーバーが取得した未提出の課題を返します。
+// 認証は HTTP の API と同じで、メタデータ authorization に "Bearer <APIキー>" を指定します。
+type CourseWorkServiceServer interface {
+	// ListCourses はコースの一覧を返します。権限 titles が必要です。
+	ListCourses(context.Context, *ListCoursesRequest) (*ListCoursesResponse, error)
+	// ListPendingCourseWork は未提出の課題を返します。権限 coursework が必要です。
+	ListPendingCourseWork(context.Context, *ListPendingCourseWorkRequest) (*ListPendingCourseWorkResponse, error)
+	// WatchCourseWork は未提出の課題の変更を送り続けます。権限 coursework が必要です。
+	WatchCourseWork(*WatchCourseWorkRequest, grpc.ServerStreamingServer[CourseWorkEvent]) error
+	mustEmbedUnimplementedCourseWorkServiceServer()
+}
+
+// UnimplementedCourseWorkServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCourseWorkServiceServer struct{}
+
+func (UnimplementedCourseWorkServiceServer) ListCourses(context.Context, *ListCoursesRequest) (*ListCoursesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCourses not implemented")
+}
+func (UnimplementedCourseWorkServiceServer) ListPendingCourseWork(context.Context, *ListPendingCourseWorkRequest) (*ListPendingCourseWorkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPendingCourseWork not implemented")
+}
+func (UnimplementedCourseWorkServiceServer) WatchCourseWork(*WatchCourseWorkRequest, grpc.ServerStreamingServer[CourseWorkEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchCourseWork not implemented")
+}
+func (UnimplementedCourseWorkServiceServer) mustEmbedUnimplementedCourseWorkServiceServer() {}
+func (UnimplementedCourseWorkServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeCourseWorkServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CourseWorkServiceServer will
+// result in compilation errors.
+type UnsafeCourseWorkServiceServer interface {
+	mustEmbedUnimplementedCourseWorkServiceServer()
+}
+
+func RegisterCourseWorkServiceServer(s grpc.ServiceRegistrar, srv CourseWorkServiceServer) {
+	// If the following call pancis, it indicates UnimplementedCourseWorkServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CourseWorkService_ServiceDesc, srv)
+}
+
+func _CourseWorkService_ListCourses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCoursesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CourseWorkServiceServer).ListCourses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CourseWorkService_ListCourses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CourseWorkServiceServer).ListCourses(ctx, req.(*ListCoursesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CourseWorkService_ListPendingCourseWork_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPendingCourseWorkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CourseWorkServiceServer).ListPendingCourseWork(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CourseWorkService_ListPendingCourseWork_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CourseWorkServiceServer).ListPendingCourseWork(ctx, req.(*ListPendingCourseWorkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CourseWorkService_WatchCourseWork_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchCourseWorkRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CourseWorkServiceServer).WatchCourseWork(m, &grpc.GenericServerStream[WatchCourseWorkRequest, CourseWorkEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CourseWorkService_WatchCourseWorkServer = grpc.ServerStreamingServer[CourseWorkEvent]
+
+// CourseWorkService_ServiceDesc is the grpc.ServiceDesc for CourseWorkService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CourseWorkService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "classroomapi.v1.CourseWorkService",
+	HandlerType: (*CourseWorkServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListCourses",
+			Handler:    _CourseWorkService_ListCourses_Handler,
+		},
+		{
+			MethodName: "ListPendingCourseWork",
+			Handler:    _CourseWorkService_ListPendingCourseWork_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchCourseWork",
+			Handler:       _CourseWorkService_WatchCourseWork_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "classroom.proto",
+}
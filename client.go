@@ -0,0 +1,104 @@
+package main
+
+import (
+	"classroom-api/client"
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ClientConfig はAPIクライアントの層の設定です。各層は client パッケージにあります。
+type ClientConfig struct {
+	// Log が true の場合はリクエストごとにメソッド、URL、状態、所要時間をログに書きます。
+	Log bool `json:"log,omitempty"`
+	// Retries は 429 と 5xx の応答を再試行する回数です。
+	Retries int `json:"retries,omitempty"`
+	// CacheTTL が 0 でない場合は GET の応答をこの時間だけメモリに保持します。
+	CacheTTL Duration `json:"cacheTtl,omitempty"`
+	// Rate は1秒あたりに送るリクエスト数の上限です。0 の場合は制限しません。
+	Rate float64 `json:"rate,omitempty"`
+}
+
+// newClientTransport は設定に従って base を client パッケージの層と集計の層で包みます。
+func newClientTransport(base http.RoundTripper, cc ClientConfig) http.RoundTripper {
+	opts := client.Options{Log: cc.Log, Retries: cc.Retries, CacheTTL: time.Duration(cc.CacheTTL), Rate: cc.Rate}
+	return client.NewTransport(base, opts, metricsMiddleware(defaultClientMetrics))
+}
+
+// clientMetrics はAPIの呼び出しの回数と所要時間の集計です。
+type clientMetrics struct {
+	mu sync.Mutex
+	// requests はホストと状態 (エラーの場合は "error") ごとの回数です。
+	requests map[string]int
+	duration time.Duration
+}
+
+// defaultClientMetrics はすべてのAPIクライアントの集計です。
+var defaultClientMetrics = &clientMetrics{requests: map[string]int{}}
+
+func (m *clientMetrics) record(host, status string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[host+" "+status]++
+	m.duration += d
+}
+
+// snapshot は「ホスト 状態」ごとの回数と合計の所要時間を返します。
+func (m *clientMetrics) snapshot() (map[string]int, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[string]int, len(m.requests))
+	for k, v := range m.requests {
+		counts[k] = v
+	}
+	return counts, m.duration
+}
+
+// summary は集計を1行ずつの文字列で返します。
+func (m *clientMetrics) summary() []string {
+	counts, total := m.snapshot()
+	var lines []string
+	for k, v := range counts {
+		lines = append(lines, k+": "+strconv.Itoa(v))
+	}
+	sort.Strings(lines)
+	return append(lines, "合計の所要時間: "+total.Round(time.Millisecond).String())
+}
+
+// metricsMiddleware はAPIの呼び出しを m とPrometheusの指標に記録します。
+func metricsMiddleware(m *clientMetrics) client.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return client.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			start := time.Now()
+			res, err := next.RoundTrip(r)
+			status := "error"
+			if err == nil {
+				status = strconv.Itoa(res.StatusCode)
+			}
+			d := time.Since(start)
+			m.record(r.URL.Host, status, d)
+			method := apiMethod(r)
+			apiRequests.inc(method, status)
+			apiLatency.observe(d, method)
+			return res, err
+		})
+	}
+}
+
+// sleepContext は d だけ待ちます。その前に ctx が終了した場合はそのエラーを返します。
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,254 @@
+// Package client はClassroom APIなどを呼び出すHTTPクライアントの層 (ミドルウェア) を提供します。
+//
+// 組み込みの層 (リクエストID、ログ、キャッシュ、再試行、送信間隔の制限) を NewTransport で組み立てられます。
+// RegisterMiddleware で登録した層は、このパッケージで作るすべてのクライアントに加わります。
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RequestIDHeader はリクエストIDを受け渡すヘッダーです。
+const RequestIDHeader = "X-Request-Id"
+
+// Middleware はAPIクライアントのリクエストとレスポンスを処理する層です。
+// next を呼び出す前後に処理を加えた http.RoundTripper を返します。
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc は関数を http.RoundTripper として使うための型です。
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// Options は組み込みの層の設定です。
+type Options struct {
+	// Log が true の場合はリクエストごとにメソッド、URL、状態、所要時間をログに書きます。
+	Log bool
+	// Retries は 429 と 5xx の応答を再試行する回数です。
+	Retries int
+	// CacheTTL が 0 でない場合は GET の応答をこの時間だけメモリに保持します。
+	CacheTTL time.Duration
+	// Rate は1秒あたりに送るリクエスト数の上限です。0 の場合は制限しません。
+	Rate float64
+}
+
+var (
+	middlewareMu    sync.Mutex
+	extraMiddleware []Middleware
+)
+
+// RegisterMiddleware はこのパッケージで作るすべてのクライアントに層を加えます。
+// このパッケージを import したパッケージの init から呼び出すことで、監査ログなどの処理を加えられます。
+// 登録した層は組み込みの層の内側 (実際の通信に近い側) で、登録した順に実行されます。
+func RegisterMiddleware(m Middleware) {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	extraMiddleware = append(extraMiddleware, m)
+}
+
+// NewTransport は base を組み込みの層と登録された層で包んだ http.RoundTripper を返します。
+// base が nil の場合は http.DefaultTransport を使います。
+// outer はリクエストIDの層のすぐ内側、ほかの組み込みの層の外側に加える層です。
+func NewTransport(base http.RoundTripper, opts Options, outer ...Middleware) http.RoundTripper {
+	chain := append([]Middleware{RequestIDMiddleware()}, outer...)
+	if opts.Log {
+		chain = append(chain, LoggingMiddleware())
+	}
+	if opts.CacheTTL > 0 {
+		chain = append(chain, CacheMiddleware(opts.CacheTTL))
+	}
+	if opts.Retries > 0 {
+		chain = append(chain, RetryMiddleware(opts.Retries))
+	}
+	if opts.Rate > 0 {
+		chain = append(chain, RateLimitMiddleware(opts.Rate))
+	}
+	middlewareMu.Lock()
+	chain = append(chain, extraMiddleware...)
+	middlewareMu.Unlock()
+	return Chain(base, chain...)
+}
+
+// New は NewTransport で組み立てた http.Client を返します。
+func New(base http.RoundTripper, opts Options, outer ...Middleware) *http.Client {
+	return &http.Client{Transport: NewTransport(base, opts, outer...)}
+}
+
+// Chain は base を層で包みます。最初の層が最も外側になります。
+func Chain(base http.RoundTripper, chain ...Middleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	rt := base
+	for i := len(chain) - 1; i >= 0; i-- {
+		rt = chain[i](rt)
+	}
+	return rt
+}
+
+type requestIDKey struct{}
+
+// WithRequestID は ctx にリクエストIDを付けます。
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID は ctx のリクエストIDを返します。なければ空です。
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDMiddleware はリクエストの ctx のリクエストIDをAPIの呼び出しのヘッダーに付けます。
+func RequestIDMiddleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if id := RequestID(r.Context()); id != "" {
+				r = r.Clone(r.Context())
+				r.Header.Set(RequestIDHeader, id)
+			}
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+// LoggingMiddleware はリクエストごとの結果をログに書きます。
+func LoggingMiddleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			start := time.Now()
+			res, err := next.RoundTrip(r)
+			elapsed := time.Since(start).Round(time.Millisecond)
+			var id string
+			if rid := RequestID(r.Context()); rid != "" {
+				id = " [" + rid + "]"
+			}
+			if err != nil {
+				log.Printf("%s %s: %v (%s)%s", r.Method, r.URL.Redacted(), err, elapsed, id)
+			} else {
+				log.Printf("%s %s: %d (%s)%s", r.Method, r.URL.Redacted(), res.StatusCode, elapsed, id)
+			}
+			return res, err
+		})
+	}
+}
+
+// CacheMiddleware は成功した GET の応答を ttl の間だけ保持し、同じURLへのリクエストに使い回します。
+func CacheMiddleware(ttl time.Duration) Middleware {
+	type entry struct {
+		dump    []byte
+		fetched time.Time
+	}
+	var mu sync.Mutex
+	cache := map[string]*entry{}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.Method != http.MethodGet {
+				return next.RoundTrip(r)
+			}
+			key := r.URL.String()
+			mu.Lock()
+			e, ok := cache[key]
+			mu.Unlock()
+			if ok && time.Since(e.fetched) < ttl {
+				if res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(e.dump)), r); err == nil {
+					return res, nil
+				}
+			}
+			res, err := next.RoundTrip(r)
+			if err != nil || res.StatusCode != http.StatusOK {
+				return res, err
+			}
+			dump, err := httputil.DumpResponse(res, true)
+			if err != nil {
+				return res, nil
+			}
+			mu.Lock()
+			cache[key] = &entry{dump: dump, fetched: time.Now()}
+			mu.Unlock()
+			return res, nil
+		})
+	}
+}
+
+// RetryMiddleware は 429 と 5xx の応答を指数的に間隔を空けて再試行します。
+// Retry-After があればその秒数だけ待ちます。本文のあるリクエストは本文を読み直せる場合だけ再試行します。
+func RetryMiddleware(retries int) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			wait := 500 * time.Millisecond
+			for attempt := 0; ; attempt++ {
+				res, err := next.RoundTrip(r)
+				retryable := err == nil && (res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500)
+				if !retryable || attempt >= retries || (r.Body != nil && r.GetBody == nil) {
+					return res, err
+				}
+				d := wait
+				if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+					d = time.Duration(s) * time.Second
+				}
+				io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+				if err := sleep(r.Context(), d); err != nil {
+					return nil, err
+				}
+				wait *= 2
+				if r.GetBody != nil {
+					body, err := r.GetBody()
+					if err != nil {
+						return nil, err
+					}
+					r = r.Clone(r.Context())
+					r.Body = body
+				}
+			}
+		})
+	}
+}
+
+// RateLimitMiddleware は1秒あたり rate 件を超えないようにリクエストの間隔を空けます。
+func RateLimitMiddleware(rate float64) Middleware {
+	interval := time.Duration(float64(time.Second) / rate)
+	var mu sync.Mutex
+	var next time.Time
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			mu.Lock()
+			now := time.Now()
+			if next.Before(now) {
+				next = now
+			}
+			d := next.Sub(now)
+			next = next.Add(interval)
+			mu.Unlock()
+			if err := sleep(r.Context(), d); err != nil {
+				return nil, err
+			}
+			return rt.RoundTrip(r)
+		})
+	}
+}
+
+// sleep は d だけ待ちます。その前に ctx が終了した場合はそのエラーを返します。
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestNewTransportOrder(t *testing.T) {
+	var order []string
+	layer := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(r)
+			})
+		}
+	}
+	RegisterMiddleware(layer("registered"))
+	defer func() { extraMiddleware = nil }()
+
+	var header string
+	base := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		header = r.Header.Get(RequestIDHeader)
+		return httptest.NewRecorder().Result(), nil
+	})
+	c := New(base, Options{}, layer("outer"))
+	r, err := http.NewRequestWithContext(WithRequestID(context.Background(), "abc"), "GET", "http://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(r); err != nil {
+		t.Fatal(err)
+	}
+	// outer は登録した層より外側で実行される
+	if want := []string{"outer", "registered"}; !slices.Equal(order, want) {
+		t.Errorf("層の順序 = %v, want %v", order, want)
+	}
+	if header != "abc" {
+		t.Errorf("%s = %q, want %q", RequestIDHeader, header, "abc")
+	}
+}
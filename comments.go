@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"io"
+	"log"
+	"os"
+)
+
+// Classroom API (v1) は提出物の限定公開コメントを読み書きするメソッドを提供していません。
+// そのため comments コマンドでは、APIで取得できる返却・採点の履歴を表示し、
+// コメントの読み書きは提出物のページをブラウザで開いて行えるようにします。
+const commentsUnsupported = "Classroom API は限定公開コメントの取得・投稿に対応していないため、コメントは提出物のページで確認してください"
+
+func runCourseworkComments(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("coursework comments", flag.ExitOnError)
+	open := fs.Bool("open", false, "コメントを読み書きするために提出物のページをブラウザで開きます")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "使い方: classroom-api coursework comments [オプション] <課題>")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\n"+commentsUnsupported+"。コメントの読み書きは -open で開いたページで行ってください。")
+	}
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	a, err := findAssignmentArgs(items, fs.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if a.Submission == nil {
+		log.Fatalf("「%s」には提出物がありません", a.CourseWork.Title)
+	}
+	printSubmissionHistory(os.Stdout, a)
+	if *open {
+		if err := openBrowser(a.Submission.AlternateLink); err != nil {
+			log.Fatalf("ブラウザを開けませんでした: %v", err)
+		}
+	}
+}
+
+// printSubmissionHistory は提出物の状態と採点の履歴を表示します。
+func printSubmissionHistory(w io.Writer, a *Assignment) {
+	s := a.Submission
+	fmt.Fprintf(w, "%s (%s)\n", a.CourseWork.Title, a.Slug)
+	fmt.Fprintf(w, "提出状況: %s\n", submissionLabel(s))
+	if s.AssignedGrade > 0 || s.State == "RETURNED" {
+		fmt.Fprintf(w, "成績:     %g / %g\n", s.AssignedGrade, a.CourseWork.MaxPoints)
+	}
+	if len(s.SubmissionHistory) > 0 {
+		fmt.Fprintln(w, "\n履歴:")
+		for _, h := range s.SubmissionHistory {
+			fmt.Fprintf(w, "  %s\n", describeHistory(h, a.CourseWork.MaxPoints))
+		}
+	}
+	fmt.Fprintf(w, "\n%s\n%s\n", commentsUnsupported, s.AlternateLink)
+}
+
+func describeHistory(h *classroom.SubmissionHistory, maxPoints float64) string {
+	switch {
+	case h.StateHistory != nil:
+		label, ok := submissionStates[h.StateHistory.State]
+		if !ok {
+			label = h.StateHistory.State
+		}
+		return formatAPITime(h.StateHistory.StateTimestamp) + " " + label
+	case h.GradeHistory != nil:
+		g := h.GradeHistory
+		return fmt.Sprintf("%s 採点 %g / %g", formatAPITime(g.GradeTimestamp), g.PointsEarned, maxPoints)
+	}
+	return ""
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Config は設定ファイル (既定では config.json) の内容です。
+type Config struct {
+	// CourseIDs は課題を取得するコースのIDです。空の場合は在籍中のすべてのコースが対象になります。
+	CourseIDs []string `json:"courseIds"`
+	// CredentialsFile はOAuthクライアントの資格情報ファイルです。
+	CredentialsFile string `json:"credentialsFile"`
+	// TokenFile はアクセストークンを保存するファイルです。
+	TokenFile string `json:"tokenFile"`
+	// DataDir は状態ファイルを保存するディレクトリです。
+	DataDir string `json:"dataDir"`
+	// Courses はコースIDごとの設定です。
+	Courses map[string]*CourseConfig `json:"courses,omitempty"`
+	// Mirror は配布資料のミラー先の設定です。
+	Mirror *MirrorConfig `json:"mirror,omitempty"`
+	// Push はClassroomのプッシュ通知 (Cloud Pub/Sub) の設定です。
+	Push *PushConfig `json:"push,omitempty"`
+	// Polling はプッシュ通知を使えない場合のポーリング間隔の設定です。
+	Polling PollingConfig `json:"polling"`
+	// Timetable は週の時間割です。リマインダーの文面と授業後のまとめに使います。
+	Timetable []Lesson `json:"timetable,omitempty"`
+	// Notify は通知の送信先の設定です。
+	Notify NotifyConfig `json:"notify"`
+	// ICal はiCalendar形式の出力の設定です。
+	ICal ICalConfig `json:"ical"`
+	// ShareTemplate は課題を共有する文面のテンプレートです。コースごとの設定が優先されます。
+	ShareTemplate string `json:"shareTemplate,omitempty"`
+	// ReadOnly が true の場合は課題の提出などClassroomのデータを変更する操作をすべて拒否します。
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// Offline が true の場合はAPIを呼ばず、sync で保存したキャッシュだけを使います。
+	Offline bool `json:"offline,omitempty"`
+	// Server はローカルのAPIサーバーの設定です。
+	Server ServerConfig `json:"server"`
+	// Calendar はGoogleカレンダーとの同期の設定です。
+	Calendar CalendarConfig `json:"calendar"`
+	// Tasks はGoogle ToDoリストとの同期の設定です。
+	Tasks TasksConfig `json:"tasks"`
+	// Todoist はTodoistへの書き出しの設定です。
+	Todoist TodoistConfig `json:"todoist"`
+	// Notion はNotionのデータベースへの書き出しの設定です。
+	Notion NotionConfig `json:"notion"`
+	// Sheets はGoogleスプレッドシートへの書き出しの設定です。
+	Sheets SheetsConfig `json:"sheets"`
+	// Meta は課題に付けるメタデータ (スヌーズ、タグ、メモなど) の設定です。
+	Meta MetaConfig `json:"meta"`
+	// Client はAPIクライアントのログ、再試行、キャッシュ、流量制限の設定です。
+	Client ClientConfig `json:"client"`
+	// Cache は sync で作るローカルのキャッシュの設定です。
+	Cache CacheConfig `json:"cache"`
+	// Daemon は daemon コマンドで同期と通知を行う予定の設定です。
+	Daemon DaemonConfig `json:"daemon"`
+
+	// dryRun が true の場合は状態ファイルを書き込みません (-canary で使います)。
+	dryRun bool
+}
+
+// CourseConfig はコースごとの設定です。
+type CourseConfig struct {
+	// Alias はスラッグに使うコースの別名です。省略時はコース名から自動で作ります。
+	Alias string `json:"alias,omitempty"`
+	// GracePeriod は締切後も提出を受け付ける猶予期間です (例: "24h")。
+	GracePeriod Duration `json:"gracePeriod,omitempty"`
+	// ShareTemplate はこのコースの課題を共有する文面のテンプレートです。
+	ShareTemplate string `json:"shareTemplate,omitempty"`
+	// Reminders はこのコースの課題を締切のどれだけ前に通知するかです。省略した場合は notify.reminders を使います。
+	Reminders []Duration `json:"reminders,omitempty"`
+}
+
+// course はコースの設定を返します。設定がない場合はゼロ値を返します。
+func (c *Config) course(courseId string) *CourseConfig {
+	if cc, ok := c.Courses[courseId]; ok && cc != nil {
+		return cc
+	}
+	return &CourseConfig{}
+}
+
+// courseAlias は設定された別名があればそれを、なければ def を返します。
+func (c *Config) courseAlias(courseId, def string) string {
+	if alias := c.course(courseId).Alias; alias != "" {
+		return alias
+	}
+	return def
+}
+
+// shareTemplate はコースの課題を共有する文面のテンプレートを返します。
+func (c *Config) shareTemplate(courseId string) string {
+	if t := c.course(courseId).ShareTemplate; t != "" {
+		return t
+	}
+	if c.ShareTemplate != "" {
+		return c.ShareTemplate
+	}
+	return defaultShareTemplate
+}
+
+// loadConfig は設定ファイルを読み込みます。ファイルがない場合は既定値を返します。
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("設定ファイルを読み取れませんでした: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, cfg); err != nil {
+			return nil, fmt.Errorf("設定ファイルを解析できませんでした: %w", err)
+		}
+	}
+	if cfg.CredentialsFile == "" {
+		cfg.CredentialsFile = "client_secret.json"
+	}
+	if cfg.TokenFile == "" {
+		cfg.TokenFile = "token.json"
+	}
+	if cfg.DataDir == "" {
+		cfg.DataDir = "."
+	}
+	if err := cfg.checkAliases(); err != nil {
+		return nil, err
+	}
+	if cfg.Notify.templates, err = parseMessageTemplates(&cfg.Notify); err != nil {
+		return nil, err
+	}
+	if cfg.Notify.quietHours, err = parseQuietHours(cfg.Notify.QuietHours); err != nil {
+		return nil, err
+	}
+	if err := cfg.Polling.validate(); err != nil {
+		return nil, err
+	}
+	cfg.Polling.setDefaults()
+	cfg.Server.RateLimit.setDefaults()
+	return cfg, nil
+}
+
+// checkAliases は同じ別名が複数のコースに設定されていないかを確かめます。
+// 同じ別名があると、別名で指定したコースがどちらになるかが決まらないためです。
+func (c *Config) checkAliases() error {
+	ids := make([]string, 0, len(c.Courses))
+	for id := range c.Courses {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	owners := map[string]string{}
+	for _, id := range ids {
+		cc := c.Courses[id]
+		if cc == nil || cc.Alias == "" {
+			continue
+		}
+		if other, ok := owners[cc.Alias]; ok {
+			return fmt.Errorf("courses.%s.alias: 別名 %q はコース %s にも設定されています", id, cc.Alias, other)
+		}
+		owners[cc.Alias] = id
+	}
+	return nil
+}
+
+// dataPath は DataDir 配下のファイルパスを返します。
+func (c *Config) dataPath(name string) string {
+	return filepath.Join(c.DataDir, name)
+}
+
+// Duration は設定ファイルで "15m" のような文字列として書く時間の長さです。
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// readJSONFile はJSONファイルを読み込みます。ファイルがない場合は何もしません。
+func readJSONFile(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// writeJSONFile はJSONファイルを一時ファイル経由で書き込みます。
+func writeJSONFile(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
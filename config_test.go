@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigDuplicateAlias(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	tests := []struct {
+		json    string
+		wantErr bool
+	}{
+		{`{"courses": {"c1": {"alias": "math"}, "c2": {"alias": "eng"}}}`, false},
+		{`{"courses": {"c1": {"alias": "math"}, "c2": {"alias": "math"}}}`, true},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile(path, []byte(tt.json), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadConfig(path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.json, err, tt.wantErr)
+		}
+	}
+}
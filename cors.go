@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig は別のオリジンのWebページから /api と /graphql を呼ぶための設定です。
+type CORSConfig struct {
+	// AllowedOrigins は許可するオリジンです (例: "http://localhost:5173")。"*" はすべてのオリジンを許可します。
+	// 空の場合はCORSのヘッダーを返しません。
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
+	// AllowedMethods は許可するメソッドです。既定は GET と POST です。
+	AllowedMethods []string `json:"allowedMethods,omitempty"`
+	// AllowedHeaders は許可するリクエストヘッダーです。既定は Authorization と Content-Type です。
+	AllowedHeaders []string `json:"allowedHeaders,omitempty"`
+	// MaxAge はブラウザーがプリフライトの結果を使う期間です。既定は10分です。
+	MaxAge Duration `json:"maxAge,omitempty"`
+}
+
+// allowOrigin は origin を許可するかどうかを返します。
+func (c *CORSConfig) allowOrigin(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsMiddleware は /api と /graphql へのリクエストにCORSのヘッダーを付け、プリフライトの OPTIONS に応答します。
+// APIキーはヘッダーで送るため、クッキーを送る Access-Control-Allow-Credentials は返しません。
+// プリフライトにはAPIキーが付かないので、読み取り専用モードやAPIキーの確認より前に置いてください。
+func corsMiddleware(cfg CORSConfig, next http.Handler) http.Handler {
+	if len(cfg.AllowedOrigins) == 0 {
+		return next
+	}
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodPost}
+	}
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = []string{"Authorization", "Content-Type"}
+	}
+	maxAge := time.Duration(cfg.MaxAge)
+	if maxAge <= 0 {
+		maxAge = 10 * time.Minute
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") && r.URL.Path != "/graphql" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if origin == "" || !cfg.allowOrigin(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", "Retry-After, Link, "+nextPageTokenHeader)
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// プリフライト
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
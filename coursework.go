@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"runtime/trace"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Assignment は課題とその所属コース、自分の提出物をまとめたものです。
+type Assignment struct {
+	Course     *classroom.Course
+	CourseWork *classroom.CourseWork
+	Submission *classroom.StudentSubmission
+	// Topic は課題が属するトピックの名前です。トピックがない場合は空文字列です。
+	Topic string
+	// Slug は「コース別名/課題スラッグ」形式の識別子です。
+	Slug string
+	// Grace はコースの設定で与えられた締切後の猶予期間です。
+	Grace time.Duration
+}
+
+// Due は課題の締切をローカル時刻で返します。締切がない場合は false を返します。
+func (a *Assignment) Due() (time.Time, bool) {
+	return dueTime(a.CourseWork)
+}
+
+// EffectiveDue は猶予期間を含めた実質的な締切を返します。
+func (a *Assignment) EffectiveDue() (time.Time, bool) {
+	due, ok := a.Due()
+	return due.Add(a.Grace), ok
+}
+
+// State は提出物の状態を返します。提出物がない場合は空文字列です。
+func (a *Assignment) State() string {
+	if a.Submission == nil {
+		return ""
+	}
+	return a.Submission.State
+}
+
+// External は課題がClassroomの外部のツール (練習セットやアドオンなど) で取り組むものかどうかを返します。
+// 外部の課題はこのツールから提出できず、提出状況も正しく取れないため、件数の集計から除き、一覧では区別して表示します。
+func (a *Assignment) External() bool {
+	return isExternalCourseWork(a.CourseWork)
+}
+
+// isExternalCourseWork は課題の種類か資料がこのAPIで扱えない種類かどうかを返します。
+// アドオンの資料はAPIの応答で既知のフィールドがどれも設定されていない資料として返されます。
+func isExternalCourseWork(c *classroom.CourseWork) bool {
+	if _, ok := workTypes[c.WorkType]; !ok {
+		return true
+	}
+	for _, m := range c.Materials {
+		if m.DriveFile == nil && m.Form == nil && m.Link == nil && m.YoutubeVideo == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// dueTime は課題の締切をローカル時刻で返します。
+// Classroom の締切日時はUTCで表されます。時刻がない場合はその日の終わりとみなします。
+func dueTime(c *classroom.CourseWork) (time.Time, bool) {
+	if c.DueDate == nil {
+		return time.Time{}, false
+	}
+	d := c.DueDate
+	if c.DueTime == nil {
+		return time.Date(int(d.Year), time.Month(d.Month), int(d.Day), 23, 59, 0, 0, time.Local), true
+	}
+	t := c.DueTime
+	return time.Date(int(d.Year), time.Month(d.Month), int(d.Day), int(t.Hours), int(t.Minutes), int(t.Seconds), 0, time.UTC).Local(), true
+}
+
+// isCourseworkVisible は課題を未提出の一覧に表示するかどうかを判定します。
+func isCourseworkVisible(a *Assignment, now time.Time) bool {
+	// 実質的な締切日が今日より前の課題は表示しない
+	if due, ok := a.EffectiveDue(); ok {
+		y, m, d := now.Date()
+		if due.Before(time.Date(y, m, d, 0, 0, 0, 0, now.Location())) {
+			return false
+		}
+	}
+	//課題の提出状況を確認して、提出済みであれば表示しない
+	return a.State() != "TURNED_IN"
+}
+
+// listCourses は設定で指定されたコースを取得します。指定がない場合は在籍中のコースをすべて返します。
+func listCourses(ctx context.Context, srv *classroom.Service, cfg *Config) ([]*classroom.Course, error) {
+	return listCoursesAs(ctx, srv, cfg, "student")
+}
+
+// listTaughtCourses は設定で指定されたコースを取得します。指定がない場合は担当しているコースをすべて返します。
+func listTaughtCourses(ctx context.Context, srv *classroom.Service, cfg *Config) ([]*classroom.Course, error) {
+	return listCoursesAs(ctx, srv, cfg, "teacher")
+}
+
+func listCoursesAs(ctx context.Context, srv *classroom.Service, cfg *Config, role string) ([]*classroom.Course, error) {
+	if len(cfg.CourseIDs) == 0 {
+		call := srv.Courses.List().CourseStates("ACTIVE")
+		if role == "teacher" {
+			call.TeacherId("me")
+		} else {
+			call.StudentId("me")
+		}
+		var courses []*classroom.Course
+		err := call.Pages(ctx, func(r *classroom.ListCoursesResponse) error {
+			courses = append(courses, r.Courses...)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("コースを取得できませんでした: %w", err)
+		}
+		return courses, nil
+	}
+	courses := make([]*classroom.Course, 0, len(cfg.CourseIDs))
+	for _, id := range cfg.CourseIDs {
+		c, err := srv.Courses.Get(id).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("コース %s を取得できませんでした: %w", id, err)
+		}
+		courses = append(courses, c)
+	}
+	return courses, nil
+}
+
+// fetchAssignments はコースごとに並行して課題と提出物を取得します。
+func fetchAssignments(ctx context.Context, srv *classroom.Service, courses []*classroom.Course) ([]*Assignment, error) {
+	ctx, task := trace.NewTask(ctx, "List course work")
+	defer task.End()
+
+	ch := make(chan *Assignment)
+	errs := make(chan error, 1) // 最初のエラーだけを保持する
+	var wg sync.WaitGroup
+	p := newFetchProgress(len(courses))
+	defer p.finish()
+
+	for _, course := range courses {
+		wg.Add(1) // ゴルーチンを追加
+		go listCourseWorkFromCourseId(ctx, srv, course, ch, errs, &wg, p)
+	}
+	go func() {
+		wg.Wait()
+		close(ch) // ゴルーチンの終了後にチャネルを閉じる
+	}()
+
+	var items []*Assignment
+	for a := range ch {
+		items = append(items, a)
+	}
+	close(errs)
+	if err := <-errs; err != nil {
+		return items, err
+	}
+	sortAssignments(items)
+	return items, nil
+}
+
+func listCourseWorkFromCourseId(ctx context.Context, srv *classroom.Service, course *classroom.Course, ch chan<- *Assignment, errs chan<- error, wg *sync.WaitGroup, p *fetchProgress) {
+	defer trace.StartRegion(ctx, "listCourseWork").End()
+	defer wg.Done()
+	p.courseStarted(course.Name)
+	var err error
+	start := time.Now()
+	defer func() {
+		p.courseDone(course.Name, err)
+		courseFetchLatency.observe(time.Since(start), course.Name)
+	}()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pages, pageErr := streamCourseWork(ctx, srv, course.Id)
+	// 課題のページの取得と並行して、コースの提出物をまとめて取得する
+	subc := make(chan map[string]*classroom.StudentSubmission, 1)
+	subErr := make(chan error, 1)
+	go func() {
+		subs, err := mySubmissions(ctx, srv, course.Id)
+		subc <- subs
+		subErr <- err
+	}()
+	topics, err := listTopics(ctx, srv, course.Id)
+	if err != nil {
+		reportError(errs, fmt.Errorf("%s: %w", course.Name, err))
+		return
+	}
+	subs := <-subc
+	if err = <-subErr; err != nil {
+		reportError(errs, fmt.Errorf("%s: %w", course.Name, err))
+		return
+	}
+	for page := range pages {
+		for _, c := range page {
+			ch <- &Assignment{Course: course, CourseWork: c, Submission: subs[c.Id], Topic: topics[c.TopicId]}
+		}
+	}
+	if err = <-pageErr; err != nil {
+		reportError(errs, fmt.Errorf("課題を取得できませんでした (%s): %w", course.Name, err))
+	}
+}
+
+// streamCourseWork はコースの課題をページごとに送るチャネルを返します。
+// ページを送ったらすぐに次のページを先読みするため、受け取った側の処理と次の取得が重なります。
+// すべてのページを送り終えるとチャネルを閉じ、エラー (なければ nil) を返します。
+func streamCourseWork(ctx context.Context, srv *classroom.Service, courseId string) (<-chan []*classroom.CourseWork, <-chan error) {
+	pages := make(chan []*classroom.CourseWork, 1) // 1ページ分を先読みする
+	errc := make(chan error, 1)
+	go func() {
+		defer close(pages)
+		defer trace.StartRegion(ctx, "listCourseWorkPages").End()
+		call := srv.Courses.CourseWork.List(courseId)
+		token := ""
+		for {
+			r, err := call.PageToken(token).Context(ctx).Do()
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case pages <- r.CourseWork:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+			if r.NextPageToken == "" {
+				errc <- nil
+				return
+			}
+			token = r.NextPageToken
+		}
+	}()
+	return pages, errc
+}
+
+// reportError はまだエラーが記録されていなければ err を記録します。
+func reportError(errs chan<- error, err error) {
+	select {
+	case errs <- err:
+	default:
+	}
+}
+
+// mySubmissions はコースのすべての課題に対する自分の提出物を、課題のIDごとに返します。
+// 課題ごとに取得する代わりに、courseWorkId に "-" を指定して1回の一覧で取得します。
+func mySubmissions(ctx context.Context, srv *classroom.Service, courseId string) (map[string]*classroom.StudentSubmission, error) {
+	defer trace.StartRegion(ctx, "listSubmissions").End()
+	subs := map[string]*classroom.StudentSubmission{}
+	err := srv.Courses.CourseWork.StudentSubmissions.List(courseId, "-").UserId("me").Pages(ctx, func(r *classroom.ListStudentSubmissionsResponse) error {
+		for _, s := range r.StudentSubmissions {
+			if _, ok := subs[s.CourseWorkId]; !ok {
+				subs[s.CourseWorkId] = s
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("提出物を取得できませんでした: %w", err)
+	}
+	return subs, nil
+}
+
+// listTopics はコースのトピックIDと名前の対応を返します。
+func listTopics(ctx context.Context, srv *classroom.Service, courseId string) (map[string]string, error) {
+	topics := map[string]string{}
+	err := srv.Courses.Topics.List(courseId).Pages(ctx, func(r *classroom.ListTopicResponse) error {
+		for _, t := range r.Topic {
+			topics[t.TopicId] = t.Name
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("トピックを取得できませんでした: %w", err)
+	}
+	return topics, nil
+}
+
+// pendingAssignments は未提出の一覧に表示する課題だけを返します。
+func pendingAssignments(items []*Assignment, now time.Time) []*Assignment {
+	var pending []*Assignment
+	for _, a := range items {
+		if isCourseworkVisible(a, now) {
+			pending = append(pending, a)
+		}
+	}
+	return pending
+}
+
+// sortAssignments は締切の早い順に並べ替えます。締切のない課題は最後になります。
+func sortAssignments(items []*Assignment) {
+	sort.SliceStable(items, func(i, j int) bool {
+		di, oki := items[i].Due()
+		dj, okj := items[j].Due()
+		if oki != okj {
+			return oki
+		}
+		if !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		if items[i].Course.Name != items[j].Course.Name {
+			return items[i].Course.Name < items[j].Course.Name
+		}
+		return items[i].CourseWork.Title < items[j].CourseWork.Title
+	})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const courseworkUsageText = `使い方: classroom-api coursework <サブコマンド> [引数]
+
+サブコマンド:
+  show <コース> <課題>   課題の詳細を表示します
+  show <スラッグ>        課題の詳細を表示します
+  open <番号|課題>       課題をブラウザで開きます (番号は list の表示番号)
+  comments <課題>        提出物の返却・採点の履歴とコメントのページを表示します
+                         (APIが対応していないため、コメントの取得・投稿はできません)
+  share <課題>           グループチャットに投稿する共有メッセージを作ります
+
+コースにはコースIDまたは別名、課題には課題IDまたはスラッグを指定できます。
+`
+
+func runCoursework(ctx context.Context, cfg *Config, args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, courseworkUsageText)
+		os.Exit(2)
+	}
+	checkReadOnly(cfg, "coursework "+args[0])
+	switch args[0] {
+	case "show":
+		runCourseworkShow(ctx, cfg, args[1:])
+	case "open":
+		runCourseworkOpen(ctx, cfg, args[1:])
+	case "comments":
+		runCourseworkComments(ctx, cfg, args[1:])
+	case "share":
+		runCourseworkShare(ctx, cfg, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "不明なサブコマンドです: %s\n\n", args[0])
+		fmt.Fprint(os.Stderr, courseworkUsageText)
+		os.Exit(2)
+	}
+}
+
+func runCourseworkShow(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("coursework show", flag.ExitOnError)
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	a, err := findAssignmentArgs(items, fs.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	printAssignmentDetail(os.Stdout, a)
+}
+
+// findAssignmentArgs はコマンドライン引数で指定された課題を探します。
+// 引数は「課題」または「コース 課題」の形式です。
+func findAssignmentArgs(items []*Assignment, args []string) (*Assignment, error) {
+	switch len(args) {
+	case 1:
+		return findAssignment(items, args[0])
+	case 2:
+		var inCourse []*Assignment
+		for _, a := range items {
+			alias, _, _ := strings.Cut(a.Slug, "/")
+			if args[0] == a.Course.Id || args[0] == alias {
+				inCourse = append(inCourse, a)
+			}
+		}
+		if len(inCourse) == 0 {
+			return nil, fmt.Errorf("コースが見つかりません: %s", args[0])
+		}
+		return findAssignment(inCourse, args[1])
+	default:
+		return nil, fmt.Errorf("課題を「<コース> <課題>」または「<スラッグ>」で指定してください")
+	}
+}
+
+// printAssignmentDetail は課題の詳細を表示します。
+func printAssignmentDetail(w io.Writer, a *Assignment) {
+	c := a.CourseWork
+	fmt.Fprintf(w, "タイトル: %s\n", c.Title)
+	fmt.Fprintf(w, "スラッグ: %s (ID: %s)\n", a.Slug, c.Id)
+	fmt.Fprintf(w, "コース:   %s\n", a.Course.Name)
+	fmt.Fprintf(w, "種類:     %s\n", workTypeLabel(c.WorkType))
+	if a.Topic != "" {
+		fmt.Fprintf(w, "トピック: %s\n", a.Topic)
+	}
+	if c.MaxPoints > 0 {
+		fmt.Fprintf(w, "配点:     %g\n", c.MaxPoints)
+	}
+	if due, ok := a.Due(); ok {
+		fmt.Fprintf(w, "締切:     %s\n", formatTime(due))
+		if a.Grace > 0 {
+			eff, _ := a.EffectiveDue()
+			fmt.Fprintf(w, "実質締切: %s (猶予 %s)\n", formatTime(eff), formatDuration(a.Grace))
+		}
+	} else {
+		fmt.Fprintln(w, "締切:     なし")
+	}
+	fmt.Fprintf(w, "作成:     %s\n", formatAPITime(c.CreationTime))
+	fmt.Fprintf(w, "更新:     %s\n", formatAPITime(c.UpdateTime))
+	fmt.Fprintf(w, "提出状況: %s\n", submissionLabel(a.Submission))
+	fmt.Fprintf(w, "リンク:   %s\n", c.AlternateLink)
+	if c.Description != "" {
+		fmt.Fprintln(w, "\n説明:")
+		for _, line := range strings.Split(c.Description, "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+	if len(c.Materials) > 0 {
+		fmt.Fprintln(w, "\n資料:")
+		for _, m := range c.Materials {
+			kind, title, link := describeMaterial(m)
+			fmt.Fprintf(w, "  - [%s] %s %s\n", kind, title, link)
+		}
+	}
+}
+
+// describeMaterial は添付資料の種類、タイトル、リンクを返します。
+func describeMaterial(m *classroom.Material) (kind, title, link string) {
+	switch {
+	case m.DriveFile != nil && m.DriveFile.DriveFile != nil:
+		return "Drive", m.DriveFile.DriveFile.Title, m.DriveFile.DriveFile.AlternateLink
+	case m.Link != nil:
+		return "リンク", m.Link.Title, m.Link.Url
+	case m.YoutubeVideo != nil:
+		return "YouTube", m.YoutubeVideo.Title, m.YoutubeVideo.AlternateLink
+	case m.Form != nil:
+		return "フォーム", m.Form.Title, m.Form.FormUrl
+	}
+	return "不明", "", ""
+}
+
+// submissionStates は提出物の状態と表示名の対応です。
+var submissionStates = map[string]string{
+	"NEW":                  "未提出",
+	"CREATED":              "未提出",
+	"TURNED_IN":            "提出済み",
+	"RETURNED":             "返却済み",
+	"RECLAIMED_BY_STUDENT": "提出を取り消し",
+}
+
+// submissionLabel は提出物の状態の表示名を返します。
+func submissionLabel(s *classroom.StudentSubmission) string {
+	if s == nil {
+		return "提出物なし"
+	}
+	label, ok := submissionStates[s.State]
+	if !ok {
+		label = s.State
+	}
+	if s.Late {
+		label += " (遅延)"
+	}
+	return label
+}
+
+var weekdays = [...]string{"日", "月", "火", "水", "木", "金", "土"}
+
+// formatTime は日時を「2006-01-02 (月) 15:04」の形式で返します。
+func formatTime(t time.Time) string {
+	return fmt.Sprintf("%s (%s) %s", t.Format("2006-01-02"), weekdays[t.Weekday()], t.Format("15:04"))
+}
+
+// formatAPITime はAPIが返すRFC3339形式の日時をローカル時刻で表示します。
+func formatAPITime(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return formatTime(t.Local())
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule は「分 時 日 月 曜日」の5つのフィールドからなるcron式です。
+// 各フィールドには *、数、範囲 (1-5)、間隔 (*/15, 7-21/2)、それらのカンマ区切りを書けます。
+// 曜日は 0 (日曜日) から 6 (土曜日) で、7 も日曜日です。日と曜日の両方を指定した場合はどちらかに一致すれば実行します。
+type cronSchedule struct {
+	minute, hour, dom, month, dow cronField
+	// domAny と dowAny は日と曜日が * かどうかです。
+	domAny, dowAny bool
+}
+
+// cronField は各値が一致するかどうかです。
+type cronField []bool
+
+// parseCron はcron式を解析します。
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron式は「分 時 日 月 曜日」の5つのフィールドで書いてください: %q", expr)
+	}
+	s := &cronSchedule{domAny: fields[2] == "*", dowAny: fields[4] == "*"}
+	var err error
+	for _, f := range []struct {
+		dst      *cronField
+		text     string
+		min, max int
+		name     string
+	}{
+		{&s.minute, fields[0], 0, 59, "分"},
+		{&s.hour, fields[1], 0, 23, "時"},
+		{&s.dom, fields[2], 1, 31, "日"},
+		{&s.month, fields[3], 1, 12, "月"},
+		{&s.dow, fields[4], 0, 7, "曜日"},
+	} {
+		if *f.dst, err = parseCronField(f.text, f.min, f.max); err != nil {
+			return nil, fmt.Errorf("cron式 %q の%sを解析できませんでした: %w", expr, f.name, err)
+		}
+	}
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	return s, nil
+}
+
+func parseCronField(text string, min, max int) (cronField, error) {
+	f := make(cronField, max+1)
+	for _, part := range strings.Split(text, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return nil, fmt.Errorf("間隔が正しくありません: %s", part)
+			}
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			loText, hiText, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(loText); err != nil {
+				return nil, fmt.Errorf("数ではありません: %s", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiText); err != nil {
+					return nil, fmt.Errorf("数ではありません: %s", part)
+				}
+			} else if hasStep {
+				// "5/15" は 5 から最大値まで15ごと
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%d から %d の範囲で指定してください: %s", min, max, part)
+		}
+		for v := lo; v <= hi; v += step {
+			f[v] = true
+		}
+	}
+	return f, nil
+}
+
+// matchDay は日付が日と曜日のフィールドに一致するかどうかを返します。
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	if !s.month[t.Month()] {
+		return false
+	}
+	dom, dow := s.dom[t.Day()], s.dow[t.Weekday()]
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// next は t より後で最も早い実行の時刻を返します。4年以内に実行の時刻がない場合 (2月30日など) はゼロ値を返します。
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(4, 0, 0)
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		if !s.matchDay(t) {
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hour[t.Hour()] {
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		text     string
+		min, max int
+		want     []int
+	}{
+		{"*", 0, 5, []int{0, 1, 2, 3, 4, 5}},
+		{"3", 0, 5, []int{3}},
+		{"1-3", 0, 5, []int{1, 2, 3}},
+		{"1,4", 0, 5, []int{1, 4}},
+		{"*/15", 0, 59, []int{0, 15, 30, 45}},
+		{"7-21/7", 0, 23, []int{7, 14, 21}},
+		{"5/20", 0, 59, []int{5, 25, 45}},
+		{"1-2,10-11", 1, 12, []int{1, 2, 10, 11}},
+	}
+	for _, tt := range tests {
+		f, err := parseCronField(tt.text, tt.min, tt.max)
+		if err != nil {
+			t.Errorf("parseCronField(%q): %v", tt.text, err)
+			continue
+		}
+		var got []int
+		for v, ok := range f {
+			if ok {
+				got = append(got, v)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseCronField(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseCronError(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-b * * * *",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) はエラーになるはずです", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2026-10-16 は金曜日
+	at := func(mon time.Month, d, h, m int) time.Time { return time.Date(2026, mon, d, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		// ちょうど実行の時刻の場合は次の実行
+		{"*/15 * * * *", at(10, 16, 3, 0), at(10, 16, 3, 15)},
+		{"*/15 * * * *", at(10, 16, 3, 14), at(10, 16, 3, 15)},
+		{"*/15 * * * *", at(10, 16, 3, 0).Add(59 * time.Second), at(10, 16, 3, 15)},
+		{"*/15 * * * *", at(10, 16, 23, 45), at(10, 17, 0, 0)},
+		{"0 3 * * *", at(10, 16, 3, 0), at(10, 17, 3, 0)},
+		{"0 3 * * *", at(10, 16, 2, 59).Add(30 * time.Second), at(10, 16, 3, 0)},
+		{"*/15 7-21 * * 1-5", at(10, 16, 21, 45), at(10, 19, 7, 0)},
+		{"0 0 1 * *", at(12, 31, 12, 0), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// 7 も日曜日
+		{"0 9 * * 7", at(10, 16, 12, 0), at(10, 18, 9, 0)},
+		{"0 9 * * 0", at(10, 16, 12, 0), at(10, 18, 9, 0)},
+		// 日と曜日の両方を指定した場合はどちらかに一致すれば実行する
+		{"0 9 20 * 6", at(10, 16, 12, 0), at(10, 17, 9, 0)},
+		{"0 9 20 * 6", at(10, 17, 12, 0), at(10, 20, 9, 0)},
+		// 日だけを指定した場合は曜日を問わない
+		{"0 9 20 * *", at(10, 16, 12, 0), at(10, 20, 9, 0)},
+		{"0 0 29 2 *", at(10, 16, 0, 0), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// 実行の時刻がない
+		{"0 0 30 2 *", at(10, 16, 0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := s.next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q.next(%s) = %s, want %s", tt.expr, tt.from.Format(time.DateTime), got.Format(time.DateTime), tt.want.Format(time.DateTime))
+		}
+	}
+}
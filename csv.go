@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// csvHeader はCSVの列見出しです。csvRecord の列と順番を合わせてください。
+var csvHeader = []string{
+	"コース", "タイトル", "種類", "締切日", "締切時刻", "配点",
+	"提出状況", "遅延", "点数", "課題のリンク", "提出物のリンク",
+}
+
+func runExportCSV(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export csv", flag.ExitOnError)
+	out := fs.String("out", "", "出力ファイル (省略時は標準出力)")
+	pendingOnly := fs.Bool("pending", false, "未提出の課題だけを出力します")
+	bom := fs.Bool("bom", true, "Excelで文字化けしないように先頭にBOMを付けます")
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *pendingOnly {
+		items = pendingAssignments(items, time.Now())
+	}
+	items = filter.apply(items)
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("出力ファイルを作成できませんでした: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if *bom {
+		io.WriteString(w, "\uFEFF")
+	}
+	if err := writeCSV(w, items); err != nil {
+		log.Fatalf("CSVを書き込めませんでした: %v", err)
+	}
+}
+
+// writeCSV は課題を1行ずつCSVで書き出します。
+func writeCSV(w io.Writer, items []*Assignment) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, a := range items {
+		if err := cw.Write(csvRecord(a)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvRecord は課題のCSVの1行を返します。
+func csvRecord(a *Assignment) []string {
+	c := a.CourseWork
+	var dueDate, dueTime, points string
+	if due, ok := a.Due(); ok {
+		dueDate, dueTime = due.Format("2006-01-02"), due.Format("15:04")
+	}
+	if c.MaxPoints > 0 {
+		points = strconv.FormatFloat(c.MaxPoints, 'f', -1, 64)
+	}
+	state, late, grade, link := "提出物なし", "", "", ""
+	if s := a.Submission; s != nil {
+		if label, ok := submissionStates[s.State]; ok {
+			state = label
+		} else {
+			state = s.State
+		}
+		late = fmt.Sprint(s.Late)
+		if isGraded(a) {
+			grade = strconv.FormatFloat(s.AssignedGrade, 'f', -1, 64)
+		}
+		link = s.AlternateLink
+	}
+	return []string{
+		a.Course.Name, c.Title, workTypeLabel(c.WorkType), dueDate, dueTime, points,
+		state, late, grade, c.AlternateLink, link,
+	}
+}
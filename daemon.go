@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// defaultDaemonSchedule は DaemonConfig.Schedule を省略したときの同期の予定です。
+var defaultDaemonSchedule = []string{"*/15 * * * *"}
+
+// DaemonConfig は daemon コマンドの設定です。
+type DaemonConfig struct {
+	// Schedule は同期して通知を送る時刻のcron式 (分 時 日 月 曜日) です。複数書いた場合はどれかに一致すれば実行します。
+	// 例: 平日の7時から22時まで15分ごとに同期する場合は ["*/15 7-21 * * 1-5", "0 22 * * 1-5"]。
+	// 省略した場合は15分ごとです。
+	Schedule []string `json:"schedule,omitempty"`
+	// FullSync は削除された課題も反映するため、すべての課題を取得し直す時刻のcron式です。省略した場合は行いません。
+	FullSync string `json:"fullSync,omitempty"`
+	// Healthcheck は同期するたびに死活監視のサービスに送る ping の設定です。
+	Healthcheck *HealthcheckConfig `json:"healthcheck,omitempty"`
+}
+
+// schedules は設定のcron式を解析します。
+func (dc *DaemonConfig) schedules() ([]*cronSchedule, error) {
+	exprs := dc.Schedule
+	if len(exprs) == 0 {
+		exprs = defaultDaemonSchedule
+	}
+	var ss []*cronSchedule
+	for _, e := range exprs {
+		s, err := parseCron(e)
+		if err != nil {
+			return nil, err
+		}
+		ss = append(ss, s)
+	}
+	return ss, nil
+}
+
+// nextRun は schedules のうち now より後で最も早い実行の時刻を返します。
+func nextRun(schedules []*cronSchedule, now time.Time) time.Time {
+	var next time.Time
+	for _, s := range schedules {
+		if t := s.next(now); !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next
+}
+
+// runDaemon は予定の時刻ごとにキャッシュを同期し、新しい課題・締切の変更・リマインダー・毎日のまとめを通知し続けます。
+// 起動したときにもすぐに1回同期します。各回では watch と同じく polling の設定に従って取得の時刻になったコースだけを取得し、
+// 締切の近い課題のあるコースは毎回、未提出の課題のないコースはまれに取得します。
+// メタデータのDriveとの同期、今週の課題を終えたときのお祝い、配布資料のミラーも行います。
+func runDaemon(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	schedule := fs.String("schedule", "", "同期する時刻のcron式 (設定の daemon.schedule の代わりに使います。例: \"*/15 7-21 * * 1-5\")")
+	fs.Parse(args)
+	if *schedule != "" {
+		cfg.Daemon.Schedule = []string{*schedule}
+	}
+	schedules, err := cfg.Daemon.schedules()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var full *cronSchedule
+	if cfg.Daemon.FullSync != "" {
+		if full, err = parseCron(cfg.Daemon.FullSync); err != nil {
+			log.Fatal(err)
+		}
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := newHTTPClient(cfg)
+	srv := newClassroomService(ctx, client)
+	db, err := openCache(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	w, err := newWatcher(ctx, cfg, srv, newCourseScheduler(&cfg.Polling, syncMode{Reason: "daemon"}, 0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 前回までのキャッシュと比べて、止まっていた間の新しい課題と変更も通知する
+	if _, before, _, err := readCache(ctx, db); err == nil && applyCourseConfig(cfg, before) == nil {
+		_, w.seen = diffPending(nil, before, time.Now())
+	} else if err != nil && !errors.Is(err, errCacheEmpty) {
+		log.Printf("キャッシュを読み取れませんでした: %v", err)
+	}
+	d := &daemon{cfg: cfg, db: db, w: w}
+	if cfg.Mirror != nil && cfg.Mirror.Target != "" {
+		if d.mirror, err = newMirrorTarget(cfg.Mirror); err != nil {
+			log.Fatal(err)
+		}
+		if d.dsrv, err = drive.NewService(ctx, option.WithHTTPClient(client)); err != nil {
+			log.Fatalf("Driveクライアントを作成できませんでした: %v", err)
+		}
+	}
+	last := time.Now()
+	d.tick(ctx, false, last)
+	sdNotify("READY=1")
+	for {
+		next := nextRun(schedules, time.Now())
+		if next.IsZero() {
+			log.Fatal("daemon.schedule に実行の時刻がありません")
+		}
+		log.Printf("次の同期は %s です", formatTime(next))
+		sdNotify("STATUS=次の同期は " + formatTime(next) + " です")
+		if sleepContext(ctx, time.Until(next)) != nil {
+			sdNotify("STOPPING=1")
+			return
+		}
+		fullSync := fullSyncDue(full, last, next)
+		last = next
+		d.tick(ctx, fullSync, time.Now())
+	}
+}
+
+// fullSyncDue は前回の同期 last より後、今回の同期 next までに全件の同期の時刻があるかどうかを返します。
+// last と同じ時刻は前回の同期で済んでいるため含めません。
+func fullSyncDue(full *cronSchedule, last, next time.Time) bool {
+	if full == nil {
+		return false
+	}
+	t := full.next(last)
+	return !t.IsZero() && !t.After(next)
+}
+
+// daemon は daemon コマンドの同期と通知の状態です。
+// 課題を取得するたびの通知は watch と同じ watcher で行い、取得した課題はキャッシュに保存します。
+type daemon struct {
+	cfg *Config
+	db  *sql.DB
+	w   *watcher
+	// mirror は配布資料のミラー先です。mirror を設定していない場合は nil です。
+	mirror mirrorTarget
+	dsrv   *drive.Service
+}
+
+// tick は1回同期して通知し、結果を死活監視に送ります。失敗してもログに書いて次の予定を待ちます。
+func (d *daemon) tick(ctx context.Context, full bool, now time.Time) {
+	err := d.run(ctx, full, now)
+	if ctx.Err() != nil {
+		// 終了するときに取り消された同期は失敗として送らない
+		return
+	}
+	if err != nil {
+		log.Print(err)
+	}
+	d.cfg.Daemon.Healthcheck.ping(ctx, err)
+}
+
+// run は取得の時刻になったコースをキャッシュに同期し、前回の同期からの変更とリマインダーを通知します。
+// full の場合はすべてのコースを取得し直します。
+func (d *daemon) run(ctx context.Context, full bool, now time.Time) error {
+	if full {
+		d.w.sched.expireAll()
+	}
+	fetch := func(ctx context.Context, courses, due []*classroom.Course) ([]*Assignment, error) {
+		if err := syncCourses(ctx, d.w.srv, d.db, courses, due, full, now); err != nil {
+			return nil, fmt.Errorf("キャッシュを同期できませんでした: %w", err)
+		}
+		_, items, _, err := readCache(ctx, d.db)
+		if err == nil {
+			err = applyCourseConfig(d.cfg, items)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("キャッシュを読み取れませんでした: %w", err)
+		}
+		return items, nil
+	}
+	if _, _, err := d.w.tick(ctx, fetch, now); err != nil {
+		return err
+	}
+	if d.mirror != nil {
+		// ミラーの失敗は通知の同期の失敗として扱わない
+		if err := syncMirror(ctx, d.cfg, d.w.srv, d.dsrv, d.mirror); err != nil {
+			log.Printf("ミラーの同期に失敗しました: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFullSyncDue(t *testing.T) {
+	full, err := parseCron("0 3 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	never, err := parseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(h, m int) time.Time { return time.Date(2026, 10, 16, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		name       string
+		full       *cronSchedule
+		last, next time.Time
+		want       bool
+	}{
+		{"設定なし", nil, at(2, 45), at(3, 0), false},
+		{"今回の同期が全件の同期の時刻", full, at(2, 45), at(3, 0), true},
+		{"前回の同期が全件の同期の時刻", full, at(3, 0), at(3, 15), false},
+		{"間に全件の同期の時刻がある", full, at(2, 50), at(3, 5), true},
+		{"間に全件の同期の時刻がない", full, at(3, 15), at(3, 30), false},
+		{"起動した直後", full, at(2, 58).Add(30 * time.Second), at(3, 0), true},
+		{"実行の時刻がない", never, at(2, 45), at(3, 0), false},
+	}
+	for _, tt := range tests {
+		if got := fullSyncDue(tt.full, tt.last, tt.next); got != tt.want {
+			t.Errorf("%s: fullSyncDue(%s, %s) = %v, want %v", tt.name, tt.last.Format("15:04:05"), tt.next.Format("15:04"), got, tt.want)
+		}
+	}
+}
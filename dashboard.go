@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// dashboardPage はダッシュボードに渡すデータです。
+type dashboardPage struct {
+	Generated string
+	Total     int
+	Courses   []*htmlCourse
+	// Key は ?key= で渡されたAPIキーで、更新ボタンのリンクに引き継ぎます。
+	Key string
+	// User は複数ユーザーモードでログインしているユーザーのメールアドレスです。
+	User string
+}
+
+// dashboardTemplate はサーバーの / で表示するダッシュボードです。
+// コースごとの表示・非表示はブラウザの localStorage に保存します。
+// /ws に接続し、サーバーが見つけた課題の変更を再読み込みせずに表に反映します。
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>未提出の課題 ({{.Total}})</title>
+<style>
+body { font-family: system-ui, -apple-system, "Hiragino Sans", "Yu Gothic UI", sans-serif; margin: 2rem; color: #222; }
+h1 { font-size: 1.5rem; }
+header { display: flex; align-items: baseline; gap: 1rem; flex-wrap: wrap; }
+.generated { color: #666; font-size: .9rem; }
+details { margin-top: 1.5rem; }
+summary { font-size: 1.15rem; font-weight: bold; cursor: pointer; }
+summary .count { color: #666; font-weight: normal; font-size: .95rem; }
+table { border-collapse: collapse; width: 100%; margin-top: .5rem; }
+th, td { padding: .4rem .6rem; border-bottom: 1px solid #ddd; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; white-space: nowrap; }
+tr.urgent td { background: #fde2e2; }
+tr.soon td { background: #fff6d6; }
+tr.new td:first-child { border-left: 4px solid #3b82f6; }
+td.num { text-align: right; }
+.empty { color: #666; }
+a.button { padding: .3rem .8rem; border: 1px solid #888; border-radius: 4px; color: inherit; text-decoration: none; }
+</style>
+</head>
+<body>
+<header>
+<h1>未提出の課題 (<span id="total">{{.Total}}</span>)</h1>
+<a class="button" href="/?refresh=1{{if .Key}}&amp;key={{.Key}}{{end}}">更新</a>
+{{if .User}}<form method="post" action="/logout" class="generated">{{.User}} <button type="submit">ログアウト</button></form>{{end}}
+<span class="generated">{{.Generated}} 時点 ・ <span style="background:#fde2e2">24時間以内</span> <span style="background:#fff6d6">3日以内</span></span>
+</header>
+{{range .Courses}}
+<details open data-course="{{.ID}}">
+<summary>{{.Name}} <span class="count">{{len .Rows}}件</span>{{if .Link}} <a href="{{.Link}}">Classroom</a>{{end}}</summary>
+<table>
+<thead><tr><th>課題</th><th>種類</th><th>トピック</th><th>締切</th><th>残り</th><th>配点</th><th>状態</th></tr></thead>
+<tbody>
+{{range .Rows}}{{template "row" .}}
+{{end}}</tbody>
+</table>
+</details>
+{{else}}<p class="empty">未提出の課題はありません。</p>
+{{end}}
+<p id="notice" hidden>ほかのコースに新しい課題があります。<a href="/?refresh=1{{if .Key}}&amp;key={{.Key}}{{end}}">再読み込み</a></p>
+<script>
+document.querySelectorAll("details[data-course]").forEach(function (d) {
+  var key = "classroom-api.hidden." + d.dataset.course;
+  if (localStorage.getItem(key) === "1") { d.open = false; }
+  d.addEventListener("toggle", function () {
+    if (d.open) { localStorage.removeItem(key); } else { localStorage.setItem(key, "1"); }
+  });
+});
+// /ws から届いた新しい課題・変更された課題の行を差し替え、提出済みになった課題の行を消す
+(function () {
+  var key = new URLSearchParams(location.search).get("key");
+  var url = (location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/ws" + (key ? "?key=" + encodeURIComponent(key) : "");
+  var ws = new WebSocket(url);
+  ws.onmessage = function (ev) {
+    var u = JSON.parse(ev.data);
+    document.getElementById("total").textContent = u.total;
+    document.title = "未提出の課題 (" + u.total + ")";
+    (u.removed || []).forEach(function (id) {
+      var tr = document.querySelector('tr[data-id="' + id + '"]');
+      if (tr) { tr.remove(); }
+    });
+    (u.rows || []).forEach(function (row) {
+      var body = document.querySelector('details[data-course="' + row.courseId + '"] tbody');
+      if (!body) { document.getElementById("notice").hidden = false; return; }
+      var tmp = document.createElement("tbody");
+      tmp.innerHTML = row.html;
+      var tr = tmp.firstElementChild;
+      tr.classList.add("new");
+      var old = body.querySelector('tr[data-id="' + row.id + '"]');
+      if (old) { body.replaceChild(tr, old); } else { body.appendChild(tr); }
+    });
+  };
+})();
+</script>
+</body>
+</html>
+{{define "row"}}<tr data-id="{{.ID}}" class="{{.Class}}"><td><a href="{{.Link}}">{{.Title}}</a></td><td>{{.Type}}</td><td>{{.Topic}}</td><td>{{.Due}}</td><td>{{.DueIn}}</td><td class="num">{{.Points}}</td><td>{{.State}}</td></tr>{{end}}`))
+
+// handleDashboard は未提出の課題をコースごとにまとめたHTMLを返します。
+// ?refresh=1 を付けると保持している取得結果を使わずに取得し直します。
+func (s *server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	a, err := s.requestAccount(r)
+	if errors.Is(err, errNotLoggedIn) {
+		login := "/login"
+		if key := r.URL.Query().Get("key"); key != "" {
+			login += "?key=" + url.QueryEscape(key)
+		}
+		http.Redirect(w, r, login, http.StatusFound)
+		return
+	}
+	if r.URL.Query().Get("refresh") != "" {
+		a.reset()
+	}
+	now := time.Now()
+	pending, ok := s.pending(w, r, now)
+	if !ok {
+		return
+	}
+	page := &dashboardPage{
+		Generated: formatTime(now),
+		Total:     countSubmittable(pending),
+		Courses:   groupHTMLCourses(pending, now),
+		Key:       r.URL.Query().Get("key"),
+	}
+	if u := s.sessionUser(r); u != nil {
+		page.User = u.Email
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, page); err != nil {
+		log.Printf("ダッシュボードを書き込めませんでした: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// deliveriesFile は通知の送信先ごとに送ったできごとを記録するファイルです。
+	deliveriesFile = "deliveries.json"
+	// deliveryRetention は送った記録を残す期間です。これより古い記録は保存するときに消します。
+	deliveryRetention = 60 * 24 * time.Hour
+)
+
+// deliveryMu はプロセス内で deliveries.json の読み書きを1つずつにします。
+// serve ではアカウントごとに通知の送信先を作るため、記録はファイルを通して共有します。
+var deliveryMu sync.Mutex
+
+// deliveryStore は Event.Key を持つできごとを送信先ごとに記録し、同じできごとを同じ送信先に2回送らないようにします。
+type deliveryStore struct {
+	path string
+	// dryRun が true の場合は記録を書き込みません。
+	dryRun bool
+}
+
+// deliveryState は送信先の名前ごとの、送ったできごとのキーと送った時刻です。
+type deliveryState struct {
+	Sent map[string]map[string]time.Time `json:"sent"`
+}
+
+func newDeliveryStore(cfg *Config) *deliveryStore {
+	return &deliveryStore{path: cfg.dataPath(deliveriesFile), dryRun: cfg.dryRun}
+}
+
+func (s *deliveryStore) load() (*deliveryState, error) {
+	st := &deliveryState{}
+	if err := readJSONFile(s.path, st); err != nil {
+		return nil, fmt.Errorf("%s を読み取れませんでした: %w", deliveriesFile, err)
+	}
+	if st.Sent == nil {
+		st.Sent = map[string]map[string]time.Time{}
+	}
+	return st, nil
+}
+
+func (s *deliveryStore) save(st *deliveryState, now time.Time) error {
+	if s.dryRun {
+		return nil
+	}
+	for sink, keys := range st.Sent {
+		for key, t := range keys {
+			if now.Sub(t) > deliveryRetention {
+				delete(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			delete(st.Sent, sink)
+		}
+	}
+	return writeJSONFile(s.path, st)
+}
+
+// claim はできごとをまだ送信先に送っていなければ送ったことにして true を返します。
+// 送れなかった場合は release で取り消します。
+func (s *deliveryStore) claim(sink, key string, now time.Time) (bool, error) {
+	deliveryMu.Lock()
+	defer deliveryMu.Unlock()
+	st, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := st.Sent[sink][key]; ok {
+		return false, nil
+	}
+	if st.Sent[sink] == nil {
+		st.Sent[sink] = map[string]time.Time{}
+	}
+	st.Sent[sink][key] = now
+	return true, s.save(st, now)
+}
+
+// release は claim で送ったことにしたできごとの記録を消します。
+func (s *deliveryStore) release(sink, key string) error {
+	deliveryMu.Lock()
+	defer deliveryMu.Unlock()
+	st, err := s.load()
+	if err != nil {
+		return err
+	}
+	delete(st.Sent[sink], key)
+	return s.save(st, time.Now())
+}
+
+// dedupNotifier は送ったことのあるできごとを送らない送信先です。Key が空のできごとは毎回送ります。
+type dedupNotifier struct {
+	Notifier
+	// id は記録に使う送信先の名前です。同じ種類の送信先が複数ある場合にも区別できるようにします。
+	id    string
+	store *deliveryStore
+}
+
+func (n *dedupNotifier) Notify(ctx context.Context, ev *Event) error {
+	if ev.Key == "" {
+		return n.Notifier.Notify(ctx, ev)
+	}
+	ok, err := n.store.claim(n.id, ev.Key, ev.Time)
+	if err != nil {
+		// 記録を読み書きできなくても通知は送る
+		log.Print(err)
+	}
+	if !ok && err == nil {
+		return nil
+	}
+	if err := n.Notifier.Notify(ctx, ev); err != nil {
+		if ok {
+			if err := n.store.release(n.id, ev.Key); err != nil {
+				log.Print(err)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func runDeliveries(ctx context.Context, cfg *Config, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "使い方: classroom-api deliveries <list|reset> [オプション]")
+		os.Exit(2)
+	}
+	store := newDeliveryStore(cfg)
+	switch args[0] {
+	case "list":
+		st, err := store.load()
+		if err != nil {
+			log.Fatal(err)
+		}
+		sinks := make([]string, 0, len(st.Sent))
+		for sink := range st.Sent {
+			sinks = append(sinks, sink)
+		}
+		sort.Strings(sinks)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "送信先\t件数\t最後に送った時刻")
+		for _, sink := range sinks {
+			var last time.Time
+			for _, t := range st.Sent[sink] {
+				if t.After(last) {
+					last = t
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", sink, len(st.Sent[sink]), formatTime(last))
+		}
+		tw.Flush()
+	case "reset":
+		fs := flag.NewFlagSet("deliveries reset", flag.ExitOnError)
+		sink := fs.String("sink", "", "記録を消す送信先の名前 (deliveries list の表示)。省略した場合はすべての送信先の記録を消します")
+		fs.Parse(args[1:])
+		deliveryMu.Lock()
+		defer deliveryMu.Unlock()
+		st, err := store.load()
+		if err != nil {
+			log.Fatal(err)
+		}
+		n := 0
+		for name, keys := range st.Sent {
+			if *sink == "" || name == *sink {
+				n += len(keys)
+				delete(st.Sent, name)
+			}
+		}
+		if err := store.save(st, time.Now()); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%d件の送信の記録を消しました。同じ通知をもう一度送れるようになります。\n", n)
+	default:
+		fmt.Fprintf(os.Stderr, "不明なサブコマンドです: %s\n", args[0])
+		os.Exit(2)
+	}
+}
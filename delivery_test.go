@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyNotifier は err を設定すると送信に失敗する recordingNotifier です。
+type flakyNotifier struct {
+	recordingNotifier
+	err error
+}
+
+func (n *flakyNotifier) Notify(ctx context.Context, ev *Event) error {
+	if n.err != nil {
+		return n.err
+	}
+	return n.recordingNotifier.Notify(ctx, ev)
+}
+
+// eventTitles はできごとのタイトルを並べます。
+func eventTitles(evs []*Event) []string {
+	var titles []string
+	for _, ev := range evs {
+		titles = append(titles, ev.Title)
+	}
+	return titles
+}
+
+func TestDedupNotifier(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir()}
+	store := newDeliveryStore(cfg)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	a := &recordingNotifier{}
+	b := &recordingNotifier{}
+	na := &dedupNotifier{Notifier: a, id: "a", store: store}
+	nb := &dedupNotifier{Notifier: b, id: "b", store: store}
+
+	ev := &Event{Type: "new_assignment", Key: "new:1", Title: "課題", Time: now}
+	for i := 0; i < 2; i++ {
+		if err := na.Notify(ctx, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(a.events) != 1 {
+		t.Errorf("同じできごとを %d 回送りました", len(a.events))
+	}
+	// 記録は送信先ごと
+	if err := nb.Notify(ctx, ev); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.events) != 1 {
+		t.Errorf("ほかの送信先に送ったできごとを送りませんでした")
+	}
+	// Key のないできごとは毎回送る
+	for i := 0; i < 2; i++ {
+		if err := na.Notify(ctx, &Event{Type: "test", Title: "テスト", Time: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(a.events) != 3 {
+		t.Errorf("Key のないできごとを送った回数 = %d, want 2", len(a.events)-1)
+	}
+}
+
+func TestDedupNotifierRetry(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir()}
+	store := newDeliveryStore(cfg)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	rec := &flakyNotifier{err: errors.New("失敗")}
+	n := &dedupNotifier{Notifier: rec, id: "rec", store: store}
+	ev := &Event{Type: "new_assignment", Key: "new:1", Title: "課題", Time: now}
+	if err := n.Notify(ctx, ev); err == nil {
+		t.Fatal("送信の失敗を返しませんでした")
+	}
+	// 送れなかったできごとは次の機会に送る
+	rec.err = nil
+	if err := n.Notify(ctx, ev); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.events) != 1 {
+		t.Errorf("送れなかったできごとを送り直しませんでした")
+	}
+}
+
+func TestDeliveryStoreRetention(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir()}
+	store := newDeliveryStore(cfg)
+	old := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if ok, err := store.claim("a", "old", old); !ok || err != nil {
+		t.Fatalf("claim = %v, %v", ok, err)
+	}
+	// 保存期間を過ぎた記録は次に保存するときに消える
+	if ok, err := store.claim("a", "new", old.Add(deliveryRetention+time.Hour)); !ok || err != nil {
+		t.Fatalf("claim = %v, %v", ok, err)
+	}
+	st, err := store.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.Sent["a"]["old"]; ok {
+		t.Error("保存期間を過ぎた記録が残っています")
+	}
+	if _, ok := st.Sent["a"]["new"]; !ok {
+		t.Error("新しい記録がありません")
+	}
+}
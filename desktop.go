@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// DesktopConfig はOSのデスクトップ通知の設定です。
+// Linux などでは notify-send、macOS では通知センター (osascript)、Windows ではトースト通知 (PowerShell) を使います。
+type DesktopConfig struct {
+	// Events は送るできごとの種類です。省略した場合は新しい課題 (created) と締切が近い課題 (due_soon) だけを送ります。
+	// 締切の前のどの時間に通知するかは notify.reminders (または notify.dueSoon) で設定します。
+	Events []string `json:"events,omitempty"`
+}
+
+// defaultDesktopEvents は DesktopConfig.Events を省略したときに送るできごとです。
+var defaultDesktopEvents = []string{"created", "due_soon"}
+
+// windowsToastScript はトースト通知を表示するPowerShellのスクリプトです。
+// タイトルと本文はスクリプトに埋め込まず、環境変数で渡します。
+const windowsToastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+$title = [Security.SecurityElement]::Escape($env:CLASSROOM_TITLE)
+$body = [Security.SecurityElement]::Escape($env:CLASSROOM_BODY)
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml("<toast><visual><binding template=""ToastGeneric""><text>$title</text><text>$body</text></binding></visual></toast>")
+$appId = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($appId).Show([Windows.UI.Notifications.ToastNotification]::new($xml))
+`
+
+// desktopNotifier は通知をOSのデスクトップ通知で表示します。
+type desktopNotifier struct {
+	cfg DesktopConfig
+}
+
+func newDesktopNotifier(cfg DesktopConfig) *desktopNotifier {
+	if len(cfg.Events) == 0 {
+		cfg.Events = defaultDesktopEvents
+	}
+	return &desktopNotifier{cfg: cfg}
+}
+
+func (n *desktopNotifier) Name() string { return "desktop" }
+
+func (n *desktopNotifier) accepts(typ string) bool { return acceptsEvent(n.cfg.Events, typ) }
+
+func (n *desktopNotifier) Notify(ctx context.Context, ev *Event) error {
+	if !n.accepts(ev.Type) {
+		return nil
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// 文字列をAppleScriptに埋め込まずに済むよう、引数で渡す
+		cmd = exec.CommandContext(ctx, "osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			ev.Title, ev.Body)
+	case "windows":
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+		cmd.Env = append(os.Environ(), "CLASSROOM_TITLE="+ev.Title, "CLASSROOM_BODY="+ev.Body)
+	default:
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			return fmt.Errorf("notify-send が見つかりません (libnotify をインストールしてください): %w", err)
+		}
+		urgency := "normal"
+		if ev.Type == "due_soon" {
+			urgency = "critical"
+		}
+		cmd = exec.CommandContext(ctx, "notify-send", "--app-name=classroom-api", "--urgency="+urgency, ev.Title, ev.Body)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// errWarning は失敗ではないが注意が必要な確認結果を表します。
+var errWarning = errors.New("warning")
+
+type warning string
+
+func (w warning) Error() string        { return string(w) }
+func (w warning) Is(target error) bool { return target == errWarning }
+
+// doctorReport は確認結果をまとめて表示します。
+type doctorReport struct {
+	failed bool
+}
+
+func (r *doctorReport) check(name string, fn func() (string, error)) {
+	detail, err := fn()
+	switch {
+	case errors.Is(err, errWarning):
+		fmt.Printf("[WARN] %s: %v\n", name, err)
+	case err != nil:
+		r.failed = true
+		fmt.Printf("[FAIL] %s: %v\n", name, err)
+	case detail != "":
+		fmt.Printf("[ OK ] %s: %s\n", name, detail)
+	default:
+		fmt.Printf("[ OK ] %s\n", name)
+	}
+}
+
+// stateFiles は DataDir に保存する状態ファイルです。doctor で壊れていないかを確認します。
+var stateFiles = []string{slugStateFile, mirrorStateFile, healthFile, enrollmentStateFile, tasksStateFile, todoistStateFile, notionStateFile, apiKeysFile, metaFile, shownStateFile, usersFile, emailStateFile, remindersStateFile, deliveriesFile, quietStateFile}
+
+func runDoctor(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	skipNotify := fs.Bool("skip-notify", false, "通知の送信テストを行いません")
+	fs.Parse(args)
+
+	r := &doctorReport{}
+	var config *oauth2.Config
+	var tok *oauth2.Token
+
+	r.check("資格情報ファイル", func() (string, error) {
+		var err error
+		config, err = oauthConfig(cfg)
+		if err != nil {
+			return "", err
+		}
+		return cfg.CredentialsFile, nil
+	})
+
+	r.check("トークン", func() (string, error) {
+		if config == nil {
+			return "", errors.New("資格情報ファイルを読み込めていません")
+		}
+		saved, err := tokenFromFile(cfg.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("%s を読み取れませんでした (一度 list を実行して認証してください): %w", cfg.TokenFile, err)
+		}
+		if saved.RefreshToken == "" {
+			return "", warning("リフレッシュトークンがありません。期限が切れると再認証が必要です")
+		}
+		tok, err = config.TokenSource(ctx, saved).Token()
+		if err != nil {
+			return "", fmt.Errorf("トークンを更新できませんでした (%s を削除して再認証してください): %w", cfg.TokenFile, err)
+		}
+		return "有効期限 " + formatTime(tok.Expiry.Local()), nil
+	})
+
+	r.check("スコープ", func() (string, error) {
+		if tok == nil {
+			return "", errors.New("有効なトークンがありません")
+		}
+		granted, err := tokenScopes(ctx, tok.AccessToken)
+		if err != nil {
+			return "", err
+		}
+		var missing []string
+		for _, s := range scopes {
+			if !contains(granted, s) {
+				missing = append(missing, s)
+			}
+		}
+		if len(missing) > 0 {
+			return "", fmt.Errorf("不足しているスコープがあります (%s を削除して再認証してください): %s", cfg.TokenFile, strings.Join(missing, ", "))
+		}
+		return fmt.Sprintf("%d 個のスコープが許可されています", len(granted)), nil
+	})
+
+	r.check("時刻のずれ", func() (string, error) {
+		skew, err := clockSkew(ctx)
+		if err != nil {
+			return "", err
+		}
+		if skew > time.Minute || skew < -time.Minute {
+			return "", warning(fmt.Sprintf("Googleのサーバーと %s ずれています。締切の判定やトークンの検証に影響します", skew))
+		}
+		return skew.String(), nil
+	})
+
+	r.check("APIへの接続", func() (string, error) {
+		if tok == nil {
+			return "", errors.New("有効なトークンがありません")
+		}
+		srv := newClassroomService(ctx, config.Client(ctx, tok))
+		res, err := srv.Courses.List().StudentId("me").PageSize(1).Context(ctx).Do()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("コースを %d 件取得できました", len(res.Courses)), nil
+	})
+
+	for _, name := range stateFiles {
+		r.check("状態ファイル "+name, func() (string, error) {
+			b, err := os.ReadFile(cfg.dataPath(name))
+			if errors.Is(err, os.ErrNotExist) {
+				return "未作成", nil
+			}
+			if err != nil {
+				return "", err
+			}
+			if !json.Valid(b) {
+				return "", fmt.Errorf("JSONとして読み取れません。ファイルを削除すると作り直されます")
+			}
+			return "", nil
+		})
+	}
+	r.check("キャッシュ "+cacheFile, func() (string, error) {
+		if _, err := os.Stat(cfg.dataPath(cacheFile)); errors.Is(err, os.ErrNotExist) {
+			return "未作成", nil
+		}
+		db, err := openCache(cfg)
+		if err != nil {
+			return "", err
+		}
+		defer db.Close()
+		_, items, synced, err := readCache(ctx, db)
+		if errors.Is(err, errCacheEmpty) {
+			return "未同期", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("読み取れません。ファイルを削除して sync を実行すると作り直されます: %w", err)
+		}
+		return fmt.Sprintf("課題 %d 件、最終同期 %s", len(items), formatTime(synced)), nil
+	})
+
+	if !*skipNotify {
+		for _, n := range newNotifiers(cfg) {
+			r.check("通知 "+n.Name(), func() (string, error) {
+				ev := &Event{Type: "test", Title: "classroom-api doctor", Body: "通知のテストです。", Time: time.Now()}
+				return "テスト通知を送信しました", n.Notify(ctx, ev)
+			})
+		}
+	}
+
+	if r.failed {
+		os.Exit(1)
+	}
+}
+
+// tokenScopes はアクセストークンに許可されたスコープを返します。
+func tokenScopes(ctx context.Context, accessToken string) ([]string, error) {
+	u := "https://oauth2.googleapis.com/tokeninfo?access_token=" + url.QueryEscape(accessToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("トークン情報を取得できませんでした: %s", res.Status)
+	}
+	var info struct {
+		Scope string `json:"scope"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return strings.Fields(info.Scope), nil
+}
+
+// clockSkew はGoogleのサーバーの時刻とローカルの時刻の差を返します。
+func clockSkew(ctx context.Context) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://www.googleapis.com/", nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	server, err := http.ParseTime(res.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("サーバーの時刻を取得できませんでした: %w", err)
+	}
+	// 往復時間の半分を足した時刻をローカルの時刻とみなす
+	local := start.Add(time.Since(start) / 2)
+	return local.Sub(server).Round(time.Second), nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"log"
+	"os"
+	"time"
+)
+
+// e2eRun はサンドボックスのコースを使った結合テストの1回分の実行です。
+type e2eRun struct {
+	cfg     *Config
+	course  *classroom.Course
+	teacher *classroom.Service
+	student *classroom.Service
+	failed  bool
+}
+
+// step はテストの1手順を実行して結果を表示します。
+func (r *e2eRun) step(name string, fn func() error) bool {
+	start := time.Now()
+	err := fn()
+	if err != nil {
+		r.failed = true
+		fmt.Printf("[FAIL] %s (%s): %v\n", name, time.Since(start).Round(time.Millisecond), err)
+		return false
+	}
+	fmt.Printf("[ OK ] %s (%s)\n", name, time.Since(start).Round(time.Millisecond))
+	return true
+}
+
+func runE2E(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("e2e", flag.ExitOnError)
+	courseId := fs.String("course", "", "テストに使うサンドボックスのコースID (必須)")
+	teacherCreds := fs.String("teacher-credentials", "", "教師アカウント用の資格情報ファイル (省略時は通常の資格情報)")
+	teacherToken := fs.String("teacher-token", "teacher_token.json", "教師アカウントのトークンファイル")
+	studentToken := fs.String("student-token", "student_token.json", "提出を行う生徒アカウントのトークンファイル")
+	fs.Parse(args)
+
+	if *courseId == "" {
+		log.Fatal("-course でサンドボックスのコースIDを指定してください")
+	}
+	if *teacherCreds == "" {
+		*teacherCreds = cfg.CredentialsFile
+	}
+
+	// 教師は課題を作成・削除し、生徒は提出する。どちらも書き込み権限が必要なので通常のトークンとは分ける
+	tconf, err := oauthConfigWithScopes(*teacherCreds, classroom.ClassroomCoursesReadonlyScope, classroom.ClassroomCourseworkStudentsScope)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sconf, err := oauthConfigWithScopes(cfg.CredentialsFile, append([]string{classroom.ClassroomCourseworkMeScope}, scopes...)...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("教師アカウントで認証します")
+	teacher := newClassroomService(ctx, getClient(tconf, *teacherToken))
+	fmt.Println("生徒アカウントで認証します")
+	student := newClassroomService(ctx, getClient(sconf, *studentToken))
+
+	course, err := teacher.Courses.Get(*courseId).Context(ctx).Do()
+	if err != nil {
+		log.Fatalf("サンドボックスのコースを取得できませんでした: %v", err)
+	}
+
+	// 生徒側の処理は対象をサンドボックスのコースだけに絞った設定で行う
+	scfg := *cfg
+	scfg.CourseIDs = []string{course.Id}
+	r := &e2eRun{cfg: &scfg, course: course, teacher: teacher, student: student}
+	r.run(ctx)
+	if r.failed {
+		os.Exit(1)
+	}
+}
+
+func (r *e2eRun) run(ctx context.Context) {
+	title := fmt.Sprintf("e2e test %s", time.Now().Format("20060102-150405"))
+	due := time.Now().Add(48 * time.Hour).UTC()
+
+	var cw *classroom.CourseWork
+	if !r.step("課題を作成する", func() (err error) {
+		cw, err = r.teacher.Courses.CourseWork.Create(r.course.Id, &classroom.CourseWork{
+			Title:       title,
+			Description: "classroom-api の結合テストで作成した課題です。自動的に削除されます。",
+			WorkType:    "ASSIGNMENT",
+			State:       "PUBLISHED",
+			MaxPoints:   10,
+			DueDate:     &classroom.Date{Year: int64(due.Year()), Month: int64(due.Month()), Day: int64(due.Day())},
+			DueTime:     &classroom.TimeOfDay{Hours: int64(due.Hour()), Minutes: int64(due.Minute())},
+		}).Context(ctx).Do()
+		return err
+	}) {
+		return
+	}
+	defer r.step("課題を削除する", func() error {
+		_, err := r.teacher.Courses.CourseWork.Delete(r.course.Id, cw.Id).Context(ctx).Do()
+		return err
+	})
+
+	var before *Assignment
+	r.step("一覧に未提出として表示される", func() (err error) {
+		before, err = r.findPending(ctx, cw.Id)
+		if err == nil && before == nil {
+			err = errors.New("未提出の一覧に課題がありません")
+		}
+		return err
+	})
+
+	r.step("締切の変更を差分として検出する", func() error {
+		if before == nil {
+			return errors.New("前の手順が失敗しています")
+		}
+		newDue := due.Add(24 * time.Hour)
+		_, err := r.teacher.Courses.CourseWork.Patch(r.course.Id, cw.Id, &classroom.CourseWork{
+			DueDate: &classroom.Date{Year: int64(newDue.Year()), Month: int64(newDue.Month()), Day: int64(newDue.Day())},
+			DueTime: &classroom.TimeOfDay{Hours: int64(newDue.Hour()), Minutes: int64(newDue.Minute())},
+		}).UpdateMask("dueDate,dueTime").Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		after, err := r.findPending(ctx, cw.Id)
+		if err != nil {
+			return err
+		}
+		if after == nil || after.fingerprint() == before.fingerprint() {
+			return errors.New("締切の変更を検出できませんでした")
+		}
+		return nil
+	})
+
+	r.step("通知を送る", func() error {
+		rec := &recordingNotifier{}
+		ns := append(newNotifiers(r.cfg), rec)
+		ev := &Event{Type: "reminder", Assignment: before, Title: "結合テスト", Body: title, Time: time.Now()}
+		if err := notifyAll(ctx, ns, ev); err != nil {
+			return err
+		}
+		if len(rec.events) != 1 {
+			return fmt.Errorf("通知が %d 件届きました", len(rec.events))
+		}
+		return nil
+	})
+
+	r.step("生徒として提出する", func() error {
+		res, err := r.student.Courses.CourseWork.StudentSubmissions.List(r.course.Id, cw.Id).UserId("me").Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		if len(res.StudentSubmissions) == 0 {
+			return errors.New("生徒の提出物がありません")
+		}
+		_, err = r.student.Courses.CourseWork.StudentSubmissions.TurnIn(r.course.Id, cw.Id, res.StudentSubmissions[0].Id, &classroom.TurnInStudentSubmissionRequest{}).Context(ctx).Do()
+		return err
+	})
+
+	r.step("提出後は一覧に表示されない", func() error {
+		a, err := r.findPending(ctx, cw.Id)
+		if err != nil {
+			return err
+		}
+		if a != nil {
+			return fmt.Errorf("提出済みの課題が表示されています (状態: %s)", a.State())
+		}
+		return nil
+	})
+}
+
+// findPending は生徒から見た未提出の一覧から課題を探します。見つからない場合は nil を返します。
+// 作成や提出の直後の状態を確かめるため、sync のキャッシュは使わずに毎回APIから取得します。
+func (r *e2eRun) findPending(ctx context.Context, id string) (*Assignment, error) {
+	courses, err := listCourses(ctx, r.student, r.cfg)
+	if err != nil {
+		return nil, err
+	}
+	items, err := loadCourseAssignments(ctx, r.cfg, r.student, courses)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range pendingAssignments(items, time.Now()) {
+		if a.CourseWork.Id == id {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+// recordingNotifier は受け取った通知を記録するだけの送信先です。
+type recordingNotifier struct {
+	events []*Event
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Notify(_ context.Context, ev *Event) error {
+	n.events = append(n.events, ev)
+	return nil
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+	"html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// emailStateFile は最後にまとめを送った時刻を記録するファイルです。
+	emailStateFile = "email.json"
+	// emailTimeout はメールサーバーとのやりとりにかける時間の上限です。
+	emailTimeout = 30 * time.Second
+)
+
+// EmailConfig はメールで送る課題のまとめの設定です。
+type EmailConfig struct {
+	// Sender は送信の方法です ("smtp" または "gmail")。省略した場合は "smtp" です。
+	// "gmail" の場合はログインしているGoogleアカウントからGmail APIで送るため、SMTPの設定は不要です。
+	Sender string `json:"sender,omitempty"`
+	// Host と Port は送信に使うSMTPサーバーです。Port を省略した場合は 587 (STARTTLS) です。
+	// 465 の場合は最初からTLSで接続します。
+	Host string `json:"host"`
+	Port int    `json:"port,omitempty"`
+	// Username と Password はSMTP認証の資格情報です。Username を省略した場合は認証しません。
+	// Password を省略した場合は環境変数 SMTP_PASSWORD を使います。
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// From は送信者のアドレス、To は宛先のアドレスです。Sender が "gmail" の場合、From は省略でき、
+	// 省略した場合はログインしているアカウントのアドレスになります。
+	From string   `json:"from"`
+	To   []string `json:"to"`
+	// Schedule は送る間隔です ("daily" または "weekly")。省略した場合は "daily" です。
+	Schedule string `json:"schedule,omitempty"`
+	// At は送る時刻 ("7:30" の形式) です。省略した場合は "7:00" です。
+	At string `json:"at,omitempty"`
+	// Weekday は Schedule が "weekly" の場合に送る曜日です ("月" または "mon" の形式)。省略した場合は月曜日です。
+	Weekday string `json:"weekday,omitempty"`
+	// Subject は件名です。省略した場合は「Classroomの課題のまとめ (7/3)」のようにします。
+	Subject string `json:"subject,omitempty"`
+	// Template は本文のHTMLのテンプレート (html/template) のファイルです。省略した場合は組み込みのテンプレートを使います。
+	// テンプレートには emailDigest が渡され、課題は list -template と同じ形式です。
+	Template string `json:"template,omitempty"`
+}
+
+// emailState は最後にまとめを送った時刻です。新しい課題はこの時刻より後に作成されたものです。
+type emailState struct {
+	LastSent time.Time `json:"lastSent"`
+}
+
+// emailDigest はまとめのテンプレートに渡すデータです。
+type emailDigest struct {
+	Title string
+	// Generated はまとめを作った時刻、Since は新しい課題とみなす作成日時の下限です。
+	Generated string
+	Since     string
+	// Pending は未提出の課題、New は Since より後に作成された課題です。
+	Pending []*TemplateData
+	New     []*TemplateData
+}
+
+// emailTemplate は組み込みのまとめのテンプレートです。メールのクライアントでも崩れないよう、スタイルは要素に直接書きます。
+var emailTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: sans-serif; color: #222;">
+<h1 style="font-size: 1.3em;">{{.Title}}</h1>
+<p style="color: #666; font-size: .9em;">{{.Generated}} 時点</p>
+<h2 style="font-size: 1.1em;">新しい課題 ({{len .New}}件)</h2>
+{{if .New}}<table style="border-collapse: collapse;">
+{{range .New}}<tr><td style="padding: 4px 8px; border-bottom: 1px solid #ddd;">{{.Course.Name}}</td><td style="padding: 4px 8px; border-bottom: 1px solid #ddd;"><a href="{{.Link}}">{{.Title}}</a></td><td style="padding: 4px 8px; border-bottom: 1px solid #ddd;">{{.DueText}}</td></tr>
+{{end}}</table>
+{{else}}<p style="color: #666;">{{.Since}} 以降に出された課題はありません。</p>
+{{end}}<h2 style="font-size: 1.1em;">未提出の課題 ({{len .Pending}}件)</h2>
+{{if .Pending}}<table style="border-collapse: collapse;">
+{{range .Pending}}<tr><td style="padding: 4px 8px; border-bottom: 1px solid #ddd;">{{.Course.Name}}</td><td style="padding: 4px 8px; border-bottom: 1px solid #ddd;"><a href="{{.Link}}">{{.Title}}</a></td><td style="padding: 4px 8px; border-bottom: 1px solid #ddd;">{{.DueText}}</td><td style="padding: 4px 8px; border-bottom: 1px solid #ddd;">{{.DueIn}}</td></tr>
+{{end}}</table>
+{{else}}<p style="color: #666;">未提出の課題はありません。</p>
+{{end}}</body>
+</html>
+`))
+
+func runEmail(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("email", flag.ExitOnError)
+	sendNow := fs.Bool("send-now", false, "予定の時刻を待たずにすぐにまとめを送ります (設定の確認用)")
+	preview := fs.Bool("preview", false, "まとめを送らず、本文のHTMLを標準出力に書き出します")
+	fs.Parse(args)
+
+	ec := cfg.Notify.Email
+	if ec == nil {
+		log.Fatal("設定ファイルに notify.email がありません")
+	}
+	sched, err := newEmailSchedule(ec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tmpl := emailTemplate
+	if ec.Template != "" {
+		if tmpl, err = template.ParseFiles(ec.Template); err != nil {
+			log.Fatalf("メールのテンプレートを読み込めませんでした: %v", err)
+		}
+	}
+	client := newHTTPClient(cfg)
+	srv := newClassroomService(ctx, client)
+	sender, err := newMailSender(ctx, ec, client)
+	if err != nil && !*preview {
+		log.Fatal(err)
+	}
+	send := func(now time.Time) error {
+		var st emailState
+		if err := readJSONFile(cfg.dataPath(emailStateFile), &st); err != nil {
+			return fmt.Errorf("%s を読み取れませんでした: %w", emailStateFile, err)
+		}
+		since := st.LastSent
+		if since.IsZero() {
+			since = now.Add(-sched.period())
+		}
+		items, err := loadAssignments(ctx, cfg, srv)
+		if err != nil {
+			return err
+		}
+		d := newEmailDigest(items, since, now)
+		var body bytes.Buffer
+		if err := tmpl.Execute(&body, d); err != nil {
+			return fmt.Errorf("メールのテンプレートを実行できませんでした: %w", err)
+		}
+		if *preview {
+			_, err := os.Stdout.Write(body.Bytes())
+			return err
+		}
+		subject := ec.Subject
+		if subject == "" {
+			subject = d.Title
+		}
+		msg, err := buildMail(ec.From, ec.To, subject, emailText(d), body.String(), now)
+		if err != nil {
+			return err
+		}
+		if err := sender.send(ctx, ec.From, ec.To, msg); err != nil {
+			return fmt.Errorf("メールを送信できませんでした: %w", err)
+		}
+		log.Printf("課題のまとめを %s に送りました (未提出 %d件、新しい課題 %d件)", strings.Join(ec.To, ", "), len(d.Pending), len(d.New))
+		if cfg.dryRun {
+			return nil
+		}
+		return writeJSONFile(cfg.dataPath(emailStateFile), &emailState{LastSent: now})
+	}
+
+	if *sendNow || *preview {
+		if err := send(time.Now()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	for {
+		next := sched.next(time.Now())
+		log.Printf("次のまとめは %s に送ります", formatTime(next))
+		if err := sleepContext(ctx, time.Until(next)); err != nil {
+			return
+		}
+		// 送れなかった場合も次の予定まで待つ。同じまとめを何度も送らないよう、すぐには再試行しない
+		if err := send(time.Now()); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// emailSchedule はまとめを送る予定です。
+type emailSchedule struct {
+	weekly  bool
+	weekday time.Weekday
+	// at は0時から送る時刻までの時間です。
+	at time.Duration
+}
+
+func newEmailSchedule(ec *EmailConfig) (*emailSchedule, error) {
+	s := &emailSchedule{at: 7 * time.Hour, weekday: time.Monday}
+	switch ec.Schedule {
+	case "", "daily":
+	case "weekly":
+		s.weekly = true
+	default:
+		return nil, fmt.Errorf("notify.email.schedule は daily または weekly です: %s", ec.Schedule)
+	}
+	if ec.At != "" {
+		t, err := time.Parse("15:04", ec.At)
+		if err != nil {
+			return nil, fmt.Errorf("notify.email.at の時刻を解析できませんでした: %s", ec.At)
+		}
+		s.at = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if ec.Weekday != "" {
+		wd, ok := weekdayNames[strings.ToLower(strings.TrimSuffix(ec.Weekday, "曜日"))]
+		if !ok {
+			return nil, fmt.Errorf("notify.email.weekday の曜日を解析できませんでした: %s", ec.Weekday)
+		}
+		s.weekday = wd
+	}
+	return s, nil
+}
+
+// period は1回のまとめが対象にする期間です。初めて送るときはこの期間に作成された課題を新しい課題とします。
+func (s *emailSchedule) period() time.Duration {
+	if s.weekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// next は now より後で最も早い送る時刻を返します。
+func (s *emailSchedule) next(now time.Time) time.Time {
+	y, m, d := now.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	for i := 0; ; i++ {
+		t := day.AddDate(0, 0, i).Add(s.at)
+		if t.After(now) && (!s.weekly || t.Weekday() == s.weekday) {
+			return t
+		}
+	}
+}
+
+// newEmailDigest は課題からまとめを作ります。外部のツールで取り組む課題も含めます。
+func newEmailDigest(items []*Assignment, since, now time.Time) *emailDigest {
+	d := &emailDigest{
+		Title:     fmt.Sprintf("Classroomの課題のまとめ (%s)", now.Format("1/2")),
+		Generated: formatTime(now),
+		Since:     formatTime(since),
+	}
+	for _, a := range pendingAssignments(items, now) {
+		d.Pending = append(d.Pending, newTemplateData(a, now))
+	}
+	for _, a := range items {
+		created, err := time.Parse(time.RFC3339, a.CourseWork.CreationTime)
+		if err == nil && created.After(since) && !created.After(now) {
+			d.New = append(d.New, newTemplateData(a, now))
+		}
+	}
+	return d
+}
+
+// emailText はHTMLを表示できないメールのクライアント向けのテキストの本文です。
+func emailText(d *emailDigest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s 時点\n\n新しい課題 (%d件)\n", d.Title, d.Generated, len(d.New))
+	for _, t := range d.New {
+		fmt.Fprintf(&b, "・%s「%s」締切: %s\n  %s\n", t.Course.Name, t.Title, t.DueText, t.Link)
+	}
+	fmt.Fprintf(&b, "\n未提出の課題 (%d件)\n", len(d.Pending))
+	for _, t := range d.Pending {
+		fmt.Fprintf(&b, "・%s「%s」締切: %s", t.Course.Name, t.Title, t.DueText)
+		if t.DueIn != "" {
+			fmt.Fprintf(&b, " (%s)", t.DueIn)
+		}
+		fmt.Fprintf(&b, "\n  %s\n", t.Link)
+	}
+	return b.String()
+}
+
+// buildMail はテキストとHTMLの本文を multipart/alternative にしたメールを作ります。
+func buildMail(from string, to []string, subject, text, html string, now time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, p := range []struct{ typ, content string }{{"text/plain", text}, {"text/html", html}} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.typ + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(w)
+		if _, err := qw.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	var msg bytes.Buffer
+	rcpts := make([]string, len(to))
+	for i, addr := range to {
+		rcpts[i] = headerAddress(addr)
+	}
+	if from != "" {
+		fmt.Fprintf(&msg, "From: %s\r\n", headerAddress(from))
+	}
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(rcpts, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// mailSender はメールの送信方法です。
+type mailSender interface {
+	send(ctx context.Context, from string, to []string, msg []byte) error
+}
+
+// newMailSender は設定からメールの送信方法を作ります。client は Gmail API で送る場合に使います。
+func newMailSender(ctx context.Context, ec *EmailConfig, client *http.Client) (mailSender, error) {
+	if len(ec.To) == 0 {
+		return nil, errors.New("notify.email.to を設定してください")
+	}
+	switch ec.Sender {
+	case "", "smtp":
+	case "gmail":
+		gsrv, err := gmail.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			return nil, fmt.Errorf("Gmailクライアントを作成できませんでした: %w", err)
+		}
+		return &gmailSender{srv: gsrv}, nil
+	default:
+		return nil, fmt.Errorf("notify.email.sender は smtp または gmail です: %s", ec.Sender)
+	}
+	if ec.From == "" {
+		return nil, errors.New("notify.email.from を設定してください")
+	}
+	if ec.Host == "" {
+		return nil, errors.New("notify.email.host (SMTPサーバー) を設定してください")
+	}
+	s := &smtpSender{host: ec.Host, port: ec.Port, username: ec.Username, password: ec.Password}
+	if s.port == 0 {
+		s.port = 587
+	}
+	if s.password == "" {
+		s.password = os.Getenv("SMTP_PASSWORD")
+	}
+	return s, nil
+}
+
+// smtpSender はSMTPサーバーでメールを送ります。
+type smtpSender struct {
+	host     string
+	port     int
+	username string
+	password string
+}
+
+func (s *smtpSender) send(ctx context.Context, from string, to []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, emailTimeout)
+	defer cancel()
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	// net/smtp はコンテキストを受け取らないため、接続の期限で時間の上限を守る
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tlsConfig := &tls.Config{ServerName: s.host}
+	if s.port == 465 {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok && s.port != 465 {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(envelopeAddress(from)); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(envelopeAddress(rcpt)); err != nil {
+			return fmt.Errorf("%s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// gmailSender はログインしているアカウントからGmail APIでメールを送ります。SMTPの通信を遮断されている環境で使います。
+type gmailSender struct {
+	srv *gmail.Service
+}
+
+func (s *gmailSender) send(ctx context.Context, _ string, _ []string, msg []byte) error {
+	// 宛先と送信者はメールのヘッダーから決まる。送信者のヘッダーがない場合はGmailがアカウントのアドレスを付ける
+	_, err := s.srv.Users.Messages.Send("me", &gmail.Message{Raw: base64.URLEncoding.EncodeToString(msg)}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("%w (Gmailの権限を追加したため、古いトークンの場合はログインし直してください)", err)
+	}
+	return nil
+}
+
+// envelopeAddress は「名前 <アドレス>」の形式のアドレスからSMTPのやりとりに使うアドレスだけを取り出します。
+func envelopeAddress(s string) string {
+	if a, err := mail.ParseAddress(s); err == nil {
+		return a.Address
+	}
+	return s
+}
+
+// headerAddress はアドレスをメールのヘッダーに書ける形式にします。名前に日本語を含む場合はエンコードします。
+func headerAddress(s string) string {
+	if a, err := mail.ParseAddress(s); err == nil {
+		return a.String()
+	}
+	return s
+}
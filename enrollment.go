@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"sort"
+	"time"
+)
+
+// enrollmentStateFile は前回の同期で在籍していたコースを記録するファイルです。
+const enrollmentStateFile = "courses.json"
+
+// enrollmentState は前回の同期で在籍していたコースのIDと名前の対応です。
+type enrollmentState struct {
+	Courses map[string]string `json:"courses"`
+}
+
+// enrollmentChanges は前回の同期と比べて追加・削除されたコースの通知を返し、今回のコースを記録します。
+// 初めての同期では記録だけを行います。
+func enrollmentChanges(cfg *Config, courses []*classroom.Course, now time.Time) ([]*Event, error) {
+	path := cfg.dataPath(enrollmentStateFile)
+	var prev enrollmentState
+	if err := readJSONFile(path, &prev); err != nil {
+		return nil, fmt.Errorf("コースの記録を読み込めませんでした: %w", err)
+	}
+	cur := enrollmentState{Courses: make(map[string]string, len(courses))}
+	var events []*Event
+	for _, c := range courses {
+		cur.Courses[c.Id] = c.Name
+		if _, ok := prev.Courses[c.Id]; prev.Courses != nil && !ok {
+			events = append(events, &Event{
+				Type:  "enrolled",
+				Title: fmt.Sprintf("コースに追加されました: %s", c.Name),
+				Body:  fmt.Sprintf("%s\n%s", c.Name, c.AlternateLink),
+				Time:  now,
+			})
+		}
+	}
+	var removed []string
+	for id, name := range prev.Courses {
+		if _, ok := cur.Courses[id]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		events = append(events, &Event{
+			Type:  "unenrolled",
+			Title: fmt.Sprintf("コースから削除されました: %s", name),
+			Body:  fmt.Sprintf("%s の課題は一覧に表示されなくなります。心当たりがない場合は先生に確認してください。", name),
+			Time:  now,
+		})
+	}
+	if err := writeJSONFile(path, &cur); err != nil {
+		return events, fmt.Errorf("コースの記録を保存できませんでした: %w", err)
+	}
+	return events, nil
+}
+
+// notifyEnrollmentChanges はコースの追加・削除を通知し、通知したできごとを返します。
+func notifyEnrollmentChanges(ctx context.Context, cfg *Config, ns []Notifier, courses []*classroom.Course, now time.Time) ([]*Event, error) {
+	events, err := enrollmentChanges(cfg, courses, now)
+	for _, ev := range events {
+		notifyAll(ctx, ns, ev)
+	}
+	return events, err
+}
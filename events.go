@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sseHeartbeat はプロキシに接続を切られないよう、変更がなくてもコメントを送る間隔です。
+const sseHeartbeat = 30 * time.Second
+
+// handleEvents は課題の変更を Server-Sent Events で送ります。
+// イベント名は created、updated、turned_in、removed で、data は GET /api/pending の要素と同じ形式です。
+//
+//	curl -N http://localhost:8000/events
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.account(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "ストリーミングに対応していません", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", (10 * time.Second).Milliseconds())
+	flusher.Flush()
+
+	updates, unsubscribe := a.subscribe()
+	defer unsubscribe()
+	t := time.NewTicker(sseHeartbeat)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			// サーバーの終了。クライアントは retry の間隔の後に再接続する
+			return
+		case <-t.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case c := <-updates:
+			for _, ev := range c.Events {
+				b, err := json.Marshal(newTemplateData(ev.Assignment, c.Time))
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "id: %d-%s\nevent: %s\ndata: %s\n\n", c.Time.Unix(), ev.Assignment.CourseWork.Id, ev.Type, b)
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+const exportUsageText = `使い方: classroom-api export <形式> [オプション]
+
+形式:
+  ical        締切をiCalendar (.ics) で出力 (-todo でタスクとして、-dir でコースごとのファイルも)
+  board       担当・同僚のコースの締切をまとめた投影用の締切表 (HTML、印刷でPDF)
+  atom        未提出の課題と最近投稿された課題のAtomフィード (フィードリーダー向け)
+  html        コースごとの並べ替えできる表にしたHTML (-out で出力先を指定、外部ファイル不要)
+  xlsx        Excelファイル (集計のシートとコースごとのシート、締切切れを強調)
+  org         Orgの見出し (DEADLINE/SCHEDULED、コースのタグ、プロパティ) でorg-agenda向けに出力
+  csv         課題ごとに提出状況や点数を含む1行のCSV (表計算ソフト向け)
+  sheets      コースごとのシートに課題と提出状況をGoogleスプレッドシートへ書き出し
+  taskwarrior task import で読み込めるJSON (-import で直接読み込み、繰り返し実行しても重複しません)
+  todoist     未提出の課題をTodoistのタスクとして追加・更新 (繰り返し実行しても重複しません)
+  notion      未提出の課題をNotionのデータベースに追加・更新
+  stats       研究用の匿名化した集計統計 (-anonymized が必要)
+`
+
+func runExport(ctx context.Context, cfg *Config, args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, exportUsageText)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "ical":
+		runExportICal(ctx, cfg, args[1:])
+	case "board":
+		runExportBoard(ctx, cfg, args[1:])
+	case "atom":
+		runExportAtom(ctx, cfg, args[1:])
+	case "html":
+		runExportHTML(ctx, cfg, args[1:])
+	case "xlsx":
+		runExportXLSX(ctx, cfg, args[1:])
+	case "org":
+		runExportOrg(ctx, cfg, args[1:])
+	case "csv":
+		runExportCSV(ctx, cfg, args[1:])
+	case "sheets":
+		runExportSheets(ctx, cfg, args[1:])
+	case "taskwarrior":
+		runExportTaskwarrior(ctx, cfg, args[1:])
+	case "todoist":
+		runExportTodoist(ctx, cfg, args[1:])
+	case "notion":
+		runExportNotion(ctx, cfg, args[1:])
+	case "stats":
+		runExportStats(ctx, cfg, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "不明な形式です: %s\n\n", args[0])
+		fmt.Fprint(os.Stderr, exportUsageText)
+		os.Exit(2)
+	}
+}
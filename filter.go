@@ -0,0 +1,260 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// workTypes は CourseWork.WorkType の値と表示名の対応です。
+var workTypes = map[string]string{
+	"ASSIGNMENT":               "課題",
+	"SHORT_ANSWER_QUESTION":    "質問(記述式)",
+	"MULTIPLE_CHOICE_QUESTION": "質問(選択式)",
+}
+
+// Filter は一覧に表示する課題の条件です。
+type Filter struct {
+	// Types は表示する課題の種類です。空の場合はすべての種類を表示します。
+	Types []string
+	// Topics は表示するトピックの名前またはIDです。空の場合はすべてのトピックを表示します。
+	Topics []string
+	// ExcludeTopics は表示しないトピックの名前またはIDです。
+	ExcludeTopics []string
+	// DueDays は締切日を today からの日数で絞り込みます (0: 今日, 1: 明日)。nil の場合は絞り込みません。
+	DueDays *int
+	// Courses は表示するコースのID・別名・名前です。空の場合はすべてのコースを表示します。
+	Courses []string
+	// States は表示する提出物の状態です。空の場合はすべての状態を表示します。
+	States []string
+	// DueBefore は猶予期間を含む締切がこの時刻より前の課題だけを表示します。ゼロ値の場合は絞り込みません。
+	DueBefore time.Time
+}
+
+// addFilterFlags は課題を絞り込むフラグを登録します。
+func addFilterFlags(fs *flag.FlagSet) *Filter {
+	f := &Filter{}
+	fs.Func("type", "表示する課題の種類 (ASSIGNMENT, SHORT_ANSWER_QUESTION, MULTIPLE_CHOICE_QUESTION をカンマ区切りで指定)", func(s string) error {
+		types, err := parseWorkTypes(s)
+		f.Types = append(f.Types, types...)
+		return err
+	})
+	fs.Func("due", "締切日で絞り込みます (today または tomorrow)", func(s string) error {
+		var days int
+		switch s {
+		case "today":
+			days = 0
+		case "tomorrow":
+			days = 1
+		default:
+			return fmt.Errorf("-due には today または tomorrow を指定してください: %s", s)
+		}
+		f.DueDays = &days
+		return nil
+	})
+	fs.Func("topic", "表示するトピックの名前またはID (カンマ区切り、繰り返し指定可)", func(s string) error {
+		f.Topics = append(f.Topics, splitList(s)...)
+		return nil
+	})
+	fs.Func("exclude-topic", "表示しないトピックの名前またはID (カンマ区切り、繰り返し指定可)", func(s string) error {
+		f.ExcludeTopics = append(f.ExcludeTopics, splitList(s)...)
+		return nil
+	})
+	fs.Func("course", "表示するコースのID・別名・名前 (カンマ区切り、繰り返し指定可)", func(s string) error {
+		f.Courses = append(f.Courses, splitList(s)...)
+		return nil
+	})
+	fs.Func("state", "表示する提出物の状態 (NEW, CREATED, RECLAIMED_BY_STUDENT などをカンマ区切りで指定)", func(s string) error {
+		states, err := parseSubmissionStates(s)
+		f.States = append(f.States, states...)
+		return err
+	})
+	fs.Func("due-before", "締切がこの日時より前の課題だけを表示します (2006-01-02、RFC 3339 または 7d のような今からの期間)", func(s string) error {
+		t, err := parseDueBefore(s, time.Now())
+		f.DueBefore = t
+		return err
+	})
+	return f
+}
+
+// filterFromQuery はAPIのクエリパラメーターから CLI と同じ条件を作ります。
+// パラメーターは course、type、state、topic、exclude_topic、due_before で、値はカンマ区切りで複数指定できます。
+func filterFromQuery(q url.Values, now time.Time) (*Filter, error) {
+	f := &Filter{}
+	for _, v := range q["course"] {
+		f.Courses = append(f.Courses, splitList(v)...)
+	}
+	for _, v := range q["topic"] {
+		f.Topics = append(f.Topics, splitList(v)...)
+	}
+	for _, v := range q["exclude_topic"] {
+		f.ExcludeTopics = append(f.ExcludeTopics, splitList(v)...)
+	}
+	for _, v := range q["type"] {
+		types, err := parseWorkTypes(v)
+		if err != nil {
+			return nil, err
+		}
+		f.Types = append(f.Types, types...)
+	}
+	for _, v := range q["state"] {
+		states, err := parseSubmissionStates(v)
+		if err != nil {
+			return nil, err
+		}
+		f.States = append(f.States, states...)
+	}
+	if v := q.Get("due_before"); v != "" {
+		t, err := parseDueBefore(v, now)
+		if err != nil {
+			return nil, err
+		}
+		f.DueBefore = t
+	}
+	return f, nil
+}
+
+// parseWorkTypes はカンマ区切りの課題の種類を解析します。大文字と小文字は区別しません。
+func parseWorkTypes(s string) ([]string, error) {
+	var types []string
+	for _, t := range splitList(s) {
+		t = strings.ToUpper(t)
+		if _, ok := workTypes[t]; !ok {
+			return nil, fmt.Errorf("不明な課題の種類です: %s", t)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// parseSubmissionStates はカンマ区切りの提出物の状態を解析します。大文字と小文字は区別しません。
+func parseSubmissionStates(s string) ([]string, error) {
+	var states []string
+	for _, st := range splitList(s) {
+		st = strings.ToUpper(st)
+		if _, ok := submissionStates[st]; !ok {
+			return nil, fmt.Errorf("不明な提出物の状態です: %s", st)
+		}
+		states = append(states, st)
+	}
+	return states, nil
+}
+
+// parseDueBefore は締切の上限を解析します。
+// 2006-01-02 の形式はローカル時刻のその日の0時、7d や 12h のような期間は now からの時刻です。
+func parseDueBefore(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if d, err := parseOffset(s); err == nil {
+		return now.Add(d), nil
+	}
+	return time.Time{}, fmt.Errorf("締切の上限は 2006-01-02、RFC 3339 または 7d の形式で指定してください: %s", s)
+}
+
+// match は課題が条件に一致するかどうかを返します。
+func (f *Filter) match(a *Assignment) bool {
+	if len(f.Types) > 0 && !contains(f.Types, a.CourseWork.WorkType) {
+		return false
+	}
+	if len(f.Topics) > 0 && !matchTopic(f.Topics, a) {
+		return false
+	}
+	if matchTopic(f.ExcludeTopics, a) {
+		return false
+	}
+	if f.DueDays != nil && !dueOnDay(a, time.Now().AddDate(0, 0, *f.DueDays)) {
+		return false
+	}
+	if len(f.Courses) > 0 && !matchCourse(f.Courses, a) {
+		return false
+	}
+	if len(f.States) > 0 && (a.Submission == nil || !contains(f.States, a.Submission.State)) {
+		return false
+	}
+	if !f.DueBefore.IsZero() {
+		due, ok := a.EffectiveDue()
+		if !ok || !due.Before(f.DueBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchCourse は課題のコースが names のいずれかのID・別名・名前に一致するかどうかを返します。
+// 名前は大文字と小文字を区別せずに比較します。
+func matchCourse(names []string, a *Assignment) bool {
+	for _, n := range names {
+		if n == a.Course.Id || n == courseLabel(a) || strings.EqualFold(n, a.Course.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// dueOnDay は課題の締切がローカル時刻で day と同じ日かどうかを返します。
+// 締切はUTCで保存されているため、日付の比較は必ずローカル時刻に変換してから行います。
+func dueOnDay(a *Assignment, day time.Time) bool {
+	due, ok := a.Due()
+	if !ok {
+		return false
+	}
+	y1, m1, d1 := due.In(day.Location()).Date()
+	y2, m2, d2 := day.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
+
+// matchTopic は課題のトピックが names のいずれかに一致するかどうかを返します。
+// 名前は大文字と小文字を区別せずに比較します。
+func matchTopic(names []string, a *Assignment) bool {
+	for _, n := range names {
+		if (a.CourseWork.TopicId != "" && n == a.CourseWork.TopicId) || (a.Topic != "" && strings.EqualFold(n, a.Topic)) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList はカンマ区切りの文字列を分割し、空の要素を取り除きます。
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// apply は条件に一致する課題だけを返します。
+func (f *Filter) apply(items []*Assignment) []*Assignment {
+	var matched []*Assignment
+	for _, a := range items {
+		if f.match(a) {
+			matched = append(matched, a)
+		}
+	}
+	return matched
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// workTypeLabel は課題の種類の表示名を返します。
+func workTypeLabel(t string) string {
+	if label, ok := workTypes[t]; ok {
+		return label
+	}
+	return t
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"io"
+	"log"
+	"os"
+	"sort"
+)
+
+// categoryTotal は成績カテゴリごとの集計です。
+type categoryTotal struct {
+	name string
+	// weight はカテゴリの重み (%) です。WEIGHTED_CATEGORIES の場合だけ使います。
+	weight float64
+	// earned と gradedMax は採点済みの課題の得点と配点の合計です。
+	earned    float64
+	gradedMax float64
+	// totalMax は採点前の課題も含めた配点の合計です。
+	totalMax float64
+	graded   int
+	count    int
+	items    []*Assignment
+}
+
+// average は採点済みの課題の得点率 (0〜1) を返します。
+func (t *categoryTotal) average() (float64, bool) {
+	if t.gradedMax == 0 {
+		return 0, false
+	}
+	return t.earned / t.gradedMax, true
+}
+
+func runGrades(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("grades", flag.ExitOnError)
+	verbose := fs.Bool("v", false, "課題ごとの得点も表示します")
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	byCourse := map[string][]*Assignment{}
+	var courses []*classroom.Course
+	for _, a := range items {
+		if _, ok := byCourse[a.Course.Id]; !ok {
+			courses = append(courses, a.Course)
+		}
+		byCourse[a.Course.Id] = append(byCourse[a.Course.Id], a)
+	}
+	sort.Slice(courses, func(i, j int) bool { return courses[i].Name < courses[j].Name })
+	for _, c := range courses {
+		printCourseGrades(os.Stdout, c, byCourse[c.Id], *verbose)
+	}
+}
+
+// isGraded は課題が採点済みかどうかを返します。
+func isGraded(a *Assignment) bool {
+	s := a.Submission
+	return s != nil && a.CourseWork.MaxPoints > 0 && (s.State == "RETURNED" || s.AssignedGrade > 0)
+}
+
+// gradeTotals は課題を成績カテゴリごとに集計します。
+func gradeTotals(course *classroom.Course, items []*Assignment) []*categoryTotal {
+	totals := map[string]*categoryTotal{}
+	var order []string
+	if gs := course.GradebookSettings; gs != nil {
+		for _, gc := range gs.GradeCategories {
+			totals[gc.Id] = &categoryTotal{name: gc.Name, weight: float64(gc.Weight) / 10000}
+			order = append(order, gc.Id)
+		}
+	}
+	for _, a := range items {
+		c := a.CourseWork
+		if c.MaxPoints == 0 {
+			continue
+		}
+		id := ""
+		if c.GradeCategory != nil {
+			id = c.GradeCategory.Id
+		}
+		t, ok := totals[id]
+		if !ok {
+			name := "カテゴリなし"
+			if c.GradeCategory != nil {
+				name = c.GradeCategory.Name
+			}
+			t = &categoryTotal{name: name}
+			totals[id] = t
+			order = append(order, id)
+		}
+		t.count++
+		t.totalMax += c.MaxPoints
+		t.items = append(t.items, a)
+		if isGraded(a) {
+			t.graded++
+			t.earned += a.Submission.AssignedGrade
+			t.gradedMax += c.MaxPoints
+		}
+	}
+	var list []*categoryTotal
+	for _, id := range order {
+		if t := totals[id]; t.count > 0 {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// printCourseGrades はコースの成績をカテゴリごとに表示します。
+func printCourseGrades(w io.Writer, course *classroom.Course, items []*Assignment, verbose bool) {
+	totals := gradeTotals(course, items)
+	if len(totals) == 0 {
+		return
+	}
+	weighted := course.GradebookSettings != nil && course.GradebookSettings.CalculationType == "WEIGHTED_CATEGORIES"
+	method := "合計点"
+	if weighted {
+		method = "カテゴリの加重平均"
+	}
+	fmt.Fprintf(w, "%s (%s)\n", course.Name, method)
+
+	var earned, gradedMax, totalMax float64
+	var weightSum, weightedAvg, progress float64
+	for _, t := range totals {
+		line := fmt.Sprintf("  %s: %g / %g 点 (採点済み %d/%d件)", t.name, t.earned, t.gradedMax, t.graded, t.count)
+		if avg, ok := t.average(); ok {
+			line += fmt.Sprintf(" 得点率 %.1f%%", avg*100)
+			if weighted && t.weight > 0 {
+				weightSum += t.weight
+				weightedAvg += t.weight * avg
+			}
+		}
+		if weighted && t.weight > 0 {
+			line += fmt.Sprintf(" 重み %g%%", t.weight)
+			// 未採点の課題をすべて0点とみなした場合の到達度
+			progress += t.weight * t.earned / t.totalMax
+		}
+		fmt.Fprintln(w, line)
+		if verbose {
+			for _, a := range t.items {
+				score := "未採点"
+				if isGraded(a) {
+					score = fmt.Sprintf("%g", a.Submission.AssignedGrade)
+				}
+				fmt.Fprintf(w, "      %s: %s / %g\n", a.CourseWork.Title, score, a.CourseWork.MaxPoints)
+			}
+		}
+		earned += t.earned
+		gradedMax += t.gradedMax
+		totalMax += t.totalMax
+	}
+	switch {
+	case weighted && weightSum > 0:
+		fmt.Fprintf(w, "  現在の成績: %.1f%% (確定分 %.1f%% / 100%%)\n", weightedAvg/weightSum*100, progress)
+	case !weighted && gradedMax > 0:
+		fmt.Fprintf(w, "  現在の成績: %.1f%% (確定分 %g / %g 点)\n", earned/gradedMax*100, earned, totalMax)
+	}
+	fmt.Fprintln(w)
+}
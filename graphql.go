@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/graph-gophers/graphql-go"
+	"google.golang.org/api/classroom/v1"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// graphqlSchema は /graphql のスキーマです。読み取り専用で、Mutation はありません。
+const graphqlSchema = `
+schema {
+	query: Query
+}
+
+type Query {
+	"コースの一覧"
+	courses: [Course!]!
+	"IDまたは別名で指定したコース"
+	course(id: String!): Course
+	"""
+	課題の一覧。pendingOnly が true (既定) の場合は未提出の課題だけを返します。
+	ほかの引数は GET /api/coursework のクエリパラメーターと同じ条件です。
+	"""
+	coursework(pendingOnly: Boolean = true, course: [String!], type: [String!], state: [String!], topic: [String!], dueBefore: String): [CourseWork!]!
+}
+
+type Course {
+	id: ID!
+	name: String!
+	section: String
+	alias: String
+	link: String!
+	"未提出の課題の件数 (外部ツールの課題を除く)"
+	pendingCount: Int!
+	coursework(pendingOnly: Boolean = true): [CourseWork!]!
+}
+
+type CourseWork {
+	id: ID!
+	"「コース別名/課題スラッグ」形式の識別子"
+	slug: String!
+	title: String!
+	description: String
+	link: String!
+	"ASSIGNMENT、SHORT_ANSWER_QUESTION、MULTIPLE_CHOICE_QUESTION など"
+	type: String!
+	topic: String
+	maxPoints: Float
+	"練習セットやアドオンなど外部のツールで取り組む課題かどうか"
+	external: Boolean!
+	"猶予期間を含む締切 (RFC 3339)"
+	due: String
+	course: Course!
+	submission: Submission
+}
+
+type Submission {
+	id: ID!
+	"NEW、CREATED、TURNED_IN、RETURNED、RECLAIMED_BY_STUDENT"
+	state: String!
+	stateLabel: String!
+	late: Boolean!
+	assignedGrade: Float
+	link: String!
+	updateTime: String
+}
+`
+
+// graphqlMaxDepth はクエリの入れ子の深さの上限です。Course と CourseWork は互いに参照できるため制限します。
+const graphqlMaxDepth = 8
+
+// newGraphQLSchema は /graphql のスキーマを作ります。
+func newGraphQLSchema() *graphql.Schema {
+	return graphql.MustParseSchema(graphqlSchema, &gqlQuery{}, graphql.UseStringDescriptions(), graphql.MaxDepth(graphqlMaxDepth))
+}
+
+// gqlData は1つのリクエストで使う取得結果です。リゾルバーはこれだけを参照し、APIを呼びません。
+type gqlData struct {
+	cfg     *Config
+	now     time.Time
+	items   []*Assignment
+	pending map[*Assignment]bool
+	courses []*classroom.Course
+}
+
+type gqlDataKey struct{}
+
+func gqlDataFrom(ctx context.Context) *gqlData {
+	return ctx.Value(gqlDataKey{}).(*gqlData)
+}
+
+// coursework は pendingOnly に応じて課題を返します。
+func (d *gqlData) coursework(pendingOnly bool) []*Assignment {
+	if !pendingOnly {
+		return d.items
+	}
+	var items []*Assignment
+	for _, a := range d.items {
+		if d.pending[a] {
+			items = append(items, a)
+		}
+	}
+	return items
+}
+
+func (d *gqlData) course(c *classroom.Course) *gqlCourse {
+	return &gqlCourse{d: d, c: c}
+}
+
+func (d *gqlData) courseWork(items []*Assignment) []*gqlCourseWork {
+	res := []*gqlCourseWork{}
+	for _, a := range items {
+		res = append(res, &gqlCourseWork{d: d, a: a})
+	}
+	return res
+}
+
+type gqlQuery struct{}
+
+func (*gqlQuery) Courses(ctx context.Context) []*gqlCourse {
+	d := gqlDataFrom(ctx)
+	res := []*gqlCourse{}
+	for _, c := range d.courses {
+		res = append(res, d.course(c))
+	}
+	return res
+}
+
+func (*gqlQuery) Course(ctx context.Context, args struct{ ID string }) *gqlCourse {
+	d := gqlDataFrom(ctx)
+	for _, c := range d.courses {
+		if c.Id == args.ID || d.cfg.course(c.Id).Alias == args.ID {
+			return d.course(c)
+		}
+	}
+	for _, a := range d.items {
+		if courseLabel(a) == args.ID {
+			return d.course(a.Course)
+		}
+	}
+	return nil
+}
+
+type gqlCourseworkArgs struct {
+	PendingOnly bool
+	Course      *[]string
+	Type        *[]string
+	State       *[]string
+	Topic       *[]string
+	DueBefore   *string
+}
+
+func (*gqlQuery) Coursework(ctx context.Context, args gqlCourseworkArgs) ([]*gqlCourseWork, error) {
+	d := gqlDataFrom(ctx)
+	// GET /api/coursework と同じ解析と絞り込みを使う
+	q := url.Values{}
+	for name, v := range map[string]*[]string{"course": args.Course, "type": args.Type, "state": args.State, "topic": args.Topic} {
+		if v != nil {
+			q[name] = *v
+		}
+	}
+	if args.DueBefore != nil {
+		q.Set("due_before", *args.DueBefore)
+	}
+	filter, err := filterFromQuery(q, d.now)
+	if err != nil {
+		return nil, err
+	}
+	return d.courseWork(filter.apply(d.coursework(args.PendingOnly))), nil
+}
+
+type gqlCourse struct {
+	d *gqlData
+	c *classroom.Course
+}
+
+func (c *gqlCourse) ID() graphql.ID { return graphql.ID(c.c.Id) }
+func (c *gqlCourse) Name() string   { return c.c.Name }
+func (c *gqlCourse) Link() string   { return c.c.AlternateLink }
+
+func (c *gqlCourse) Section() *string { return optionalString(c.c.Section) }
+
+func (c *gqlCourse) Alias() *string {
+	if alias := c.d.cfg.course(c.c.Id).Alias; alias != "" {
+		return &alias
+	}
+	for _, a := range c.d.items {
+		if a.Course.Id == c.c.Id {
+			return optionalString(courseLabel(a))
+		}
+	}
+	return nil
+}
+
+func (c *gqlCourse) items(pendingOnly bool) []*Assignment {
+	var items []*Assignment
+	for _, a := range c.d.coursework(pendingOnly) {
+		if a.Course.Id == c.c.Id {
+			items = append(items, a)
+		}
+	}
+	return items
+}
+
+func (c *gqlCourse) PendingCount() int32 {
+	return int32(countSubmittable(c.items(true)))
+}
+
+func (c *gqlCourse) Coursework(args struct{ PendingOnly bool }) []*gqlCourseWork {
+	return c.d.courseWork(c.items(args.PendingOnly))
+}
+
+type gqlCourseWork struct {
+	d *gqlData
+	a *Assignment
+}
+
+func (w *gqlCourseWork) ID() graphql.ID       { return graphql.ID(w.a.CourseWork.Id) }
+func (w *gqlCourseWork) Slug() string         { return w.a.Slug }
+func (w *gqlCourseWork) Title() string        { return w.a.CourseWork.Title }
+func (w *gqlCourseWork) Description() *string { return optionalString(w.a.CourseWork.Description) }
+func (w *gqlCourseWork) Link() string         { return w.a.CourseWork.AlternateLink }
+func (w *gqlCourseWork) Type() string         { return w.a.CourseWork.WorkType }
+func (w *gqlCourseWork) Topic() *string       { return optionalString(w.a.Topic) }
+func (w *gqlCourseWork) External() bool       { return w.a.External() }
+func (w *gqlCourseWork) Course() *gqlCourse   { return w.d.course(w.a.Course) }
+
+func (w *gqlCourseWork) MaxPoints() *float64 {
+	if w.a.CourseWork.MaxPoints == 0 {
+		return nil
+	}
+	return &w.a.CourseWork.MaxPoints
+}
+
+func (w *gqlCourseWork) Due() *string {
+	due, ok := w.a.EffectiveDue()
+	if !ok {
+		return nil
+	}
+	return optionalString(due.Format(time.RFC3339))
+}
+
+func (w *gqlCourseWork) Submission() *gqlSubmission {
+	if w.a.Submission == nil {
+		return nil
+	}
+	return &gqlSubmission{w.a.Submission}
+}
+
+type gqlSubmission struct {
+	s *classroom.StudentSubmission
+}
+
+func (s *gqlSubmission) ID() graphql.ID      { return graphql.ID(s.s.Id) }
+func (s *gqlSubmission) State() string       { return s.s.State }
+func (s *gqlSubmission) StateLabel() string  { return submissionLabel(s.s) }
+func (s *gqlSubmission) Late() bool          { return s.s.Late }
+func (s *gqlSubmission) Link() string        { return s.s.AlternateLink }
+func (s *gqlSubmission) UpdateTime() *string { return optionalString(s.s.UpdateTime) }
+
+func (s *gqlSubmission) AssignedGrade() *float64 {
+	// APIは採点されていない提出物の点数を省略するため、0点と区別できない
+	if s.s.AssignedGrade == 0 {
+		return nil
+	}
+	return &s.s.AssignedGrade
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// graphqlRequest は POST /graphql の本文です。
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+	Extensions    map[string]any `json:"extensions"`
+}
+
+// handleGraphQL はGraphQLのクエリを実行します。POST の JSON 本文と、GET の query パラメーターを受け付けます。
+// 結果はアカウントの取得済みの結果から作るため、1回のクエリでClassroom APIを何度も呼ぶことはありません。
+func (s *server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphqlRequest
+	if r.Method == http.MethodGet {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+	} else if err := decodeJSONBody(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "query を指定してください", http.StatusBadRequest)
+		return
+	}
+	a, ok := s.account(w, r)
+	if !ok {
+		return
+	}
+	v, err := a.cached(r.Context())
+	if errors.Is(err, errNotLoggedIn) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("課題を取得できませんでした: %v", err)
+		http.Error(w, "課題を取得できませんでした", http.StatusBadGateway)
+		return
+	}
+	now := time.Now()
+	d := &gqlData{cfg: a.cfg, now: now, items: v.Items, pending: map[*Assignment]bool{}, courses: v.Courses}
+	for _, it := range pendingAssignments(v.Items, now) {
+		d.pending[it] = true
+	}
+	ctx := context.WithValue(r.Context(), gqlDataKey{}, d)
+	writeJSON(w, http.StatusOK, s.graphql.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
@@ -0,0 +1,295 @@
+package main
+
+//go:generate protoc -I classroompb --go_out=classroompb --go_opt=paths=source_relative --go-grpc_out=classroompb --go-grpc_opt=paths=source_relative classroompb/classroom.proto
+
+import (
+	"classroom-api/classroompb"
+	"context"
+	"crypto/tls"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// grpcScopes はメソッドごとに必要な権限です。HTTPの同じ内容のエンドポイントと合わせています。
+var grpcScopes = map[string]string{
+	classroompb.CourseWorkService_ListCourses_FullMethodName:           "titles",
+	classroompb.CourseWorkService_ListPendingCourseWork_FullMethodName: "coursework",
+	classroompb.CourseWorkService_WatchCourseWork_FullMethodName:       "coursework",
+}
+
+// grpcService は classroompb.CourseWorkServiceServer を実装します。
+type grpcService struct {
+	classroompb.UnimplementedCourseWorkServiceServer
+	s *server
+}
+
+// newGRPCServer は gRPC のサーバーを作ります。tlsConfig が nil の場合は平文で待ち受けます。
+func (s *server) newGRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	logger := newAccessLogger(s.cfg)
+	opts = append(opts,
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+			var res any
+			err := s.grpcCall(ctx, info.FullMethod, logger, func(ctx context.Context) (err error) {
+				res, err = h(ctx, req)
+				return err
+			})
+			return res, err
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+			return s.grpcCall(ss.Context(), info.FullMethod, logger, func(ctx context.Context) error {
+				return h(srv, &grpcServerStream{ServerStream: ss, ctx: ctx})
+			})
+		}),
+	)
+	gs := grpc.NewServer(opts...)
+	classroompb.RegisterCourseWorkServiceServer(gs, &grpcService{s: s})
+	return gs
+}
+
+// serveGRPC は l の接続を gs で処理します。gs.GracefulStop で終了した場合は nil を返します。
+func serveGRPC(gs *grpc.Server, l net.Listener) error {
+	return gs.Serve(l)
+}
+
+// grpcServerStream はリクエストIDを付けたコンテキストをハンドラーに渡すための ServerStream です。
+type grpcServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *grpcServerStream) Context() context.Context { return ss.ctx }
+
+// grpcCall はHTTPのミドルウェアと同じく、呼び出しにリクエストIDを付けてリクエスト数の制限、読み取り専用モード、
+// 権限を確かめてから call を実行し、logger が nil でなければアクセスログを書きます。
+func (s *server) grpcCall(ctx context.Context, method string, logger *slog.Logger, call func(ctx context.Context) error) error {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(requestIDHeader); len(v) > 0 && validRequestID.MatchString(v[0]) {
+			id = v[0]
+		}
+	}
+	if id == "" {
+		id = newRequestID()
+	}
+	ctx = withRequestID(ctx, id)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, id))
+	start := time.Now()
+	err := s.checkGRPCRateLimit(ctx)
+	if err == nil {
+		err = checkGRPCReadOnly(s.cfg, method)
+	}
+	if err == nil {
+		err = s.checkGRPCScope(ctx, method)
+	}
+	if err == nil {
+		err = call(ctx)
+	}
+	if logger != nil {
+		r := grpcRequest(ctx)
+		logger.LogAttrs(ctx, slog.LevelInfo, "request",
+			slog.String("id", id),
+			slog.String("method", "GRPC"),
+			slog.String("path", method),
+			slog.String("grpc_code", status.Code(err).String()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("user", s.requestUser(r)),
+			slog.String("remote", r.RemoteAddr),
+		)
+	}
+	return err
+}
+
+// checkGRPCRateLimit はHTTPのAPIと同じ制限を gRPC の呼び出しにも適用します。
+// 制限を超えた場合はメタデータの retry-after に次に呼び出せるまでの秒数を付けます。
+func (s *server) checkGRPCRateLimit(ctx context.Context) error {
+	if ok, wait := s.limiter.allow(s.limiter.clientKey(grpcRequest(ctx)), time.Now()); !ok {
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter(wait)))
+		return status.Error(codes.ResourceExhausted, "リクエストが多すぎます")
+	}
+	return nil
+}
+
+// grpcRequest はHTTPのAPIと同じ確認を使うため、メタデータの authorization をヘッダーに持ち、
+// 接続元のアドレスを RemoteAddr に持つリクエストを作ります。
+func grpcRequest(ctx context.Context) *http.Request {
+	r := (&http.Request{Method: http.MethodPost, URL: &url.URL{Path: "/"}, Header: http.Header{}}).WithContext(ctx)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get("authorization") {
+			r.Header.Add("Authorization", v)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+	}
+	return r
+}
+
+// checkGRPCScope はメソッドに必要な権限を確かめます。
+func (s *server) checkGRPCScope(ctx context.Context, method string) error {
+	scope, ok := grpcScopes[method]
+	if !ok {
+		return status.Error(codes.Unimplemented, "不明なメソッドです")
+	}
+	if err := s.checkScope(grpcRequest(ctx), scope); err != nil {
+		code := codes.PermissionDenied
+		if err.status == http.StatusUnauthorized {
+			code = codes.Unauthenticated
+		}
+		return status.Error(code, err.msg)
+	}
+	return nil
+}
+
+// cached はリクエストのアカウントの取得結果を返します。
+func (g *grpcService) cached(ctx context.Context) (*account, *cachedAccount, error) {
+	a, err := g.s.requestAccount(grpcRequest(ctx))
+	if err != nil {
+		return nil, nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	v, err := a.cached(ctx)
+	if errors.Is(err, errNotLoggedIn) {
+		return nil, nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		log.Printf("課題を取得できませんでした: %v", err)
+		return nil, nil, status.Error(codes.Unavailable, "課題を取得できませんでした")
+	}
+	return a, v, nil
+}
+
+func (g *grpcService) ListCourses(ctx context.Context, req *classroompb.ListCoursesRequest) (*classroompb.ListCoursesResponse, error) {
+	a, v, err := g.cached(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &classroompb.ListCoursesResponse{}
+	for _, c := range apiCourses(a.cfg, v.Courses, pendingAssignments(v.Items, time.Now())) {
+		res.Courses = append(res.Courses, &classroompb.Course{
+			Id: c.Id, Name: c.Name, Section: c.Section, Alias: c.Alias, Link: c.Link, Pending: int32(c.Pending),
+		})
+	}
+	return res, nil
+}
+
+func (g *grpcService) ListPendingCourseWork(ctx context.Context, req *classroompb.ListPendingCourseWorkRequest) (*classroompb.ListPendingCourseWorkResponse, error) {
+	now := time.Now()
+	// GET /api/coursework と同じ解析と絞り込みを使う
+	q := url.Values{"course": req.Course, "type": req.Type, "state": req.State, "topic": req.Topic}
+	if req.DueBefore != "" {
+		q.Set("due_before", req.DueBefore)
+	}
+	filter, err := filterFromQuery(q, now)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var size string
+	if req.PageSize != 0 {
+		size = strconv.Itoa(int(req.PageSize))
+	}
+	pg, err := parsePage(size, req.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	_, v, err := g.cached(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := filter.apply(pendingAssignments(v.Items, now))
+	start, end, next := pg.slice(len(items))
+	res := &classroompb.ListPendingCourseWorkResponse{NextPageToken: next}
+	for _, a := range items[start:end] {
+		res.CourseWork = append(res.CourseWork, courseWorkProto(a, now))
+	}
+	return res, nil
+}
+
+// grpcEventTypes は liveEvent.Type と CourseWorkEvent.Type の対応です。
+var grpcEventTypes = map[string]classroompb.CourseWorkEvent_Type{
+	"created":   classroompb.CourseWorkEvent_CREATED,
+	"updated":   classroompb.CourseWorkEvent_UPDATED,
+	"turned_in": classroompb.CourseWorkEvent_TURNED_IN,
+	"removed":   classroompb.CourseWorkEvent_REMOVED,
+}
+
+func (g *grpcService) WatchCourseWork(req *classroompb.WatchCourseWorkRequest, stream classroompb.CourseWorkService_WatchCourseWorkServer) error {
+	a, err := g.s.requestAccount(grpcRequest(stream.Context()))
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	updates, unsubscribe := a.subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-g.s.ctx.Done():
+			return status.Error(codes.Unavailable, "サーバーを終了しています")
+		case c := <-updates:
+			for _, ev := range c.Events {
+				err := stream.Send(&classroompb.CourseWorkEvent{
+					Type:       grpcEventTypes[ev.Type],
+					CourseWork: courseWorkProto(ev.Assignment, c.Time),
+					Total:      int32(c.Total),
+					Time:       timestamppb.New(c.Time),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// courseWorkProto は課題を gRPC のメッセージにします。内容は GET /api/pending の要素と同じです。
+func courseWorkProto(a *Assignment, now time.Time) *classroompb.CourseWork {
+	d := newTemplateData(a, now)
+	cw := &classroompb.CourseWork{
+		Id:          d.ID,
+		Slug:        d.Slug,
+		Title:       d.Title,
+		Description: d.Description,
+		Link:        d.Link,
+		Type:        d.Type,
+		Topic:       d.Topic,
+		MaxPoints:   d.Points,
+		External:    d.External,
+		Course: &classroompb.Course{
+			Id:      d.Course.ID,
+			Name:    d.Course.Name,
+			Section: d.Course.Section,
+			Alias:   d.Course.Alias,
+			Link:    d.Course.Link,
+		},
+	}
+	if d.HasDue {
+		cw.Due = timestamppb.New(d.Due)
+	}
+	if s := a.Submission; s != nil {
+		cw.Submission = &classroompb.Submission{
+			Id:            s.Id,
+			State:         s.State,
+			StateLabel:    d.Submission.StateLabel,
+			Late:          s.Late,
+			AssignedGrade: s.AssignedGrade,
+			Link:          s.AlternateLink,
+		}
+	}
+	return cw
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"classroom-api/classroompb"
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+)
+
+func TestGRPCCallChecks(t *testing.T) {
+	cfg := &Config{ReadOnly: true}
+	cfg.Server.RateLimit = RateLimitConfig{Rate: 1, Burst: 2}
+	cfg.Server.RateLimit.setDefaults()
+	s := &server{cfg: cfg, limiter: newRateLimiter(cfg.Server.RateLimit)}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}})
+	ok := func(context.Context) error { return nil }
+
+	var id string
+	err := s.grpcCall(ctx, classroompb.CourseWorkService_ListCourses_FullMethodName, nil, func(ctx context.Context) error {
+		id = requestID(ctx)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("読み取りのメソッドを拒否しました: %v", err)
+	}
+	if id == "" {
+		t.Error("呼び出しにリクエストIDが付いていません")
+	}
+	// 読み取り専用モードでは一覧にないメソッドを拒否する
+	err = s.grpcCall(ctx, "/classroom.CourseWorkService/TurnIn", nil, ok)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("読み取り専用モードで変更のメソッドを呼び出せました: %v", err)
+	}
+	// HTTPと同じく接続元のアドレスごとに制限する
+	err = s.grpcCall(ctx, classroompb.CourseWorkService_ListCourses_FullMethodName, nil, ok)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("制限を超えた呼び出しを許可しました: %v", err)
+	}
+	other := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 1234}})
+	if err := s.grpcCall(other, classroompb.CourseWorkService_ListCourses_FullMethodName, nil, ok); err != nil {
+		t.Errorf("ほかの接続元の呼び出しを拒否しました: %v", err)
+	}
+}
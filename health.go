@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// healthFile は最後の同期の状態を記録するファイルです。
+const healthFile = "health.json"
+
+// Health は同期の状態です。
+type Health struct {
+	// Mode は "push" または "polling" です。
+	Mode string `json:"mode"`
+	// Reason はポーリングに切り替えた理由です。
+	Reason    string    `json:"reason,omitempty"`
+	LastSync  time.Time `json:"lastSync"`
+	LastError string    `json:"lastError,omitempty"`
+	NextSync  time.Time `json:"nextSync"`
+}
+
+// newHealth は同期の結果から状態を作ります。
+func newHealth(mode syncMode, last time.Time, err error, next time.Duration) *Health {
+	h := &Health{Mode: "polling", Reason: mode.Reason, LastSync: last, NextSync: last.Add(next)}
+	if mode.Push {
+		h.Mode = "push"
+	}
+	if err != nil {
+		h.LastError = err.Error()
+	}
+	return h
+}
+
+func saveHealth(cfg *Config, h *Health) {
+	if err := writeJSONFile(cfg.dataPath(healthFile), h); err != nil {
+		log.Printf("状態を保存できませんでした: %v", err)
+	}
+}
+
+func loadHealth(cfg *Config) (*Health, error) {
+	h := &Health{}
+	if err := readJSONFile(cfg.dataPath(healthFile), h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func runHealth(ctx context.Context, cfg *Config, args []string) {
+	h, err := loadHealth(cfg)
+	if err != nil {
+		log.Fatalf("状態を読み取れませんでした: %v", err)
+	}
+	if h.LastSync.IsZero() {
+		fmt.Println("まだ同期していません")
+		os.Exit(1)
+	}
+	mode := "プッシュ通知"
+	if h.Mode != "push" {
+		mode = "ポーリング"
+		if h.Reason != "" {
+			mode += " (" + h.Reason + ")"
+		}
+	}
+	fmt.Printf("モード:     %s\n", mode)
+	fmt.Printf("最終同期:   %s\n", formatTime(h.LastSync))
+	fmt.Printf("次回同期:   %s\n", formatTime(h.NextSync))
+	if h.LastError != "" {
+		fmt.Printf("エラー:     %s\n", h.LastError)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HealthcheckConfig は daemon が同期するたびに死活監視のサービス (healthchecks.io など) に送る ping の設定です。
+// ping が途絶えるとサービスが知らせるため、トークンの期限切れやプロセスの停止に気付けます。
+type HealthcheckConfig struct {
+	// URL は同期に成功したときに GET する URL です (例: "https://hc-ping.com/<uuid>")。
+	URL string `json:"url"`
+	// FailURL は同期に失敗したときにエラーメッセージを本文にして POST する URL です (例: "https://hc-ping.com/<uuid>/fail")。
+	// 省略した場合は失敗を送らず、ping が途絶えることで知らせます。
+	FailURL string `json:"failUrl,omitempty"`
+}
+
+// healthcheckMaxBody は失敗の ping に付けるエラーメッセージの最大の文字数です。
+const healthcheckMaxBody = 10000
+
+// ping は同期の結果を死活監視のサービスに送ります。syncErr が nil なら成功、そうでなければ失敗として送ります。
+// 送れなかった場合もログに書くだけで、同期は続けます。
+func (hc *HealthcheckConfig) ping(ctx context.Context, syncErr error) {
+	if hc == nil {
+		return
+	}
+	var err error
+	switch {
+	case syncErr == nil && hc.URL != "":
+		err = sendPing(ctx, http.MethodGet, hc.URL, "")
+	case syncErr != nil && hc.FailURL != "":
+		err = sendPing(ctx, http.MethodPost, hc.FailURL, truncateRunes(syncErr.Error(), healthcheckMaxBody))
+	}
+	if err != nil {
+		log.Printf("死活監視に ping を送れませんでした: %v", err)
+	}
+}
+
+func sendPing(ctx context.Context, method, rawURL, body string) error {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "classroom-api")
+	if body != "" {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	res, err := client.Do(req)
+	if err != nil {
+		// URL にはチェックの秘密の ID が含まれるため、エラーメッセージには含めない
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1024))
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("%s を返しました", res.Status)
+	}
+	return nil
+}
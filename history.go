@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"log"
+	"time"
+)
+
+// historyRetention はキャッシュに課題の変更履歴を残す期間です。
+const historyRetention = 90 * 24 * time.Hour
+
+// courseWorkChange は先生が課題を編集したときの変更1件です。
+type courseWorkChange struct {
+	CourseWorkID string
+	Course       string
+	Title        string
+	// Field は変更された項目 ("due", "title", "description") です。
+	Field string
+	// Old と New は変更前と変更後の値です。締切は RFC 3339 形式で、締切がない場合は空です。
+	Old, New string
+	Changed  time.Time
+}
+
+// diffCourseWork は同じ課題の前回と今回の内容を比べて、締切・タイトル・説明の変更を返します。
+func diffCourseWork(prev, cur *classroom.CourseWork, now time.Time) []*courseWorkChange {
+	changed := now
+	if t, err := time.Parse(time.RFC3339Nano, cur.UpdateTime); err == nil {
+		changed = t
+	}
+	var changes []*courseWorkChange
+	add := func(field, old, new string) {
+		if old == new {
+			return
+		}
+		changes = append(changes, &courseWorkChange{
+			CourseWorkID: cur.Id,
+			Course:       cur.CourseId,
+			Title:        cur.Title,
+			Field:        field,
+			Old:          old,
+			New:          new,
+			Changed:      changed,
+		})
+	}
+	add("due", dueString(prev), dueString(cur))
+	add("title", prev.Title, cur.Title)
+	add("description", prev.Description, cur.Description)
+	return changes
+}
+
+func dueString(c *classroom.CourseWork) string {
+	due, ok := dueTime(c)
+	if !ok {
+		return ""
+	}
+	return due.Format(time.RFC3339)
+}
+
+// recordChanges はキャッシュにある課題と items を比べ、変更を履歴に追加します。
+// 保存期間を過ぎた履歴は削除します。履歴の時刻は文字列として比べるため、すべてUTCで保存します。
+func recordChanges(ctx context.Context, tx *sql.Tx, items []*Assignment, now time.Time) error {
+	for _, a := range items {
+		var data string
+		err := tx.QueryRowContext(ctx, "SELECT data FROM coursework WHERE id = ?", a.CourseWork.Id).Scan(&data)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		prev := &classroom.CourseWork{}
+		if err := json.Unmarshal([]byte(data), prev); err != nil {
+			return err
+		}
+		for _, ch := range diffCourseWork(prev, a.CourseWork, now) {
+			_, err := tx.ExecContext(ctx, "INSERT INTO history (coursework_id, course, title, field, old, new, changed) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				ch.CourseWorkID, a.Course.Name, ch.Title, ch.Field, ch.Old, ch.New, ch.Changed.UTC().Format(time.RFC3339))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	_, err := tx.ExecContext(ctx, "DELETE FROM history WHERE changed < ?", now.Add(-historyRetention).UTC().Format(time.RFC3339))
+	return err
+}
+
+// loadChanges は since 以降の課題の変更を新しい順に返します。
+func loadChanges(ctx context.Context, db *sql.DB, since time.Time) ([]*courseWorkChange, error) {
+	rows, err := db.QueryContext(ctx, "SELECT coursework_id, course, title, field, old, new, changed FROM history WHERE changed >= ? ORDER BY changed DESC", since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []*courseWorkChange
+	for rows.Next() {
+		ch := &courseWorkChange{}
+		var changed string
+		if err := rows.Scan(&ch.CourseWorkID, &ch.Course, &ch.Title, &ch.Field, &ch.Old, &ch.New, &changed); err != nil {
+			return nil, err
+		}
+		if ch.Changed, err = time.Parse(time.RFC3339, changed); err != nil {
+			return nil, err
+		}
+		changes = append(changes, ch)
+	}
+	return changes, rows.Err()
+}
+
+// describe は変更を「締切が火曜日から金曜日に変更されました」のような文で返します。
+func (ch *courseWorkChange) describe() string {
+	switch ch.Field {
+	case "due":
+		return fmt.Sprintf("「%s」(%s) の締切が %s から %s に変更されました", ch.Title, ch.Course, describeDue(ch.Old), describeDue(ch.New))
+	case "title":
+		return fmt.Sprintf("「%s」(%s) のタイトルが「%s」に変更されました", ch.Old, ch.Course, ch.New)
+	case "description":
+		return fmt.Sprintf("「%s」(%s) の説明が編集されました", ch.Title, ch.Course)
+	}
+	return fmt.Sprintf("「%s」(%s) の %s が変更されました", ch.Title, ch.Course, ch.Field)
+}
+
+func describeDue(s string) string {
+	if s == "" {
+		return "なし"
+	}
+	return formatAPITime(s)
+}
+
+func runChanges(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("changes", flag.ExitOnError)
+	days := fs.Int("days", 14, "表示する期間 (日数)")
+	fs.Parse(args)
+
+	db, err := openCache(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	changes, err := loadChanges(ctx, db, time.Now().AddDate(0, 0, -*days))
+	if err != nil {
+		log.Fatalf("変更履歴を読み取れませんでした: %v", err)
+	}
+	if len(changes) == 0 {
+		fmt.Printf("直近%d日間に変更された課題はありません (変更履歴は sync のたびに記録されます)\n", *days)
+		return
+	}
+	for _, ch := range changes {
+		fmt.Printf("%s  %s\n", formatTime(ch.Changed.Local()), ch.describe())
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"google.golang.org/api/classroom/v1"
+	"testing"
+	"time"
+)
+
+func TestHistoryTimesInUTC(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir()}
+	db, err := openCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	jst := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, jst)
+	course := &classroom.Course{Id: "c1", Name: "数学"}
+	courses := []*classroom.Course{course}
+	if err := writeCache(ctx, db, courses, []*Assignment{{Course: course, CourseWork: &classroom.CourseWork{Id: "w1", CourseId: "c1", Title: "前"}}}, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := mergeCache(ctx, db, courses, []*Assignment{{Course: course, CourseWork: &classroom.CourseWork{Id: "w1", CourseId: "c1", Title: "後", UpdateTime: "2026-10-16T00:00:00Z"}}}, now); err != nil {
+		t.Fatal(err)
+	}
+	// UTCの更新日時の変更も、ローカル時刻で指定した30分前からの履歴に含まれる
+	changes, err := loadChanges(ctx, db, now.Add(-30*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || !changes[0].Changed.Equal(now) {
+		t.Fatalf("履歴 = %v, want %s の変更1件", changes, now)
+	}
+	// 変更の後からの履歴には含まれない
+	if changes, err := loadChanges(ctx, db, now.Add(time.Minute)); err != nil || len(changes) != 0 {
+		t.Errorf("変更の後からの履歴 = %d件, %v, want 0件", len(changes), err)
+	}
+}
+
+func TestRestoreCacheSkipsHistory(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir()}
+	db, err := openCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	course := &classroom.Course{Id: "c1", Name: "数学"}
+	courses := []*classroom.Course{course}
+	if err := writeCache(ctx, db, courses, []*Assignment{{Course: course, CourseWork: &classroom.CourseWork{Id: "w1", CourseId: "c1", Title: "今"}}}, now); err != nil {
+		t.Fatal(err)
+	}
+	// 過去のスナップショットとの差は課題の変更ではない
+	old := now.Add(-24 * time.Hour)
+	if err := restoreCache(ctx, db, courses, []*Assignment{{Course: course, CourseWork: &classroom.CourseWork{Id: "w1", CourseId: "c1", Title: "昔"}}}, old); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := loadChanges(ctx, db, old.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("スナップショットの読み込みで変更履歴を記録しました: %d件", len(changes))
+	}
+}
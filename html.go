@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// htmlReport はHTMLのレポートに渡すデータです。
+type htmlReport struct {
+	Title     string
+	Generated string
+	Courses   []*htmlCourse
+}
+
+type htmlCourse struct {
+	ID   string
+	Name string
+	Link string
+	Rows []*htmlRow
+}
+
+type htmlRow struct {
+	ID    string
+	Title string
+	Link  string
+	Type  string
+	Topic string
+	// Due は表示用の締切、DueSort は並べ替え用の値 (Unix時間、締切なしは空) です。
+	Due     string
+	DueSort string
+	DueIn   string
+	Points  string
+	State   string
+	// Class は締切の近さに応じたCSSのクラスです。
+	Class string
+}
+
+// htmlTemplate は外部のファイルを読み込まない1つのHTMLです。
+// 表の見出しをクリックするとその列で並べ替えます。
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, -apple-system, "Hiragino Sans", "Yu Gothic UI", sans-serif; margin: 2rem; color: #222; }
+h1 { font-size: 1.5rem; }
+h2 { font-size: 1.2rem; margin-top: 2rem; }
+h2 a { color: inherit; }
+.generated { color: #666; font-size: .9rem; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: .4rem .6rem; border-bottom: 1px solid #ddd; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; cursor: pointer; user-select: none; white-space: nowrap; }
+th[aria-sort="ascending"]::after { content: " ▲"; }
+th[aria-sort="descending"]::after { content: " ▼"; }
+tr.urgent td { background: #fde2e2; }
+tr.soon td { background: #fff6d6; }
+td.num { text-align: right; }
+.empty { color: #666; }
+@media print { th { cursor: auto; } a { color: inherit; text-decoration: none; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="generated">{{.Generated}} 時点 ・ <span style="background:#fde2e2">24時間以内</span> <span style="background:#fff6d6">3日以内</span></p>
+{{range .Courses}}
+<h2>{{if .Link}}<a href="{{.Link}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</h2>
+<table class="sortable">
+<thead><tr><th>課題</th><th>種類</th><th>トピック</th><th aria-sort="ascending">締切</th><th>残り</th><th>配点</th><th>状態</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr class="{{.Class}}"><td><a href="{{.Link}}">{{.Title}}</a></td><td>{{.Type}}</td><td>{{.Topic}}</td><td data-sort="{{.DueSort}}">{{.Due}}</td><td data-sort="{{.DueSort}}">{{.DueIn}}</td><td class="num">{{.Points}}</td><td>{{.State}}</td></tr>
+{{end}}</tbody>
+</table>
+{{else}}<p class="empty">未提出の課題はありません。</p>
+{{end}}
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  var headers = table.querySelectorAll("th");
+  headers.forEach(function (th, col) {
+    th.addEventListener("click", function () {
+      var asc = th.getAttribute("aria-sort") !== "ascending";
+      headers.forEach(function (h) { h.removeAttribute("aria-sort"); });
+      th.setAttribute("aria-sort", asc ? "ascending" : "descending");
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      var key = function (row) {
+        var cell = row.cells[col];
+        return cell.hasAttribute("data-sort") ? cell.getAttribute("data-sort") : cell.textContent.trim();
+      };
+      rows.sort(function (a, b) {
+        var x = key(a), y = key(b);
+        // 空の値 (締切なしなど) は常に最後にする
+        if (x === "" || y === "") { return (x === "") - (y === ""); }
+        var nx = Number(x), ny = Number(y);
+        var c = !isNaN(nx) && !isNaN(ny) ? nx - ny : x.localeCompare(y, "ja");
+        return asc ? c : -c;
+      });
+      rows.forEach(function (row) { body.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+func runExportHTML(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export html", flag.ExitOnError)
+	out := fs.String("out", "", "出力するHTMLファイル (必須)")
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	if *out == "" {
+		log.Fatal("-out で出力するファイルを指定してください")
+	}
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	now := time.Now()
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("出力ファイルを作成できませんでした: %v", err)
+	}
+	defer f.Close()
+	if err := writeHTMLReport(f, "未提出の課題", filter.apply(pendingAssignments(items, now)), now); err != nil {
+		log.Fatalf("HTMLを書き込めませんでした: %v", err)
+	}
+}
+
+// writeHTMLReport は課題をコースごとの表にしたHTMLを書き出します。コースは最初の課題の締切順に並びます。
+func writeHTMLReport(w io.Writer, title string, items []*Assignment, now time.Time) error {
+	report := &htmlReport{Title: title, Generated: formatTime(now), Courses: groupHTMLCourses(items, now)}
+	return htmlTemplate.Execute(w, report)
+}
+
+// groupHTMLCourses は課題をコースごとの表の行にまとめます。コースは最初の課題の締切順に並びます。
+func groupHTMLCourses(items []*Assignment, now time.Time) []*htmlCourse {
+	var courses []*htmlCourse
+	index := map[string]*htmlCourse{}
+	for _, a := range items {
+		c, ok := index[a.Course.Id]
+		if !ok {
+			c = &htmlCourse{ID: a.Course.Id, Name: a.Course.Name, Link: a.Course.AlternateLink}
+			index[a.Course.Id] = c
+			courses = append(courses, c)
+		}
+		c.Rows = append(c.Rows, newHTMLRow(a, now))
+	}
+	return courses
+}
+
+func newHTMLRow(a *Assignment, now time.Time) *htmlRow {
+	d := newTemplateData(a, now)
+	r := &htmlRow{
+		ID:    d.ID,
+		Title: d.Title,
+		Link:  d.Link,
+		Type:  workTypeLabel(d.Type),
+		Topic: d.Topic,
+		Due:   d.DueText,
+		DueIn: d.DueIn,
+		State: d.Submission.StateLabel,
+	}
+	if d.HasDue {
+		r.DueSort = strconv.FormatInt(d.Due.Unix(), 10)
+	}
+	if d.Points > 0 {
+		r.Points = strconv.FormatFloat(d.Points, 'f', -1, 64)
+	}
+	if d.External {
+		r.Type = "外部"
+	}
+	if r.State == "" {
+		r.State = "提出物なし"
+	}
+	switch urgencyOf(a, now) {
+	case urgencyUrgent:
+		r.Class = "urgent"
+	case urgencySoon:
+		r.Class = "soon"
+	}
+	return r
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ICalConfig はiCalendar形式の出力の設定です。
+type ICalConfig struct {
+	// Alarms は締切の何時間前に通知するか (VALARM) の一覧です。
+	Alarms []Duration `json:"alarms,omitempty"`
+	// Todo が true の場合は予定 (VEVENT) の代わりにタスク (VTODO) として出力します。
+	Todo bool `json:"todo,omitempty"`
+}
+
+func runExportICal(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export ical", flag.ExitOnError)
+	out := fs.String("out", "", "出力ファイル (省略時は標準出力)")
+	dir := fs.String("dir", "", "コースごとの .ics とすべてをまとめた all.ics をこのディレクトリに書き出します")
+	alarms := cfg.ICal.alarms()
+	fs.Func("alarm", "締切の何時間前に通知するか (例: 1d,1h)。設定ファイルの ical.alarms より優先します", func(s string) (err error) {
+		alarms, err = parseAlarms(s)
+		return err
+	})
+	todo := fs.Bool("todo", cfg.ICal.Todo, "予定 (VEVENT) の代わりにタスク (VTODO) として出力します")
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	now := time.Now()
+	pending := filter.apply(pendingAssignments(items, now))
+
+	if *dir == "" {
+		w := os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				log.Fatalf("出力ファイルを作成できませんでした: %v", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := writeICal(w, "Classroom の締切", pending, alarms, *todo, now); err != nil {
+			log.Fatalf("カレンダーを書き込めませんでした: %v", err)
+		}
+		return
+	}
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		log.Fatalf("ディレクトリを作成できませんでした: %v", err)
+	}
+	byCourse := map[string][]*Assignment{}
+	names := map[string]string{}
+	for _, a := range pending {
+		alias, _, _ := strings.Cut(a.Slug, "/")
+		byCourse[alias] = append(byCourse[alias], a)
+		names[alias] = a.Course.Name
+	}
+	write := func(name, title string, items []*Assignment) {
+		f, err := os.Create(filepath.Join(*dir, name))
+		if err != nil {
+			log.Fatalf("出力ファイルを作成できませんでした: %v", err)
+		}
+		defer f.Close()
+		if err := writeICal(f, title, items, alarms, *todo, now); err != nil {
+			log.Fatalf("カレンダーを書き込めませんでした: %v", err)
+		}
+	}
+	for alias, items := range byCourse {
+		write(safeName(alias)+".ics", names[alias]+" の締切", items)
+	}
+	write("all.ics", "Classroom の締切", pending)
+}
+
+// alarms は設定された通知の時刻の一覧を返します。
+func (c ICalConfig) alarms() []time.Duration {
+	var alarms []time.Duration
+	for _, d := range c.Alarms {
+		alarms = append(alarms, time.Duration(d))
+	}
+	return alarms
+}
+
+// parseAlarms はカンマ区切りの通知の時刻 (例: "1d,1h") を解析します。
+func parseAlarms(s string) ([]time.Duration, error) {
+	var alarms []time.Duration
+	for _, v := range splitList(s) {
+		d, err := parseOffset(v)
+		if err != nil {
+			return nil, err
+		}
+		alarms = append(alarms, d)
+	}
+	return alarms, nil
+}
+
+// handleCalendar は未提出の課題の締切をiCalendar形式で返します。
+// カレンダーアプリが購読URLとして定期的に取得するため、取得のたびに最新の課題を返します。
+// 設定ファイルの ical の代わりに todo と alarm を、list コマンドと同じ絞り込みをクエリで指定できます。
+func (s *server) handleCalendar(w http.ResponseWriter, r *http.Request, params CalendarParams) {
+	now := time.Now()
+	filter, err := filterFromQuery(r.URL.Query(), now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	alarms, todo := s.cfg.ICal.alarms(), s.cfg.ICal.Todo
+	if params.Alarm != nil {
+		if alarms, err = parseAlarms(*params.Alarm); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if params.Todo != nil {
+		todo = *params.Todo
+	}
+	pending, ok := s.pending(w, r, now)
+	if !ok {
+		return
+	}
+	var b bytes.Buffer
+	if err := writeICal(&b, "Classroom の締切", filter.apply(pending), alarms, todo, now); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(b.Bytes())
+}
+
+// parseOffset は "1d" や "2h30m" のような時間の長さを解析します。日数 (d) も使えます。
+func parseOffset(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		if _, err := fmt.Sscanf(days, "%d", &n); err != nil {
+			return 0, fmt.Errorf("時間の長さを解析できませんでした: %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// writeICal は締切のある課題をiCalendar形式 (RFC 5545) で書き出します。
+// todo が true の場合は締切を DUE に持つタスク (VTODO) として書き出します。
+func writeICal(w io.Writer, name string, items []*Assignment, alarms []time.Duration, todo bool, now time.Time) error {
+	iw := &icalWriter{w: w}
+	iw.line("BEGIN:VCALENDAR")
+	iw.line("VERSION:2.0")
+	iw.line("PRODID:-//classroom-api//Classroom deadlines//JA")
+	iw.line("CALSCALE:GREGORIAN")
+	iw.prop("X-WR-CALNAME", name)
+	for _, a := range items {
+		due, ok := a.Due()
+		if !ok {
+			continue
+		}
+		c := a.CourseWork
+		component := "VEVENT"
+		if todo {
+			component = "VTODO"
+		}
+		iw.line("BEGIN:" + component)
+		iw.line("UID:" + c.CourseId + "-" + c.Id + "@classroom-api")
+		iw.line("DTSTAMP:" + icalTime(now))
+		if todo {
+			iw.line("DUE:" + icalTime(due))
+			iw.line("STATUS:NEEDS-ACTION")
+			iw.prop("SUMMARY", c.Title)
+		} else {
+			iw.line("DTSTART:" + icalTime(due))
+			iw.line("DTEND:" + icalTime(due))
+			iw.prop("SUMMARY", "【締切】"+c.Title)
+		}
+		iw.prop("DESCRIPTION", icalDescription(a))
+		iw.prop("CATEGORIES", a.Course.Name)
+		iw.line("URL:" + c.AlternateLink)
+		for _, d := range alarms {
+			iw.line("BEGIN:VALARM")
+			iw.line("ACTION:DISPLAY")
+			iw.line("TRIGGER:-" + icalDuration(d))
+			iw.prop("DESCRIPTION", fmt.Sprintf("%s の締切まで%s", c.Title, formatDuration(d)))
+			iw.line("END:VALARM")
+		}
+		iw.line("END:" + component)
+	}
+	iw.line("END:VCALENDAR")
+	return iw.err
+}
+
+// icalDescription は予定の説明欄の本文を返します。
+func icalDescription(a *Assignment) string {
+	c := a.CourseWork
+	var b strings.Builder
+	fmt.Fprintf(&b, "コース: %s\n", a.Course.Name)
+	fmt.Fprintf(&b, "種類: %s\n", workTypeLabel(c.WorkType))
+	if a.Topic != "" {
+		fmt.Fprintf(&b, "トピック: %s\n", a.Topic)
+	}
+	if c.MaxPoints > 0 {
+		fmt.Fprintf(&b, "配点: %g\n", c.MaxPoints)
+	}
+	fmt.Fprintf(&b, "リンク: %s\n", c.AlternateLink)
+	if c.Description != "" {
+		b.WriteString("\n" + c.Description)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// icalWriter は行を CRLF で区切り、75オクテットで折り返して書き込みます。
+type icalWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (iw *icalWriter) line(s string) {
+	if iw.err != nil {
+		return
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		l := len(string(r))
+		if n+l > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += l
+	}
+	b.WriteString("\r\n")
+	_, iw.err = io.WriteString(iw.w, b.String())
+}
+
+// prop はテキスト値をエスケープしてプロパティを書き込みます。
+func (iw *icalWriter) prop(name, value string) {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	iw.line(name + ":" + r.Replace(value))
+}
+
+func icalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icalDuration は時間の長さを RFC 5545 の DURATION 形式で返します。
+func icalDuration(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("P%dD", d/(24*time.Hour))
+	}
+	return fmt.Sprintf("PT%dM", d/time.Minute)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+const (
+	lineAPI = "https://api.line.me/v2/bot"
+	// lineMaxText はテキストメッセージの最大の文字数です。
+	lineMaxText = 5000
+)
+
+// LineConfig はLINE Messaging APIでの通知の設定です。
+type LineConfig struct {
+	// Token はチャネルアクセストークンです。省略した場合は環境変数 LINE_CHANNEL_ACCESS_TOKEN を使います。
+	Token string `json:"token,omitempty"`
+	// To は送り先のユーザーID (U...) またはグループID (C...) です。
+	To []string `json:"to"`
+	// Events は送るできごとの種類です。省略した場合は新しい課題、締切の変更、締切が近い課題、毎日のまとめ、
+	// リマインダー、授業後のまとめを送ります。毎日のまとめだけを送る場合は ["daily_digest"] にします。
+	Events []string `json:"events,omitempty"`
+}
+
+// defaultLineEvents は LineConfig.Events を省略したときに送るできごとです。
+var defaultLineEvents = []string{"created", "due_changed", "due_soon", "daily_digest", "reminder", "digest"}
+
+// lineNotifier は通知をLINEのプッシュメッセージで送ります。
+type lineNotifier struct {
+	cfg  LineConfig
+	rest *restClient
+}
+
+func newLineNotifier(cfg LineConfig) *lineNotifier {
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("LINE_CHANNEL_ACCESS_TOKEN")
+	}
+	if len(cfg.Events) == 0 {
+		cfg.Events = defaultLineEvents
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	return &lineNotifier{cfg: cfg, rest: &restClient{name: "LINE", base: lineAPI, token: cfg.Token, client: client}}
+}
+
+func (n *lineNotifier) Name() string { return "line" }
+
+func (n *lineNotifier) accepts(typ string) bool { return acceptsEvent(n.cfg.Events, typ) }
+
+func (n *lineNotifier) Notify(ctx context.Context, ev *Event) error {
+	if !n.accepts(ev.Type) {
+		return nil
+	}
+	if n.cfg.Token == "" {
+		return fmt.Errorf("LINEのチャネルアクセストークンがありません (notify.line.token または環境変数 LINE_CHANNEL_ACCESS_TOKEN)")
+	}
+	text := truncateRunes(ev.Title+"\n"+ev.Body, lineMaxText)
+	// グループにも送れるよう、複数の送り先にまとめて送る multicast ではなく1件ずつ push で送る
+	for _, to := range n.cfg.To {
+		body := map[string]any{
+			"to":       to,
+			"messages": []map[string]string{{"type": "text", "text": text}},
+		}
+		if err := n.rest.do(ctx, http.MethodPost, "/message/push", body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"google.golang.org/api/option"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// quietErrorExit は list -quiet が失敗したときの終了コードです。件数の終了コードと区別するため、件数は 124 で打ち切ります。
+const quietErrorExit = 125
+
+func runList(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	watch := fs.Bool("watch", false, "一定間隔で再取得して一覧を表示し続けます")
+	interval := fs.Duration("interval", 0, "-watch の再取得の間隔 (0 の場合は締切の近さと時間帯に応じて調整)")
+	quiet := fs.Bool("quiet", false, "何も出力せず、未提出の課題の件数を終了コードにします (最大 124、外部ツールの課題を除く)。失敗した場合は 125 で終了します")
+	count := fs.Bool("count", false, "未提出の課題の件数だけを出力します (外部ツールの課題を除く)")
+	filter := addFilterFlags(fs)
+	opts := addOutputFlags(fs)
+	onlyNew := fs.Bool("new-only", false, "前回の実行以降に投稿・変更された課題だけを表示します (cron からの通知向け)")
+	text := fs.String("template", "", "各課題の表示に使うテンプレート (-template help でデータの説明を表示)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		if listQuiet(args) {
+			os.Exit(quietErrorExit)
+		}
+		os.Exit(2)
+	}
+	fatal := log.Fatal
+	if *quiet {
+		// 件数の終了コードと区別できるように、失敗したときは予約した終了コードで終了する
+		fatal = func(v ...any) {
+			log.Print(v...)
+			os.Exit(quietErrorExit)
+		}
+	}
+
+	var tmpl *template.Template
+	if *text == "help" {
+		fmt.Print(templateHelp)
+		return
+	} else if *text != "" {
+		if !strings.HasSuffix(*text, "\n") {
+			*text += "\n"
+		}
+		var err error
+		if tmpl, err = parseTemplate("list", *text); err != nil {
+			fatal("テンプレートを解析できませんでした: ", err)
+		}
+	}
+
+	var client *http.Client
+	if *quiet {
+		// 認証が必要な場合にブラウザでの認証を待たずに失敗する
+		var err error
+		if client, err = savedHTTPClient(cfg); err != nil {
+			fatal(err)
+		}
+	} else {
+		client = newHTTPClient(cfg)
+	}
+	srv, err := classroom.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		fatal("Classroomクライアントを作成できませんでした: ", err)
+	}
+	if *watch {
+		watchList(ctx, cfg, srv, filter, opts, *interval)
+		return
+	}
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		fatal(err)
+	}
+	pending := filter.apply(pendingAssignments(items, time.Now()))
+	if *onlyNew {
+		if pending, err = newOnly(cfg, pending); err != nil {
+			fatal(err)
+		}
+	}
+	switch {
+	case *quiet:
+		os.Exit(exitCodeForCount(countSubmittable(pending)))
+	case *count:
+		fmt.Println(countSubmittable(pending))
+		return
+	}
+	if tmpl != nil {
+		for _, a := range pending {
+			if err := tmpl.Execute(os.Stdout, newTemplateData(a, opts.now)); err != nil {
+				log.Fatalf("テンプレートを適用できませんでした: %v", err)
+			}
+		}
+		return
+	}
+	for i, a := range pending {
+		fmt.Printf("%2d. ", i+1)
+		printAssignment(os.Stdout, a, opts)
+	}
+}
+
+// loadAssignments は対象コースの課題をすべて取得します。
+// cfg.Cache.MaxAge 以内に sync していればキャッシュから読み取ります。
+func loadAssignments(ctx context.Context, cfg *Config, srv *classroom.Service) ([]*Assignment, error) {
+	if items, ok, err := loadCachedAssignments(ctx, cfg); err != nil {
+		return nil, err
+	} else if ok {
+		if err := applyCourseConfig(cfg, items); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+	courses, err := listCourses(ctx, srv, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return loadCourseAssignments(ctx, cfg, srv, courses)
+}
+
+// loadCourses は対象コースの一覧を返します。オフラインモードではキャッシュから読み取ります。
+func loadCourses(ctx context.Context, cfg *Config, srv *classroom.Service) ([]*classroom.Course, error) {
+	if !cfg.Offline {
+		return listCourses(ctx, srv, cfg)
+	}
+	db, err := openCache(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	courses, _, _, err := readCache(ctx, db)
+	return courses, err
+}
+
+// loadCourseAssignments は指定したコースの課題をすべて取得します。
+func loadCourseAssignments(ctx context.Context, cfg *Config, srv *classroom.Service, courses []*classroom.Course) ([]*Assignment, error) {
+	items, err := fetchAssignments(ctx, srv, courses)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyCourseConfig(cfg, items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// applyCourseConfig は設定に従って課題にスラッグと猶予期間を設定します。
+func applyCourseConfig(cfg *Config, items []*Assignment) error {
+	if err := assignSlugs(cfg, items); err != nil {
+		return err
+	}
+	for _, a := range items {
+		a.Grace = time.Duration(cfg.course(a.Course.Id).GracePeriod)
+	}
+	return nil
+}
+
+// countSubmittable は外部のツールで取り組む課題を除いた件数を返します。
+func countSubmittable(items []*Assignment) int {
+	n := 0
+	for _, a := range items {
+		if !a.External() {
+			n++
+		}
+	}
+	return n
+}
+
+// exitCodeForCount は件数を終了コードに変換します。
+// 126 以上はシェルで特別な意味を持ち、125 は失敗を表すため 124 で打ち切ります。
+func exitCodeForCount(n int) int {
+	if n >= quietErrorExit {
+		return quietErrorExit - 1
+	}
+	return n
+}
+
+// listQuiet は list の引数に -quiet があるかどうかを返します。引数を解析できなかったときの終了コードを決めるために使います。
+func listQuiet(args []string) bool {
+	for _, a := range args {
+		if a == "--" {
+			break
+		}
+		switch strings.TrimLeft(a, "-") {
+		case "quiet", "quiet=true", "quiet=1":
+			return strings.HasPrefix(a, "-")
+		}
+	}
+	return false
+}
+
+func printAssignment(w io.Writer, a *Assignment, opts *outputOptions) {
+	c := a.CourseWork
+	topic := a.Topic
+	if topic == "" {
+		topic = "-"
+	}
+	label := workTypeLabel(c.WorkType)
+	if a.External() {
+		label = "外部"
+	}
+	line := fmt.Sprintf("[%s] %s (%s) due:%s topic:%s link:%s", label, c.Title, a.Slug, opts.dueLabel(a), topic, c.AlternateLink)
+	if opts.color() {
+		if color := urgencyColor(a, opts.now); color != "" {
+			line = color + line + colorReset
+		}
+	}
+	fmt.Fprintln(w, line)
+}
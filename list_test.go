@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestExitCodeForCount(t *testing.T) {
+	for _, tt := range []struct{ n, want int }{{0, 0}, {3, 3}, {124, 124}, {125, 124}, {300, 124}} {
+		if got := exitCodeForCount(tt.n); got != tt.want {
+			t.Errorf("exitCodeForCount(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestListQuiet(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"-quiet"}, true},
+		{[]string{"-course", "数学", "--quiet"}, true},
+		{[]string{"-quiet=true"}, true},
+		{[]string{"-quiet=false"}, false},
+		{[]string{"quiet"}, false},
+		{[]string{"--", "-quiet"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := listQuiet(tt.args); got != tt.want {
+			t.Errorf("listQuiet(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
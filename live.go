@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"golang.org/x/net/websocket"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// liveUpdate は /ws で送る、新しく見つかった課題・変更された課題・一覧から外れた課題の通知です。
+type liveUpdate struct {
+	Type string `json:"type"`
+	// Rows は追加または置き換える表の行です。
+	Rows []*liveRow `json:"rows,omitempty"`
+	// Removed は提出や削除で未提出の一覧から外れた課題のIDです。
+	Removed []string `json:"removed,omitempty"`
+	// Total は未提出の課題の件数です (外部ツールの課題を除く)。
+	Total int `json:"total"`
+}
+
+// liveRow はダッシュボードの表の1行です。HTML はサーバーで描画した <tr> 要素です。
+type liveRow struct {
+	ID         string `json:"id"`
+	CourseID   string `json:"courseId"`
+	CourseName string `json:"courseName"`
+	HTML       string `json:"html"`
+}
+
+// liveEvent は課題の変更1件です。
+type liveEvent struct {
+	// Type は created (新しく未提出の一覧に入った)、updated (内容や状態が変わった)、
+	// turned_in (提出した)、removed (削除などで一覧から外れた) のいずれかです。
+	Type       string
+	Assignment *Assignment
+	// Previous は updated の場合の前回取得したときの課題です。
+	Previous *Assignment
+}
+
+// liveChanges は課題を取得し直したときに見つかった変更です。
+type liveChanges struct {
+	Events []*liveEvent
+	// Total は未提出の課題の件数です (外部ツールの課題を除く)。
+	Total int
+	Time  time.Time
+}
+
+// subscribe は課題の変更を受け取るチャネルを登録します。
+// 変更はバックグラウンドで課題を取得し直したときに送られます。
+func (a *account) subscribe() (<-chan *liveChanges, func()) {
+	ch := make(chan *liveChanges, 8)
+	a.liveMu.Lock()
+	defer a.liveMu.Unlock()
+	if a.subs == nil {
+		a.subs = map[chan *liveChanges]struct{}{}
+	}
+	a.subs[ch] = struct{}{}
+	return ch, func() {
+		a.liveMu.Lock()
+		defer a.liveMu.Unlock()
+		delete(a.subs, ch)
+	}
+}
+
+// diffPending は前回の未提出の課題 seen と今回の課題 items を比べた変更と、今回の未提出の課題を返します。
+func diffPending(seen map[string]*Assignment, items []*Assignment, now time.Time) (*liveChanges, map[string]*Assignment) {
+	pending := pendingAssignments(items, now)
+	changes := &liveChanges{Total: countSubmittable(pending), Time: now}
+	current := make(map[string]*Assignment, len(pending))
+	for _, it := range pending {
+		current[it.CourseWork.Id] = it
+		prev, ok := seen[it.CourseWork.Id]
+		switch {
+		case !ok:
+			changes.Events = append(changes.Events, &liveEvent{Type: "created", Assignment: it})
+		case liveFingerprint(prev) != liveFingerprint(it):
+			changes.Events = append(changes.Events, &liveEvent{Type: "updated", Assignment: it, Previous: prev})
+		}
+	}
+	byID := make(map[string]*Assignment, len(items))
+	for _, it := range items {
+		byID[it.CourseWork.Id] = it
+	}
+	for id, prev := range seen {
+		if _, ok := current[id]; ok {
+			continue
+		}
+		ev := &liveEvent{Type: "removed", Assignment: prev}
+		if it, ok := byID[id]; ok {
+			ev.Assignment = it
+			if st := it.State(); st == "TURNED_IN" || st == "RETURNED" {
+				ev.Type = "turned_in"
+			}
+		}
+		changes.Events = append(changes.Events, ev)
+	}
+	return changes, current
+}
+
+// notify は新しい課題と変更された課題を通知の送信先に送ります。
+func (a *account) notify(ctx context.Context, c *liveChanges) {
+	notifyChanges(ctx, a.notifiers, c, loadSnoozes(a.cfg))
+}
+
+// notifyChanges は変更のうち新しい課題と変更された課題を ns に送ります。
+// 締切が変わった場合は updated の代わりに due_changed として送ります。meta でスヌーズ中の課題は送りません。
+func notifyChanges(ctx context.Context, ns []Notifier, c *liveChanges, meta *metaStore) {
+	for _, ev := range c.Events {
+		if meta.snoozed(ev.Assignment, c.Time) {
+			continue
+		}
+		typ, title := ev.Type, ""
+		due := formatDue(ev.Assignment)
+		key := assignmentKey(ev.Assignment)
+		switch {
+		case ev.Type == "created":
+			title = "新しい課題: " + ev.Assignment.CourseWork.Title
+		case ev.Type == "updated" && dueString(ev.Previous.CourseWork) != dueString(ev.Assignment.CourseWork):
+			typ = "due_changed"
+			title = "締切が変更されました: " + ev.Assignment.CourseWork.Title
+			due = formatDue(ev.Previous) + " → " + due
+			key += "/" + dueString(ev.Assignment.CourseWork)
+		case ev.Type == "updated":
+			title = "課題が変更されました: " + ev.Assignment.CourseWork.Title
+			key += "/" + ev.Assignment.fingerprint()
+		default:
+			continue
+		}
+		notifyAll(ctx, ns, &Event{
+			Type:       typ,
+			Assignment: ev.Assignment,
+			Previous:   ev.Previous,
+			Key:        typ + "/" + key,
+			Title:      title,
+			Body:       fmt.Sprintf("%s\n締切: %s\n%s", ev.Assignment.Course.Name, due, ev.Assignment.CourseWork.AlternateLink),
+			Time:       c.Time,
+		})
+	}
+}
+
+// formatDue は課題の締切を表示用の文字列で返します。締切がない場合は「なし」です。
+func formatDue(a *Assignment) string {
+	if t, ok := a.Due(); ok {
+		return formatTime(t)
+	}
+	return "なし"
+}
+
+// broadcast は登録されたチャネルに送ります。受け取りが追いつかないチャネルには送りません。
+func (a *account) broadcast(c *liveChanges) {
+	a.liveMu.Lock()
+	defer a.liveMu.Unlock()
+	for ch := range a.subs {
+		select {
+		case ch <- c:
+		default:
+		}
+	}
+}
+
+// newLiveUpdate は変更をダッシュボードの表の行の差し替えにします。
+func newLiveUpdate(c *liveChanges) *liveUpdate {
+	u := &liveUpdate{Type: "update", Total: c.Total}
+	for _, ev := range c.Events {
+		a := ev.Assignment
+		switch ev.Type {
+		case "created", "updated":
+			row, err := renderLiveRow(a, c.Time)
+			if err != nil {
+				log.Printf("行を描画できませんでした: %v", err)
+				continue
+			}
+			u.Rows = append(u.Rows, row)
+		default:
+			u.Removed = append(u.Removed, a.CourseWork.Id)
+		}
+	}
+	return u
+}
+
+// liveFingerprint は表示に関わる課題と提出物の変更を検出するための値です。
+func liveFingerprint(a *Assignment) string {
+	return a.CourseWork.UpdateTime + "|" + a.State() + "|" + dueString(a.CourseWork)
+}
+
+func renderLiveRow(a *Assignment, now time.Time) (*liveRow, error) {
+	var b bytes.Buffer
+	if err := dashboardTemplate.ExecuteTemplate(&b, "row", newHTMLRow(a, now)); err != nil {
+		return nil, err
+	}
+	return &liveRow{ID: a.CourseWork.Id, CourseID: a.Course.Id, CourseName: a.Course.Name, HTML: b.String()}, nil
+}
+
+// handleLive は WebSocket で課題の変更をダッシュボードに送ります。
+// 別のサイトのページから接続されないよう、Origin がこのサーバーの場合だけ受け付けます。
+func (s *server) handleLive(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.account(w, r)
+	if !ok {
+		return
+	}
+	ws := websocket.Server{
+		Handshake: func(c *websocket.Config, r *http.Request) error {
+			origin, err := url.Parse(r.Header.Get("Origin"))
+			if err != nil || origin.Host != r.Host {
+				return websocket.ErrBadWebSocketOrigin
+			}
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			defer conn.Close()
+			updates, unsubscribe := a.subscribe()
+			defer unsubscribe()
+			closed := make(chan struct{})
+			go func() {
+				// クライアントからのメッセージは使わず、切断の検出だけに使う
+				io.Copy(io.Discard, conn)
+				close(closed)
+			}()
+			for {
+				select {
+				case <-closed:
+					return
+				case <-s.ctx.Done():
+					return
+				case c := <-updates:
+					if err := websocket.JSON.Send(conn, newLiveUpdate(c)); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+	ws.ServeHTTP(w, r)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"golang.org/x/oauth2"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// errNotLoggedIn はサーバーにまだGoogleアカウントでログインしていないことを表します。
+var errNotLoggedIn = errors.New("ログインしていません。サーバーの /login でGoogleアカウントを認証してください")
+
+// serverTokenStore はサーバーが使うOAuthトークンです。/oauth2/callback で取得したトークンに差し替えられます。
+// トークンが更新されたときは save で保存し直します。
+type serverTokenStore struct {
+	config *oauth2.Config
+	save   func(*oauth2.Token) error
+
+	mu     sync.Mutex
+	source oauth2.TokenSource
+	last   string
+}
+
+// newServerTokenStore はトークン tok (まだなければ nil) を使うトークンストアを作ります。
+func newServerTokenStore(config *oauth2.Config, tok *oauth2.Token, save func(*oauth2.Token) error) *serverTokenStore {
+	s := &serverTokenStore{config: config, save: save}
+	if tok != nil {
+		s.source = config.TokenSource(context.Background(), tok)
+		s.last = tok.AccessToken
+	}
+	return s
+}
+
+// Token は oauth2.TokenSource を実装します。
+func (s *serverTokenStore) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.source == nil {
+		return nil, errNotLoggedIn
+	}
+	tok, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok.AccessToken != s.last {
+		s.last = tok.AccessToken
+		if err := s.save(tok); err != nil {
+			log.Printf("更新したトークンを保存できませんでした: %v", err)
+		}
+	}
+	return tok, nil
+}
+
+// set はログインで取得したトークンに差し替えて保存します。
+func (s *serverTokenStore) set(tok *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.source = s.config.TokenSource(context.Background(), tok)
+	s.last = tok.AccessToken
+	return s.save(tok)
+}
+
+// loggedIn はトークンがあるかどうかを返します。
+func (s *serverTokenStore) loggedIn() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.source != nil
+}
+
+// oauthStateCookie はログインの開始と /oauth2/callback の対応を確かめるためのクッキーです。
+const oauthStateCookie = "classroom_api_oauth_state"
+
+// oauthStateTTL は /login で発行した state が使える期間です。
+const oauthStateTTL = 10 * time.Minute
+
+// oauthStateStore は /login で発行した state です。/oauth2/callback はここにある state を1回だけ受け付けます。
+// クッキーとクエリはどちらもクライアントが決められるため、発行していない state の認証コードでトークンが差し替えられないようにします。
+type oauthStateStore struct {
+	mu     sync.Mutex
+	issued map[string]time.Time
+}
+
+// issue は新しい state を発行します。
+func (st *oauthStateStore) issue(now time.Time) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(b)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.issued == nil {
+		st.issued = map[string]time.Time{}
+	}
+	for s, t := range st.issued {
+		if now.Sub(t) > oauthStateTTL {
+			delete(st.issued, s)
+		}
+	}
+	st.issued[state] = now
+	return state, nil
+}
+
+// consume は state が発行したもので期限内であれば削除して true を返します。
+func (st *oauthStateStore) consume(state string, now time.Time) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	t, ok := st.issued[state]
+	if !ok {
+		return false
+	}
+	delete(st.issued, state)
+	return now.Sub(t) <= oauthStateTTL
+}
+
+// redirectURL はリクエストを受けたホストの /oauth2/callback のURLです。
+// Google Cloud Console の承認済みのリダイレクトURIにこのURLを登録してください。
+func redirectURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/oauth2/callback"
+}
+
+// handleLogin はGoogleの認証画面にリダイレクトします。
+func (s *server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if s.oauth == nil {
+		http.Error(w, "オフラインモードではログインできません", http.StatusServiceUnavailable)
+		return
+	}
+	state, err := s.oauthStates.issue(time.Now())
+	if err != nil {
+		http.Error(w, "ログインを開始できませんでした", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/oauth2/callback",
+		MaxAge:   int(oauthStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	config := *s.oauth
+	config.RedirectURL = redirectURL(r)
+	http.Redirect(w, r, config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce), http.StatusFound)
+}
+
+// handleOAuthCallback は認証コードをトークンに交換して保存し、ダッシュボードに戻ります。
+func (s *server) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	if s.oauth == nil {
+		http.Error(w, "オフラインモードではログインできません", http.StatusServiceUnavailable)
+		return
+	}
+	q := r.URL.Query()
+	c, err := r.Cookie(oauthStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(c.Value), []byte(q.Get("state"))) != 1 || !s.oauthStates.consume(q.Get("state"), time.Now()) {
+		http.Error(w, "ログインの状態が一致しません。/login からやり直してください", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/oauth2/callback", MaxAge: -1})
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "認証が拒否されました: "+e, http.StatusForbidden)
+		return
+	}
+	config := *s.oauth
+	config.RedirectURL = redirectURL(r)
+	tok, err := config.Exchange(r.Context(), q.Get("code"))
+	if err != nil {
+		log.Printf("トークンを取得できませんでした: %v", err)
+		http.Error(w, "トークンを取得できませんでした", http.StatusBadGateway)
+		return
+	}
+	if s.users == nil {
+		if err := s.single.tokens.set(tok); err != nil {
+			log.Printf("トークンを保存できませんでした: %v", err)
+		}
+		s.single.reset()
+		log.Printf("ログインしました")
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	u, err := idTokenUser(tok)
+	if err != nil {
+		log.Printf("アカウントを確認できませんでした: %v", err)
+		http.Error(w, "Googleアカウントを確認できませんでした", http.StatusBadGateway)
+		return
+	}
+	session, err := s.users.login(u, time.Now())
+	if err != nil {
+		log.Print(err)
+		http.Error(w, "ログインを保存できませんでした", http.StatusInternalServerError)
+		return
+	}
+	a := s.userAccount(u)
+	if err := a.tokens.set(tok); err != nil {
+		log.Printf("トークンを保存できませんでした: %v", err)
+	}
+	a.reset()
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session,
+		Path:     "/",
+		MaxAge:   int(sessionTTL / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	log.Printf("%s がログインしました", u.Email)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// handleLogout は複数ユーザーモードのセッションを終了します。保存したトークンは残ります。
+func (s *server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		if err := s.users.logout(c.Value); err != nil {
+			log.Printf("セッションを削除できませんでした: %v", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/", http.StatusFound)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOAuthStateStore(t *testing.T) {
+	var st oauthStateStore
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	state, err := st.issue(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.consume("forged", now) {
+		t.Error("発行していない state を受け付けました")
+	}
+	if !st.consume(state, now.Add(time.Minute)) {
+		t.Fatal("発行した state を受け付けませんでした")
+	}
+	if st.consume(state, now.Add(time.Minute)) {
+		t.Error("同じ state を2回受け付けました")
+	}
+	old, err := st.issue(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.consume(old, now.Add(oauthStateTTL+time.Second)) {
+		t.Error("期限切れの state を受け付けました")
+	}
+}
+
+func TestOAuthCallbackRejectsForgedState(t *testing.T) {
+	s := &server{cfg: &Config{}, oauth: &oauth2.Config{}}
+	r := httptest.NewRequest("GET", "/oauth2/callback?state=forged&code=attacker", nil)
+	// クッキーとクエリの state を揃えても、発行していなければ受け付けない
+	r.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: "forged"})
+	w := httptest.NewRecorder()
+	s.handleOAuthCallback(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
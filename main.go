@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+const usageText = `使い方: classroom-api [-config ファイル] <コマンド> [オプション]
+
+コマンド:
+  list        未提出の課題を一覧表示します (既定)
+  watch       未提出の課題を一定間隔で再取得して表示し続けます (list -watch と同じ)
+  remind      次の授業を基準にしたリマインダーを通知します (-follow で授業後にまとめを通知)
+  e2e         サンドボックスのコースで課題の作成から提出までを通して確認します
+  health      最後の同期の状態 (プッシュ通知/ポーリング) を表示します
+  deliveries  通知を送った記録を一覧表示・消去します (deliveries reset で同じ通知を送り直せます)
+  doctor      資格情報・トークン・APIへの接続などを確認します
+  today       今日が締切の未提出の課題を表示します (list -due today と同じ)
+  tomorrow    明日が締切の未提出の課題を表示します (list -due tomorrow と同じ)
+  next        最も締切の近い未提出の課題を1行で表示します
+  search      課題のタイトルと説明をあいまい検索します
+  report      これから1週間と締切切れの課題をMarkdownのレポートにします
+  grades      成績をコースと成績カテゴリごとに集計して表示します
+  coursework  課題ごとの操作 (show, open, comments, share) を行います
+  meta        課題にスヌーズ・タグ・メモなどを付け、端末間で同期します
+  calendar    未提出の課題の締切をGoogleカレンダーに同期します
+  email       未提出の課題と新しい課題のまとめを毎日または毎週メールで送ります (-send-now ですぐに送信)
+  telegram    Telegramのボットとして /pending や /today のコマンドにキャッシュの課題で答えます
+  tasks       未提出の課題をGoogle ToDoリストに同期します
+  sync        コース・課題・提出物をローカルのキャッシュ (SQLite) に保存します
+  daemon      cron式の予定で sync と通知を続けて行います (外部のcronの代わりに常駐します)
+  changes     sync で記録した課題の締切・タイトル・説明の変更履歴を表示します
+  snapshot    取得したデータをJSONのスナップショットに書き出し・読み込みます
+  mirror      課題と資料の添付ファイルをWebDAV/SMBの共有にミラーします
+  export      課題をほかの形式で出力します (export -h で形式の一覧)
+  serve       未提出の課題をJSONで返すローカルのAPIサーバーを起動します
+  keys        ローカルのAPIサーバーのAPIキーを発行・一覧表示・無効化します
+  schema      JSONで出力する形式のJSON Schemaを出力します
+`
+
+func usage() {
+	fmt.Fprint(flag.CommandLine.Output(), usageText)
+	fmt.Fprintln(flag.CommandLine.Output(), "\nグローバルオプション:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	setupJournalLogging()
+	configPath := flag.String("config", "config.json", "設定ファイルのパス")
+	readOnly := flag.Bool("read-only", false, "Classroomのデータを変更するコマンドとAPIをすべて無効にします")
+	offline := flag.Bool("offline", false, "APIを呼ばず、sync で保存したキャッシュから課題を読み取ります")
+	canary := flag.String("canary", "", "指定した設定ファイルで状態を書き込まず通知も送らずに同期し、現在の設定との違いを表示します")
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, args := "list", flag.Args()
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		if (cmd == "list" || cmd == "today" || cmd == "tomorrow") && listQuiet(args) {
+			log.Print(err)
+			os.Exit(quietErrorExit)
+		}
+		log.Fatal(err)
+	}
+	if *readOnly {
+		cfg.ReadOnly = true
+	}
+	if *offline {
+		cfg.Offline = true
+	}
+
+	ctx := context.Background()
+	if *canary != "" {
+		runCanary(ctx, cfg, *canary)
+		return
+	}
+	checkReadOnly(cfg, cmd)
+	switch cmd {
+	case "list":
+		runList(ctx, cfg, args)
+	case "watch":
+		runList(ctx, cfg, append([]string{"-watch"}, args...))
+	case "remind":
+		runRemind(ctx, cfg, args)
+	case "deliveries":
+		runDeliveries(ctx, cfg, args)
+	case "doctor":
+		runDoctor(ctx, cfg, args)
+	case "e2e":
+		runE2E(ctx, cfg, args)
+	case "schema":
+		runSchema(ctx, cfg, args)
+	case "health":
+		runHealth(ctx, cfg, args)
+	case "today", "tomorrow":
+		runList(ctx, cfg, append([]string{"-due", cmd}, args...))
+	case "next":
+		runNext(ctx, cfg, args)
+	case "search":
+		runSearch(ctx, cfg, args)
+	case "report":
+		runReport(ctx, cfg, args)
+	case "grades":
+		runGrades(ctx, cfg, args)
+	case "meta":
+		runMeta(ctx, cfg, args)
+	case "coursework":
+		runCoursework(ctx, cfg, args)
+	case "serve":
+		runServe(ctx, cfg, args)
+	case "keys":
+		runKeys(ctx, cfg, args)
+	case "mirror":
+		runMirror(ctx, cfg, args)
+	case "calendar":
+		runCalendar(ctx, cfg, args)
+	case "email":
+		runEmail(ctx, cfg, args)
+	case "telegram":
+		runTelegram(ctx, cfg, args)
+	case "tasks":
+		runTasks(ctx, cfg, args)
+	case "sync":
+		runSync(ctx, cfg, args)
+	case "daemon":
+		runDaemon(ctx, cfg, args)
+	case "changes":
+		runChanges(ctx, cfg, args)
+	case "snapshot":
+		runSnapshot(ctx, cfg, args)
+	case "export":
+		runExport(ctx, cfg, args)
+	default:
+		fmt.Fprintf(os.Stderr, "不明なコマンドです: %s\n\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+	if cfg.Client.Log {
+		for _, line := range defaultClientMetrics.summary() {
+			log.Print(line)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MessageTemplate は通知のタイトルと本文のテンプレート (text/template) です。省略した方は既定の文面を使います。
+type MessageTemplate struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+// messageData は通知のテンプレートに渡すデータです。課題は list -template と同じ形式です。
+type messageData struct {
+	// Type はできごとの種類、Title と Body は既定の文面です。
+	Type  string
+	Title string
+	Body  string
+	Time  time.Time
+	// CourseWork は関係する課題、Previous は変更前の課題 (due_changed と updated の場合) です。なければ nil です。
+	CourseWork *TemplateData
+	Previous   *TemplateData
+	// Items はまとめやリマインダーに含まれる課題です。
+	Items []*TemplateData
+}
+
+// messageTemplates は英語の文面です。notify.locale が "en" の場合に、notify.templates で指定しなかったできごとに使います。
+var messageTemplates = map[string]map[string]MessageTemplate{
+	"en": {
+		"created": {
+			Title: `New assignment: {{.CourseWork.Title}}`,
+			Body:  "{{.CourseWork.Course.Name}}\nDue: {{template \"due\" .CourseWork}}\n{{.CourseWork.Link}}",
+		},
+		"due_changed": {
+			Title: `Due date changed: {{.CourseWork.Title}}`,
+			Body:  "{{.CourseWork.Course.Name}}\nDue: {{template \"due\" .Previous}} → {{template \"due\" .CourseWork}}\n{{.CourseWork.Link}}",
+		},
+		"updated": {
+			Title: `Assignment updated: {{.CourseWork.Title}}`,
+			Body:  "{{.CourseWork.Course.Name}}\nDue: {{template \"due\" .CourseWork}}\n{{.CourseWork.Link}}",
+		},
+		"due_soon": {
+			Title: `Due soon: {{.CourseWork.Title}}`,
+			Body:  "{{.CourseWork.Course.Name}}\nDue: {{template \"due\" .CourseWork}}\n{{.CourseWork.Link}}",
+		},
+		"daily_digest": {
+			Title: `Pending assignments for {{.Time.Format "Jan 2"}} ({{len .Items}})`,
+			Body:  "{{range .Items}}- {{.Course.Name}}: {{.Title}} (due {{template \"due\" .}})\n{{else}}No pending assignments.{{end}}",
+		},
+		"reminder": {
+			Title: `You have pending assignments`,
+			Body:  "{{range .Items}}- {{.Course.Name}}: {{.Title}} (due {{template \"due\" .}})\n{{end}}",
+		},
+		"digest": {
+			Title: `Class ended ({{len .Items}} pending)`,
+			Body:  "{{range .Items}}- {{.Course.Name}}: {{.Title}} (due {{template \"due\" .}})\n{{end}}",
+		},
+		"quiet_digest": {
+			Title: `Notifications during quiet hours ({{len .Items}} assignments)`,
+			Body:  "{{range .Items}}- {{.Course.Name}}: {{.Title}} (due {{template \"due\" .}})\n{{else}}{{.Body}}{{end}}",
+		},
+		"test": {
+			Title: `classroom-api doctor`,
+			Body:  `This is a test notification.`,
+		},
+	},
+}
+
+// messageHelpers はすべての通知のテンプレートで使える補助のテンプレートです。
+// {{template "due" .CourseWork}} は締切を「Jan 2 15:04」の形式で、締切や課題がなければ「none」を書きます。
+const messageHelpers = `{{define "due"}}{{if and . .HasDue}}{{.Due.Format "Jan 2 15:04"}}{{else}}none{{end}}{{end}}`
+
+// messageTemplateSet は解析した通知のテンプレートです。キーは "created" のようなできごとの種類、
+// または "slack.created" のような送信先の名前とできごとの種類です。
+type messageTemplateSet map[string]*parsedMessageTemplate
+
+type parsedMessageTemplate struct {
+	title, body *template.Template
+}
+
+// parseMessageTemplates は notify.locale と notify.templates から通知のテンプレートを作ります。
+func parseMessageTemplates(nc *NotifyConfig) (messageTemplateSet, error) {
+	texts := map[string]MessageTemplate{}
+	if nc.Locale != "" && nc.Locale != "ja" {
+		builtin, ok := messageTemplates[nc.Locale]
+		if !ok {
+			return nil, fmt.Errorf("notify.locale は ja または en です: %s", nc.Locale)
+		}
+		for k, v := range builtin {
+			texts[k] = v
+		}
+	}
+	for k, v := range nc.Templates {
+		// 言語の文面のうち一部だけを置き換えられるよう、省略した方は残す
+		t := texts[k]
+		if v.Title != "" {
+			t.Title = v.Title
+		}
+		if v.Body != "" {
+			t.Body = v.Body
+		}
+		texts[k] = t
+	}
+	set := messageTemplateSet{}
+	for k, v := range texts {
+		p := &parsedMessageTemplate{}
+		var err error
+		if v.Title != "" {
+			if p.title, err = parseMessageTemplate(k+".title", v.Title); err != nil {
+				return nil, fmt.Errorf("notify.templates.%s.title: %w", k, err)
+			}
+		}
+		if v.Body != "" {
+			if p.body, err = parseMessageTemplate(k+".body", v.Body); err != nil {
+				return nil, fmt.Errorf("notify.templates.%s.body: %w", k, err)
+			}
+		}
+		set[k] = p
+	}
+	return set, nil
+}
+
+func parseMessageTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(messageHelpers)
+	if err != nil {
+		return nil, err
+	}
+	return t.Parse(text)
+}
+
+// lookup は送信先とできごとの種類に対応するテンプレートを返します。
+// 送信先ごとのテンプレートを優先し、そちらで省略したタイトルか本文は種類ごとのテンプレートを使います。
+func (s messageTemplateSet) lookup(sink, typ string) *parsedMessageTemplate {
+	t, general := s[sink+"."+typ], s[typ]
+	switch {
+	case t == nil:
+		return general
+	case general == nil:
+		return t
+	}
+	merged := *t
+	if merged.title == nil {
+		merged.title = general.title
+	}
+	if merged.body == nil {
+		merged.body = general.body
+	}
+	return &merged
+}
+
+// messageNotifier はテンプレートで作ったタイトルと本文で通知を送る送信先です。
+type messageNotifier struct {
+	Notifier
+	templates messageTemplateSet
+}
+
+func (n *messageNotifier) Notify(ctx context.Context, ev *Event) error {
+	t := n.templates.lookup(n.Name(), ev.Type)
+	if t == nil {
+		return n.Notifier.Notify(ctx, ev)
+	}
+	// 同じできごとをほかの送信先にも送るため、書き換えるのは写しにする
+	custom := *ev
+	data := newMessageData(ev)
+	var err error
+	if t.title != nil {
+		custom.Title, err = executeMessageTemplate(t.title, data)
+	}
+	if err == nil && t.body != nil {
+		custom.Body, err = executeMessageTemplate(t.body, data)
+	}
+	if err != nil {
+		// テンプレートの誤りで通知が届かないことのないよう、既定の文面で送る
+		log.Printf("%s への通知のテンプレートを実行できませんでした: %v", n.Name(), err)
+		return n.Notifier.Notify(ctx, ev)
+	}
+	custom.Templated = true
+	return n.Notifier.Notify(ctx, &custom)
+}
+
+func executeMessageTemplate(t *template.Template, data *messageData) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func newMessageData(ev *Event) *messageData {
+	d := &messageData{Type: ev.Type, Title: ev.Title, Body: ev.Body, Time: ev.Time}
+	if ev.Assignment != nil {
+		d.CourseWork = newTemplateData(ev.Assignment, ev.Time)
+	}
+	if ev.Previous != nil {
+		d.Previous = newTemplateData(ev.Previous, ev.Time)
+	}
+	for _, a := range ev.Items {
+		d.Items = append(d.Items, newTemplateData(a, ev.Time))
+	}
+	return d
+}
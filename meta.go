@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// metaFile は課題に付けた手元のメタデータ (スヌーズ、タグ、メモ、着手済み) を保存するファイルです。
+const metaFile = "meta.json"
+
+// metaDriveName はメタデータを端末間で同期するためにDriveのアプリ専用フォルダに置くファイルの名前です。
+const metaDriveName = "classroom-api-meta.json"
+
+// metaFields はメタデータの項目とその説明です。
+var metaFields = map[string]string{
+	"snooze":  "この日時まで通知を止めます (例: 2026-10-20 または 2026-10-20T18:00)",
+	"tags":    "カンマ区切りのタグ",
+	"note":    "メモ",
+	"started": "着手済みかどうか (true/false)",
+}
+
+// MetaConfig は課題のメタデータの設定です。
+type MetaConfig struct {
+	// DriveSync が true の場合は watch のたびにDriveのアプリ専用フォルダと同期します。
+	DriveSync bool `json:"driveSync,omitempty"`
+}
+
+// metaEntry はメタデータの1項目です。削除した項目も同期のために Deleted として残します。
+type metaEntry struct {
+	Value   string    `json:"value,omitempty"`
+	Updated time.Time `json:"updated"`
+	Deleted bool      `json:"deleted,omitempty"`
+}
+
+// metaStore は「課題のキー#項目」ごとのメタデータです。
+type metaStore struct {
+	Entries map[string]*metaEntry `json:"entries"`
+}
+
+func metaKey(a *Assignment, field string) string {
+	return assignmentKey(a) + "#" + field
+}
+
+func loadMeta(cfg *Config) (*metaStore, error) {
+	m := &metaStore{}
+	if err := readJSONFile(cfg.dataPath(metaFile), m); err != nil {
+		return nil, fmt.Errorf("メタデータを読み込めませんでした: %w", err)
+	}
+	if m.Entries == nil {
+		m.Entries = map[string]*metaEntry{}
+	}
+	return m, nil
+}
+
+func (m *metaStore) save(cfg *Config) error {
+	return writeJSONFile(cfg.dataPath(metaFile), m)
+}
+
+// get は項目の値を返します。
+func (m *metaStore) get(a *Assignment, field string) (string, bool) {
+	e, ok := m.Entries[metaKey(a, field)]
+	if !ok || e.Deleted {
+		return "", false
+	}
+	return e.Value, true
+}
+
+// set は項目の値を設定します。value が空の場合は削除します。
+func (m *metaStore) set(a *Assignment, field, value string, now time.Time) {
+	m.Entries[metaKey(a, field)] = &metaEntry{Value: value, Updated: now, Deleted: value == ""}
+}
+
+// merge はほかの端末のメタデータを取り込みます。項目ごとに更新日時の新しいほうを残します (last-write-wins)。
+// 取り込みで変わった項目があれば true を返します。
+func (m *metaStore) merge(other *metaStore) bool {
+	changed := false
+	for k, e := range other.Entries {
+		if cur, ok := m.Entries[k]; !ok || e.Updated.After(cur.Updated) {
+			m.Entries[k] = e
+			changed = true
+		}
+	}
+	return changed
+}
+
+// syncMetaDrive は手元のメタデータとDriveのアプリ専用フォルダのメタデータを統合し、両方に保存します。
+func syncMetaDrive(ctx context.Context, cfg *Config, dsrv *drive.Service) error {
+	local, err := loadMeta(cfg)
+	if err != nil {
+		return err
+	}
+	r, err := dsrv.Files.List().Spaces("appDataFolder").
+		Q(fmt.Sprintf("name = '%s' and trashed = false", metaDriveName)).
+		Fields("files(id)").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("Driveのメタデータを探せませんでした: %w", err)
+	}
+	var fileId string
+	if len(r.Files) > 0 {
+		fileId = r.Files[0].Id
+		res, err := dsrv.Files.Get(fileId).Context(ctx).Download()
+		if err != nil {
+			return fmt.Errorf("Driveのメタデータを取得できませんでした: %w", err)
+		}
+		remote := &metaStore{}
+		err = json.NewDecoder(res.Body).Decode(remote)
+		res.Body.Close()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("Driveのメタデータを解析できませんでした: %w", err)
+		}
+		local.merge(remote)
+	}
+	if err := local.save(cfg); err != nil {
+		return fmt.Errorf("メタデータを保存できませんでした: %w", err)
+	}
+	b, err := json.Marshal(local)
+	if err != nil {
+		return err
+	}
+	if fileId == "" {
+		f := &drive.File{Name: metaDriveName, Parents: []string{"appDataFolder"}, MimeType: "application/json"}
+		_, err = dsrv.Files.Create(f).Media(bytes.NewReader(b)).Context(ctx).Do()
+	} else {
+		_, err = dsrv.Files.Update(fileId, &drive.File{}).Media(bytes.NewReader(b)).Context(ctx).Do()
+	}
+	if err != nil {
+		return fmt.Errorf("Driveにメタデータを保存できませんでした: %w", err)
+	}
+	return nil
+}
+
+const metaUsageText = `使い方: classroom-api meta <サブコマンド> [引数]
+
+サブコマンド:
+  show <課題>              課題のメタデータを表示します
+  set <課題> <項目> <値>    メタデータを設定します (値を "" にすると削除)
+  sync                     Driveのアプリ専用フォルダを通してほかの端末と同期します
+
+項目:
+`
+
+func runMeta(ctx context.Context, cfg *Config, args []string) {
+	if len(args) == 0 {
+		printMetaUsage()
+		os.Exit(2)
+	}
+	client := newHTTPClient(cfg)
+	switch args[0] {
+	case "sync":
+		dsrv, err := drive.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			log.Fatalf("Driveクライアントを作成できませんでした: %v", err)
+		}
+		if err := syncMetaDrive(ctx, cfg, dsrv); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "show", "set":
+	default:
+		fmt.Fprintf(os.Stderr, "不明なサブコマンドです: %s\n\n", args[0])
+		printMetaUsage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("meta "+args[0], flag.ExitOnError)
+	fs.Parse(args[1:])
+	srv := newClassroomService(ctx, client)
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if fs.NArg() == 0 {
+		log.Fatal("課題を指定してください")
+	}
+	a, err := findAssignment(items, fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	m, err := loadMeta(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if args[0] == "show" {
+		fmt.Printf("%s (%s)\n", a.CourseWork.Title, a.Slug)
+		fields := make([]string, 0, len(metaFields))
+		for f := range metaFields {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		for _, f := range fields {
+			if v, ok := m.get(a, f); ok {
+				fmt.Printf("  %s: %s\n", f, v)
+			}
+		}
+		return
+	}
+	if fs.NArg() != 3 {
+		log.Fatal("課題、項目、値を指定してください")
+	}
+	field, value := fs.Arg(1), strings.TrimSpace(fs.Arg(2))
+	if err := validateMeta(field, value); err != nil {
+		log.Fatal(err)
+	}
+	m.set(a, field, value, time.Now())
+	if err := m.save(cfg); err != nil {
+		log.Fatalf("メタデータを保存できませんでした: %v", err)
+	}
+}
+
+// validateMeta は項目と値を確認します。
+func validateMeta(field, value string) error {
+	if _, ok := metaFields[field]; !ok {
+		return fmt.Errorf("不明な項目です: %s", field)
+	}
+	if value == "" {
+		return nil
+	}
+	switch field {
+	case "snooze":
+		if _, err := parseSnooze(value); err != nil {
+			return err
+		}
+	case "started":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("started には true か false を指定してください")
+		}
+	}
+	return nil
+}
+
+// snoozed は課題が now の時点でスヌーズ中かどうかを返します。m が nil の場合は false です。
+func (m *metaStore) snoozed(a *Assignment, now time.Time) bool {
+	if m == nil || a == nil {
+		return false
+	}
+	v, ok := m.get(a, "snooze")
+	if !ok {
+		return false
+	}
+	t, err := parseSnooze(v)
+	return err == nil && now.Before(t)
+}
+
+// loadSnoozes は通知の前にスヌーズを確かめるためにメタデータを読み込みます。
+// 読み込めない場合はログに書いて nil を返し、スヌーズせずに通知します。
+func loadSnoozes(cfg *Config) *metaStore {
+	m, err := loadMeta(cfg)
+	if err != nil {
+		log.Print(err)
+		return nil
+	}
+	return m
+}
+
+// parseSnooze はスヌーズの期限を解析します。日付だけの場合はその日の始まりです。
+func parseSnooze(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("日時を解析できませんでした: %s", s)
+}
+
+func printMetaUsage() {
+	fmt.Fprint(os.Stderr, metaUsageText)
+	fields := make([]string, 0, len(metaFields))
+	for f := range metaFields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	for _, f := range fields {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", f, metaFields[f])
+	}
+}
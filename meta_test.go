@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"google.golang.org/api/classroom/v1"
+	"testing"
+	"time"
+)
+
+func TestSnoozedAssignmentNotifications(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir()}
+	cfg.Notify.DueSoon = Duration(24 * time.Hour)
+	course := &classroom.Course{Id: "c1", Name: "数学"}
+	work := &classroom.CourseWork{Id: "w1", CourseId: "c1", Title: "課題", WorkType: "ASSIGNMENT", DueDate: &classroom.Date{Year: 2026, Month: 10, Day: 20}}
+	a := &Assignment{Course: course, CourseWork: work}
+	m, err := loadMeta(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.set(a, "snooze", "2026-10-20T12:00", time.Now())
+	if err := m.save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	al, err := newAlerter(cfg, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// スヌーズ中は締切が近くてもリマインダーを送らず、新しい課題も通知しない
+	snoozed := time.Date(2026, 10, 20, 9, 0, 0, 0, time.Local)
+	rec := &recordingNotifier{}
+	al.deliver(ctx, []Notifier{rec}, []*Assignment{a}, snoozed)
+	if len(rec.events) != 0 {
+		t.Errorf("スヌーズ中の課題のリマインダーを送りました: %v", eventTitles(rec.events))
+	}
+	changes, _ := diffPending(map[string]*Assignment{}, []*Assignment{a}, snoozed)
+	notifyChanges(ctx, []Notifier{rec}, changes, loadSnoozes(cfg))
+	if len(rec.events) != 0 {
+		t.Errorf("スヌーズ中の課題の変更を通知しました: %v", eventTitles(rec.events))
+	}
+
+	// スヌーズが明けたら送っていないリマインダーを送る
+	woke := time.Date(2026, 10, 20, 13, 0, 0, 0, time.Local)
+	al.deliver(ctx, []Notifier{rec}, []*Assignment{a}, woke)
+	if len(rec.events) != 1 || rec.events[0].Type != "due_soon" {
+		t.Errorf("スヌーズが明けた後のできごと = %v, want due_soon 1件", eventTitles(rec.events))
+	}
+	changes, _ = diffPending(map[string]*Assignment{}, []*Assignment{a}, woke)
+	notifyChanges(ctx, []Notifier{rec}, changes, loadSnoozes(cfg))
+	if len(rec.events) != 2 {
+		t.Errorf("スヌーズが明けた後の課題の変更を通知しませんでした")
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultBuckets はヒストグラムの既定のバケットの上限 (秒) です。
+var defaultBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// counterVec はラベルごとのカウンターです。
+type counterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+}
+
+// inc はラベルの値が values のカウンターを1増やします。
+func (c *counterVec) inc(values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[strings.Join(values, "\x00")]++
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, key, ""), formatFloat(c.values[key]))
+	}
+}
+
+// histogram はヒストグラムの1系列です。counts[i] は buckets[i] 以下の観測数です。
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// histogramVec はラベルごとのヒストグラムです。
+type histogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	values     map[string]*histogram
+}
+
+func newHistogramVec(name, help string, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: defaultBuckets, values: map[string]*histogram{}}
+}
+
+// observe はラベルの値が values のヒストグラムに d を記録します。
+func (h *histogramVec) observe(d time.Duration, values ...string) {
+	v := d.Seconds()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := strings.Join(values, "\x00")
+	s, ok := h.values[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = s
+	}
+	for i, le := range h.buckets {
+		if v <= le {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.values) {
+		s := h.values[key]
+		for i, le := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, formatFloat(le)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, key, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, key, ""), s.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatLabels はラベルを {a="x",b="y"} の形式にします。le が空でなければ le ラベルを加えます。
+func formatLabels(names []string, key, le string) string {
+	var parts []string
+	if len(names) > 0 {
+		for i, v := range strings.Split(key, "\x00") {
+			parts = append(parts, fmt.Sprintf("%s=%q", names[i], v))
+		}
+	}
+	if le != "" {
+		parts = append(parts, fmt.Sprintf("le=%q", le))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	apiRequests = newCounterVec("classroom_api_requests_total",
+		"Google APIの呼び出し数 (メソッドと状態ごと)", "method", "status")
+	apiLatency = newHistogramVec("classroom_api_request_duration_seconds",
+		"Google APIの呼び出しの所要時間", "method")
+	courseFetchLatency = newHistogramVec("classroom_course_fetch_duration_seconds",
+		"コースごとの課題と提出物の取得の所要時間", "course")
+	refreshLatency = newHistogramVec("classroom_refresh_duration_seconds",
+		"サーバーのバックグラウンドでの取得の所要時間")
+	cacheLookups = newCounterVec("classroom_server_cache_lookups_total",
+		"サーバーの取得結果の参照数 (result は hit または miss)", "result")
+)
+
+// apiVersionSegment はパスの先頭の v1 のようなバージョンのうち、英字以外を含む部分です。
+var apiVersionSegment = regexp.MustCompile(`[^A-Za-z]`)
+
+// apiMethod はリクエストのURLから courses.courseWork.list のようなメソッド名を作ります。
+// IDをラベルに含めないことで、系列の数がコースや課題の数に比例して増えないようにします。
+func apiMethod(r *http.Request) string {
+	segs := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	// 先頭の v1 などのバージョンを除く
+	if len(segs) > 0 && len(segs[0]) > 1 && segs[0][0] == 'v' && apiVersionSegment.MatchString(segs[0]) {
+		segs = segs[1:]
+	}
+	var names []string
+	last := ""
+	for i, s := range segs {
+		// studentSubmissions/{id}:turnIn のようなカスタムメソッド
+		s, custom, _ := strings.Cut(s, ":")
+		if i%2 == 0 {
+			names = append(names, s)
+			last = "collection"
+		} else {
+			last = "item"
+		}
+		if custom != "" {
+			return strings.Join(names, ".") + "." + custom
+		}
+	}
+	if len(names) == 0 {
+		return r.Method
+	}
+	action := map[string]map[string]string{
+		"collection": {http.MethodGet: "list", http.MethodPost: "create"},
+		"item":       {http.MethodGet: "get", http.MethodPatch: "patch", http.MethodPut: "update", http.MethodDelete: "delete"},
+	}[last][r.Method]
+	if action == "" {
+		action = strings.ToLower(r.Method)
+	}
+	return strings.Join(names, ".") + "." + action
+}
+
+// handleMetrics はPrometheusのテキスト形式でメトリクスを返します。
+// 複数ユーザーモードではすべてのユーザーの集計になるため、セッションではなくユーザーに紐づかない count の権限のAPIキーを求め、
+// 未提出の課題の件数もコース名を含めずに合計だけを返します。
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	multi := s.users != nil
+	if multi {
+		if err := s.checkAPIKey(r, "count"); err != nil {
+			err.write(w)
+			return
+		}
+		if k := s.keys.lookup(requestAPIKey(r), time.Now()); k.User != "" {
+			http.Error(w, "ユーザーのAPIキーではすべてのユーザーのメトリクスを取得できません", http.StatusForbidden)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	apiRequests.write(w)
+	apiLatency.write(w)
+	if !multi {
+		// コースごとの所要時間のラベルはコース名のため、複数ユーザーモードでは返さない
+		courseFetchLatency.write(w)
+	}
+	refreshLatency.write(w)
+	cacheLookups.write(w)
+
+	// 未提出の課題の件数は、取得済みの結果からリクエストのたびに数える
+	pending := map[string]int{}
+	now := time.Now()
+	for _, a := range s.allAccounts() {
+		v, ok := a.cache.get(a.key)
+		if !ok {
+			continue
+		}
+		for _, c := range v.Courses {
+			pending[c.Name] += 0
+		}
+		for _, it := range pendingAssignments(v.Items, now) {
+			if !it.External() {
+				pending[it.Course.Name]++
+			}
+		}
+	}
+	fmt.Fprint(w, "# HELP classroom_pending_assignments 未提出の課題の件数 (外部ツールの課題を除く)\n# TYPE classroom_pending_assignments gauge\n")
+	if multi {
+		total := 0
+		for _, n := range pending {
+			total += n
+		}
+		fmt.Fprintf(w, "classroom_pending_assignments %d\n", total)
+		return
+	}
+	for _, course := range sortedKeys(pending) {
+		fmt.Fprintf(w, "classroom_pending_assignments{course=%q} %d\n", course, pending[course])
+	}
+}
+
+// allAccounts は取得を行っているすべてのアカウントを返します。
+func (s *server) allAccounts() []*account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var accounts []*account
+	if s.single != nil {
+		accounts = append(accounts, s.single)
+	}
+	for _, a := range s.accounts {
+		accounts = append(accounts, a)
+	}
+	return accounts
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"google.golang.org/api/classroom/v1"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsMultiUser(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir()}
+	cfg.Server.APIKeys = []ConfigAPIKey{{Name: "prometheus", Key: "count-0123456789abcdef", Scopes: []string{"count"}}}
+	keys, err := loadAPIKeys(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := loadUserStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userKey, _, err := keys.mint("mine", "u1", []string{"count"}, 0, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := newMemoryCache()
+	course := &classroom.Course{Id: "c1", Name: "秘密のコース"}
+	cache.put("u1", &cachedAccount{Courses: []*classroom.Course{course}, Items: []*Assignment{
+		{Course: course, CourseWork: &classroom.CourseWork{Id: "w1", Title: "課題", WorkType: "ASSIGNMENT"}},
+	}})
+	s := &server{cfg: cfg, keys: keys, users: users, accounts: map[string]*account{"u1": {key: "u1", cache: cache}}}
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		// ユーザーに紐づいたキーではほかのユーザーの件数を見られない
+		{userKey, http.StatusForbidden},
+		{"count-0123456789abcdef", http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/metrics", nil)
+		if tt.key != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.key)
+		}
+		w := httptest.NewRecorder()
+		s.handleMetrics(w, r)
+		if w.Code != tt.want {
+			t.Errorf("キー %q: status = %d, want %d", tt.key, w.Code, tt.want)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		body := w.Body.String()
+		if strings.Contains(body, "秘密のコース") {
+			t.Errorf("複数ユーザーモードのメトリクスにコース名が含まれています")
+		}
+		if !strings.Contains(body, "classroom_pending_assignments 1\n") {
+			t.Errorf("未提出の課題の合計がありません:\n%s", body)
+		}
+	}
+}
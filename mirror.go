@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MirrorConfig は配布資料のミラー先の設定です。daemon コマンドは同期するたびにミラーも同期します。
+type MirrorConfig struct {
+	// Target はミラー先です。http(s):// はWebDAV、smb:// はマウント済みの共有、
+	// それ以外はローカルディレクトリとして扱います。
+	Target string `json:"target"`
+	// MountPoint は smb:// のミラー先をマウントしたローカルのパスです。
+	MountPoint string `json:"mountPoint,omitempty"`
+	// Username と Password はWebDAVのBasic認証に使います。
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// mirrorTarget はミラー先のファイルシステムです。パスは "/" 区切りの相対パスです。
+type mirrorTarget interface {
+	MakeDir(ctx context.Context, p string) error
+	Put(ctx context.Context, p string, data []byte) error
+	Delete(ctx context.Context, p string) error
+}
+
+// mirrorFile はミラーする1つのファイルです。
+type mirrorFile struct {
+	path    string
+	version string
+	fetch   func(ctx context.Context) ([]byte, error)
+}
+
+// mirrorStateFile はミラー済みのファイルとそのバージョンを記録するファイルです。
+const mirrorStateFile = "mirror.json"
+
+func runMirror(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("mirror", flag.ExitOnError)
+	interval := fs.Duration("interval", 0, "指定した間隔で同期を繰り返します (例: 30m)")
+	fs.Parse(args)
+
+	if cfg.Mirror == nil || cfg.Mirror.Target == "" {
+		log.Fatal("設定ファイルに mirror.target がありません")
+	}
+	target, err := newMirrorTarget(cfg.Mirror)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := newHTTPClient(cfg)
+	srv := newClassroomService(ctx, client)
+	dsrv, err := drive.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		log.Fatalf("Driveクライアントを作成できませんでした: %v", err)
+	}
+
+	for {
+		if err := syncMirror(ctx, cfg, srv, dsrv, target); err != nil {
+			log.Printf("ミラーの同期に失敗しました: %v", err)
+		}
+		if *interval <= 0 {
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// newMirrorTarget は設定からミラー先を作成します。
+func newMirrorTarget(mc *MirrorConfig) (mirrorTarget, error) {
+	u, err := url.Parse(mc.Target)
+	if err != nil {
+		return nil, fmt.Errorf("ミラー先を解析できませんでした: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &webdavTarget{base: u, username: mc.Username, password: mc.Password, client: http.DefaultClient}, nil
+	case "smb":
+		// SMBは直接話さず、OSでマウントした共有に書き込む
+		if mc.MountPoint == "" {
+			return nil, fmt.Errorf("smb:// のミラー先には mirror.mountPoint の指定が必要です")
+		}
+		return dirTarget(mc.MountPoint), nil
+	default:
+		return dirTarget(mc.Target), nil
+	}
+}
+
+// syncMirror はミラー先を現在の課題と資料の添付ファイルに合わせます。
+// 変更のないファイルは転送せず、Classroom から消えたファイルはミラー先からも削除します。
+func syncMirror(ctx context.Context, cfg *Config, srv *classroom.Service, dsrv *drive.Service, target mirrorTarget) error {
+	state := map[string]string{}
+	if err := readJSONFile(cfg.dataPath(mirrorStateFile), &state); err != nil {
+		return fmt.Errorf("ミラーの状態を読み取れませんでした: %w", err)
+	}
+
+	files, incomplete, err := collectMirrorFiles(ctx, cfg, srv, dsrv)
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	dirs := map[string]bool{}
+	for _, f := range files {
+		seen[f.path] = true
+		if state[f.path] == f.version {
+			continue
+		}
+		data, err := f.fetch(ctx)
+		if err != nil {
+			log.Printf("%s をダウンロードできませんでした: %v", f.path, err)
+			continue
+		}
+		dir := path.Dir(f.path)
+		if !dirs[dir] {
+			if err := target.MakeDir(ctx, dir); err != nil {
+				return fmt.Errorf("フォルダ %s を作成できませんでした: %w", dir, err)
+			}
+			dirs[dir] = true
+		}
+		if err := target.Put(ctx, f.path, data); err != nil {
+			return fmt.Errorf("%s を書き込めませんでした: %w", f.path, err)
+		}
+		fmt.Printf("更新: %s\n", f.path)
+		state[f.path] = f.version
+	}
+	for p := range state {
+		if seen[p] || incomplete[path.Dir(p)] {
+			// 情報を取得できなかったファイルのあるフォルダでは、消えたかどうか分からないため削除しない
+			continue
+		}
+		if err := target.Delete(ctx, p); err != nil {
+			return fmt.Errorf("%s を削除できませんでした: %w", p, err)
+		}
+		fmt.Printf("削除: %s\n", p)
+		delete(state, p)
+	}
+	return writeJSONFile(cfg.dataPath(mirrorStateFile), state)
+}
+
+// collectMirrorFiles はミラーするファイルを「コース/トピック/課題」の構成で列挙します。
+// 名前が同じになるファイルには " (2)" のような番号を付けます。
+// incomplete はDriveのファイルの情報を取得できず、列挙できなかったファイルがあるフォルダです。
+func collectMirrorFiles(ctx context.Context, cfg *Config, srv *classroom.Service, dsrv *drive.Service) (files []mirrorFile, incomplete map[string]bool, err error) {
+	courses, err := listCourses(ctx, srv, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	incomplete = map[string]bool{}
+	used := map[string]bool{}
+	for _, course := range courses {
+		topics, err := listTopics(ctx, srv, course.Id)
+		if err != nil {
+			return nil, nil, err
+		}
+		add := func(topicId, title string, materials []*classroom.Material) {
+			topic, ok := topics[topicId]
+			if !ok {
+				topic = "トピックなし"
+			}
+			dir := path.Join(safeName(course.Name), safeName(topic), safeName(title))
+			for _, m := range materials {
+				f, ok, err := mirrorFileFromMaterial(dsrv, dir, m)
+				if err != nil {
+					log.Print(err)
+					incomplete[dir] = true
+					continue
+				}
+				if ok {
+					f.path = uniqueMirrorPath(used, f.path)
+					files = append(files, f)
+				}
+			}
+		}
+		err = srv.Courses.CourseWork.List(course.Id).Pages(ctx, func(r *classroom.ListCourseWorkResponse) error {
+			for _, c := range r.CourseWork {
+				add(c.TopicId, c.Title, c.Materials)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("課題を取得できませんでした (%s): %w", course.Name, err)
+		}
+		err = srv.Courses.CourseWorkMaterials.List(course.Id).Pages(ctx, func(r *classroom.ListCourseWorkMaterialResponse) error {
+			for _, m := range r.CourseWorkMaterial {
+				add(m.TopicId, m.Title, m.Materials)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("資料を取得できませんでした (%s): %w", course.Name, err)
+		}
+	}
+	return files, incomplete, nil
+}
+
+// uniqueMirrorPath は used にない p を返します。すでに使われている場合は拡張子の前に " (2)" のような番号を付けます。
+func uniqueMirrorPath(used map[string]bool, p string) string {
+	ext := path.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	for i := 2; used[p]; i++ {
+		p = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	used[p] = true
+	return p
+}
+
+// mirrorFileFromMaterial は添付ファイル1つをミラーするファイルに変換します。
+// Driveのファイルはダウンロードし、リンク・YouTube・フォームはインターネットショートカットとして保存します。
+// ミラーしない添付ファイルの場合は false を、Driveのファイルの情報を取得できなかった場合はエラーを返します。
+func mirrorFileFromMaterial(dsrv *drive.Service, dir string, m *classroom.Material) (mirrorFile, bool, error) {
+	switch {
+	case m.DriveFile != nil && m.DriveFile.DriveFile != nil:
+		df := m.DriveFile.DriveFile
+		f, err := dsrv.Files.Get(df.Id).Fields("id", "name", "mimeType", "modifiedTime").SupportsAllDrives(true).Do()
+		if err != nil {
+			return mirrorFile{}, false, fmt.Errorf("Driveのファイル %s を取得できませんでした: %w", df.Title, err)
+		}
+		name := f.Name
+		fetch := func(ctx context.Context) ([]byte, error) {
+			res, err := dsrv.Files.Get(f.Id).SupportsAllDrives(true).Context(ctx).Download()
+			if err != nil {
+				return nil, err
+			}
+			defer res.Body.Close()
+			return io.ReadAll(res.Body)
+		}
+		// Googleドキュメント形式のファイルはPDFに変換する
+		if strings.HasPrefix(f.MimeType, "application/vnd.google-apps.") {
+			name += ".pdf"
+			fetch = func(ctx context.Context) ([]byte, error) {
+				res, err := dsrv.Files.Export(f.Id, "application/pdf").Context(ctx).Download()
+				if err != nil {
+					return nil, err
+				}
+				defer res.Body.Close()
+				return io.ReadAll(res.Body)
+			}
+		}
+		return mirrorFile{path: path.Join(dir, safeName(name)), version: f.ModifiedTime, fetch: fetch}, true, nil
+	case m.Link != nil:
+		return shortcutFile(dir, m.Link.Title, m.Link.Url), true, nil
+	case m.YoutubeVideo != nil:
+		return shortcutFile(dir, m.YoutubeVideo.Title, m.YoutubeVideo.AlternateLink), true, nil
+	case m.Form != nil:
+		return shortcutFile(dir, m.Form.Title, m.Form.FormUrl), true, nil
+	}
+	return mirrorFile{}, false, nil
+}
+
+// shortcutFile はURLを開くインターネットショートカット (.url) を作ります。
+func shortcutFile(dir, title, u string) mirrorFile {
+	if title == "" {
+		title = u
+	}
+	data := []byte("[InternetShortcut]\r\nURL=" + u + "\r\n")
+	return mirrorFile{
+		path:    path.Join(dir, safeName(title)+".url"),
+		version: u,
+		fetch:   func(context.Context) ([]byte, error) { return data, nil },
+	}
+}
+
+// safeName はファイル名に使えない文字を置き換えます。
+func safeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(s)
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
+
+// dirTarget はローカル (またはマウント済み) のディレクトリへのミラー先です。
+type dirTarget string
+
+func (d dirTarget) MakeDir(_ context.Context, p string) error {
+	return os.MkdirAll(filepath.Join(string(d), filepath.FromSlash(p)), 0755)
+}
+
+func (d dirTarget) Put(_ context.Context, p string, data []byte) error {
+	name := filepath.Join(string(d), filepath.FromSlash(p))
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+func (d dirTarget) Delete(_ context.Context, p string) error {
+	err := os.Remove(filepath.Join(string(d), filepath.FromSlash(p)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// webdavTarget はWebDAVサーバーへのミラー先です。
+type webdavTarget struct {
+	base     *url.URL
+	username string
+	password string
+	client   *http.Client
+}
+
+func (t *webdavTarget) do(ctx context.Context, method, p string, body []byte, ok ...int) error {
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	rawURL := strings.TrimSuffix(t.base.String(), "/") + "/" + strings.Join(segs, "/")
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if t.username != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	for _, code := range ok {
+		if res.StatusCode == code {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %s: %s", method, rawURL, res.Status)
+}
+
+func (t *webdavTarget) MakeDir(ctx context.Context, p string) error {
+	// MKCOLは親フォルダが必要なので上の階層から順に作る
+	segs := strings.Split(p, "/")
+	for i := range segs {
+		// 201: 作成, 405: すでに存在する
+		if err := t.do(ctx, "MKCOL", strings.Join(segs[:i+1], "/"), nil, http.StatusCreated, http.StatusMethodNotAllowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *webdavTarget) Put(ctx context.Context, p string, data []byte) error {
+	return t.do(ctx, http.MethodPut, p, data, http.StatusOK, http.StatusCreated, http.StatusNoContent)
+}
+
+func (t *webdavTarget) Delete(ctx context.Context, p string) error {
+	return t.do(ctx, http.MethodDelete, p, nil, http.StatusOK, http.StatusNoContent, http.StatusNotFound)
+}
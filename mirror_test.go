@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestUniqueMirrorPath(t *testing.T) {
+	used := map[string]bool{}
+	tests := []struct {
+		p    string
+		want string
+	}{
+		{"数学/課題/資料.pdf", "数学/課題/資料.pdf"},
+		{"数学/課題/資料.pdf", "数学/課題/資料 (2).pdf"},
+		{"数学/課題/資料.pdf", "数学/課題/資料 (3).pdf"},
+		// 番号を付けた名前と同じ名前のファイルも重ならない
+		{"数学/課題/資料 (2).pdf", "数学/課題/資料 (2) (2).pdf"},
+		{"数学/課題/リンク", "数学/課題/リンク"},
+		{"数学/課題/リンク", "数学/課題/リンク (2)"},
+	}
+	for _, tt := range tests {
+		if got := uniqueMirrorPath(used, tt.p); got != tt.want {
+			t.Errorf("uniqueMirrorPath(%q) = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import "fmt"
+
+// shownStateFile は list -new-only で表示済みの課題を記録するファイルです。
+const shownStateFile = "shown.json"
+
+// shownState は表示済みの課題のIDと、表示したときの課題の更新日時の対応です。
+type shownState struct {
+	CourseWork map[string]string `json:"coursework"`
+}
+
+// newOnly は前回までに表示していない課題と、表示した後に変更された課題だけを返し、
+// 返した課題を表示済みとして記録します。
+func newOnly(cfg *Config, items []*Assignment) ([]*Assignment, error) {
+	path := cfg.dataPath(shownStateFile)
+	state := &shownState{}
+	if err := readJSONFile(path, state); err != nil {
+		return nil, fmt.Errorf("表示済みの課題の記録を読み込めませんでした: %w", err)
+	}
+	if state.CourseWork == nil {
+		state.CourseWork = map[string]string{}
+	}
+	var fresh []*Assignment
+	for _, a := range items {
+		c := a.CourseWork
+		if updated, ok := state.CourseWork[c.Id]; ok && updated == c.UpdateTime {
+			continue
+		}
+		state.CourseWork[c.Id] = c.UpdateTime
+		fresh = append(fresh, a)
+	}
+	if len(fresh) == 0 || cfg.dryRun {
+		return fresh, nil
+	}
+	if err := writeJSONFile(path, state); err != nil {
+		return nil, fmt.Errorf("表示済みの課題の記録を保存できませんでした: %w", err)
+	}
+	return fresh, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+func runNext(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("next", flag.ExitOnError)
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	now := time.Now()
+	for _, a := range filter.apply(pendingAssignments(items, now)) {
+		due, ok := a.EffectiveDue()
+		if !ok || due.Before(now) {
+			continue
+		}
+		fmt.Printf("%s (%s) %s\n", a.CourseWork.Title, a.Course.Name, formatRemaining(due.Sub(now)))
+		return
+	}
+	// 締切のある未提出の課題がない場合は何も出力せず、終了コード 1 で終わる
+	os.Exit(1)
+}
+
+// formatRemaining は締切までの残り時間を「あと2日3時間」のような形式で返します。
+func formatRemaining(d time.Duration) string {
+	if d < 0 {
+		return "締切超過 " + formatDuration(-d)
+	}
+	return "あと" + formatDuration(d)
+}
+
+func formatDuration(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0 && hours > 0:
+		return fmt.Sprintf("%d日%d時間", days, hours)
+	case days > 0:
+		return fmt.Sprintf("%d日", days)
+	case hours > 0:
+		return fmt.Sprintf("%d時間%d分", hours, minutes)
+	}
+	return fmt.Sprintf("%d分", minutes)
+}
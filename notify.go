@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Event は通知するできごとです。
+type Event struct {
+	// Type はできごとの種類です (reminder, digest, created, due_soon など)。
+	Type string
+	// Assignment は関係する課題です。複数の課題にまたがる場合は nil です。
+	Assignment *Assignment
+	// Previous は変更前の課題です (updated と due_changed の場合)。
+	Previous *Assignment
+	// Items はまとめやリマインダーのように複数の課題にまたがるできごとの課題です。
+	Items []*Assignment
+	// Key はできごとを識別するキーです。同じキーのできごとは同じ送信先に1回だけ送ります。
+	// 空の場合は送るたびに通知します。
+	Key   string
+	Title string
+	Body  string
+	Time  time.Time
+	// Templated は Title と Body を notify.templates で作ったかどうかです。
+	// true の場合、送信先は課題から独自の文面を作らずに Title と Body をそのまま使います。
+	Templated bool
+}
+
+// Notifier は通知の送信先です。
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, ev *Event) error
+}
+
+// NotifyConfig は通知の送信先の設定です。
+type NotifyConfig struct {
+	// Command は通知のたびに実行するコマンドです。本文は標準入力に渡され、
+	// 種類とタイトルは環境変数 CLASSROOM_EVENT と CLASSROOM_TITLE に設定されます。
+	Command string `json:"command,omitempty"`
+	// Stdout が true の場合は標準出力にも書き出します。
+	Stdout bool `json:"stdout,omitempty"`
+	// Slack はSlackへの通知の設定です。
+	Slack *SlackConfig `json:"slack,omitempty"`
+	// Teams はMicrosoft Teamsへの通知の設定です。
+	Teams *TeamsConfig `json:"teams,omitempty"`
+	// Line はLINE Messaging APIでの通知の設定です。
+	Line *LineConfig `json:"line,omitempty"`
+	// Telegram はTelegramのボットでの通知の設定です。telegram コマンドでボットのコマンドにも答えます。
+	Telegram *TelegramConfig `json:"telegram,omitempty"`
+	// Ntfy は ntfy でのスマートフォンへのプッシュ通知の設定です。
+	Ntfy *NtfyConfig `json:"ntfy,omitempty"`
+	// Pushover はPushoverでのプッシュ通知の設定です。
+	Pushover *PushoverConfig `json:"pushover,omitempty"`
+	// Desktop はOSのデスクトップ通知の設定です。watch と serve を実行しているコンピューターに表示します。
+	Desktop *DesktopConfig `json:"desktop,omitempty"`
+	// Webhooks は通知をJSONでPOSTする送信先です。
+	Webhooks []WebhookConfig `json:"webhooks,omitempty"`
+	// Email はメールで送る毎日または毎週の課題のまとめの設定です。email コマンドで送ります。
+	Email *EmailConfig `json:"email,omitempty"`
+	// Reminders は締切のどれだけ前に未提出の課題を通知するかです (例: ["48h", "24h", "2h"])。
+	// それぞれの時間について課題ごとに1回だけ通知し (due_soon)、送ったことは再起動しても覚えています。
+	// 締切が変わった場合は送り直します。コースごとの設定 (courses.<ID>.reminders) がある場合はそちらを使います。
+	// serve (server.notify が有効な場合) と watch で使います。
+	Reminders []Duration `json:"reminders,omitempty"`
+	// DueSoon が 0 でない場合は、締切までこの時間を切った未提出の課題を課題ごとに1回通知します (due_soon)。
+	// Reminders に1つの時間を加えるのと同じです。
+	DueSoon Duration `json:"dueSoon,omitempty"`
+	// DailyDigest を設定した場合は、毎日この時刻 ("7:30" の形式) を過ぎたら未提出の課題のまとめを通知します (daily_digest)。
+	DailyDigest string `json:"dailyDigest,omitempty"`
+	// Celebrate は今週締切の課題をすべて提出したときのお祝いの設定です。
+	Celebrate CelebrateConfig `json:"celebrate"`
+	// Locale は通知の文面の言語です ("ja" または "en")。省略した場合は日本語です。
+	Locale string `json:"locale,omitempty"`
+	// Templates はできごとの種類ごとの通知のタイトルと本文のテンプレートです。キーは "created" のような種類か、
+	// 特定の送信先だけに使う場合は "slack.created" のような送信先の名前と種類です。
+	// テンプレートには .Type、.Title と .Body (既定の文面)、.Time、.CourseWork と .Previous (list -template と同じ形式の課題)、
+	// .Items (まとめに含まれる課題) が渡されます。
+	Templates map[string]MessageTemplate `json:"templates,omitempty"`
+
+	// QuietHours は通知を送らない時間帯です (例: [{"start": "23:00", "end": "7:00"}])。静かな時間の通知はためておき、
+	// 終わった後に送信先ごとに1つのまとめ (quiet_digest) にして送ります。sinks で送信先ごとに設定できます。
+	QuietHours []QuietHoursConfig `json:"quietHours,omitempty"`
+
+	// templates は Locale と Templates を解析したものです (loadConfig で作ります)。
+	templates messageTemplateSet
+	// quietHours は QuietHours を解析したものです (loadConfig で作ります)。
+	quietHours []*quietRule
+}
+
+// newNotifiers は設定から通知の送信先を作ります。何も設定されていない場合は標準出力に書き出します。
+func newNotifiers(cfg *Config) []Notifier {
+	var ns []Notifier
+	nc := cfg.Notify
+	if nc.Command != "" {
+		ns = append(ns, commandNotifier(nc.Command))
+	}
+	if nc.Slack != nil {
+		ns = append(ns, newSlackNotifier(*nc.Slack))
+	}
+	if nc.Teams != nil {
+		ns = append(ns, newTeamsNotifier(*nc.Teams))
+	}
+	if nc.Line != nil {
+		ns = append(ns, newLineNotifier(*nc.Line))
+	}
+	if nc.Telegram != nil {
+		ns = append(ns, newTelegramNotifier(*nc.Telegram))
+	}
+	if nc.Ntfy != nil {
+		ns = append(ns, newNtfyNotifier(*nc.Ntfy))
+	}
+	if nc.Pushover != nil {
+		ns = append(ns, newPushoverNotifier(*nc.Pushover))
+	}
+	if nc.Desktop != nil {
+		ns = append(ns, newDesktopNotifier(*nc.Desktop))
+	}
+	for _, wc := range nc.Webhooks {
+		ns = append(ns, newWebhookNotifier(wc))
+	}
+	if nc.Stdout || len(ns) == 0 {
+		ns = append(ns, stdoutNotifier{})
+	}
+	store := newDeliveryStore(cfg)
+	for i, sink := range ns {
+		id := sink.Name()
+		if w, ok := sink.(*webhookNotifier); ok {
+			id += ":" + w.cfg.URL
+		}
+		n := sink
+		if len(nc.templates) > 0 {
+			n = &messageNotifier{Notifier: n, templates: nc.templates}
+		}
+		// 静かな時間にためた通知は送ったことにする
+		n = newQuietNotifier(cfg, n, id, sink)
+		ns[i] = &dedupNotifier{Notifier: n, id: id, store: store}
+	}
+	return ns
+}
+
+// notifyAll はすべての送信先に通知します。送信に失敗した送信先があってもほかの送信先には送ります。
+func notifyAll(ctx context.Context, ns []Notifier, ev *Event) error {
+	var errs []string
+	for _, n := range ns {
+		if err := n.Notify(ctx, ev); err != nil {
+			log.Printf("%s への通知に失敗しました: %v", n.Name(), err)
+			errs = append(errs, n.Name())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("通知に失敗しました: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+type stdoutNotifier struct{}
+
+func (stdoutNotifier) Name() string { return "stdout" }
+
+func (stdoutNotifier) Notify(_ context.Context, ev *Event) error {
+	_, err := fmt.Printf("■ %s\n%s\n\n", ev.Title, ev.Body)
+	return err
+}
+
+// commandNotifier は通知のたびにシェルコマンドを実行します。
+type commandNotifier string
+
+func (c commandNotifier) Name() string { return "command" }
+
+func (c commandNotifier) Notify(ctx context.Context, ev *Event) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", string(c))
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", string(c))
+	}
+	cmd.Stdin = strings.NewReader(ev.Body)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "CLASSROOM_EVENT="+ev.Type, "CLASSROOM_TITLE="+ev.Title)
+	return cmd.Run()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// NotionConfig はNotionのデータベースへの書き出しの設定です。
+type NotionConfig struct {
+	// Token はNotionのインテグレーションのトークンです。環境変数 NOTION_TOKEN でも指定できます。
+	Token string `json:"token,omitempty"`
+	// Database は書き出すデータベースのIDです。インテグレーションと共有しておく必要があります。
+	Database string `json:"database,omitempty"`
+	// Properties はデータベースのプロパティ名です。省略した項目は既定の名前を使います。
+	Properties NotionProperties `json:"properties"`
+}
+
+// NotionProperties はデータベースのプロパティ名です。
+type NotionProperties struct {
+	// Title はタイトルのプロパティです (既定: Name)。
+	Title string `json:"title,omitempty"`
+	// Course はセレクトのプロパティです (既定: Course)。
+	Course string `json:"course,omitempty"`
+	// Due は日付のプロパティです (既定: Due)。
+	Due string `json:"due,omitempty"`
+	// Status はセレクトのプロパティです (既定: Status)。
+	Status string `json:"status,omitempty"`
+	// URL はURLのプロパティです (既定: URL)。
+	URL string `json:"url,omitempty"`
+}
+
+func (p *NotionProperties) setDefaults() {
+	for _, f := range []struct {
+		v   *string
+		def string
+	}{{&p.Title, "Name"}, {&p.Course, "Course"}, {&p.Due, "Due"}, {&p.Status, "Status"}, {&p.URL, "URL"}} {
+		if *f.v == "" {
+			*f.v = f.def
+		}
+	}
+}
+
+const (
+	notionAPI     = "https://api.notion.com/v1"
+	notionVersion = "2022-06-28"
+	// notionStateFile は課題と作成したNotionのページの対応を記録するファイルです。
+	notionStateFile = "notion.json"
+)
+
+type notionPage struct {
+	ID string `json:"id"`
+}
+
+func runExportNotion(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export notion", flag.ExitOnError)
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	nc := cfg.Notion
+	if nc.Token == "" {
+		nc.Token = os.Getenv("NOTION_TOKEN")
+	}
+	if nc.Token == "" {
+		log.Fatal("Notionのトークンがありません (設定ファイルの notion.token または環境変数 NOTION_TOKEN)")
+	}
+	if nc.Database == "" {
+		log.Fatal("設定ファイルに notion.database がありません")
+	}
+	nc.Properties.setDefaults()
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := &restClient{name: "Notion", base: notionAPI, token: nc.Token, header: map[string]string{"Notion-Version": notionVersion}}
+	if err := syncNotion(ctx, cfg, &nc, client, items, filter, time.Now()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// syncNotion は未提出の課題をNotionのデータベースに追加・更新し、提出済みになった課題の状態を更新します。
+// 課題とページの対応は notion.json に記録し、繰り返し実行しても重複して追加しません。
+func syncNotion(ctx context.Context, cfg *Config, nc *NotionConfig, client *restClient, items []*Assignment, filter *Filter, now time.Time) error {
+	path := cfg.dataPath(notionStateFile)
+	state := map[string]*syncedTask{}
+	if err := readJSONFile(path, &state); err != nil {
+		return fmt.Errorf("Notionの同期の状態を読み取れませんでした: %w", err)
+	}
+
+	var added, updated int
+	err := func() error {
+		for _, a := range items {
+			key := assignmentKey(a)
+			st, found := state[key]
+			turnedIn := a.State() == "TURNED_IN" || a.State() == "RETURNED"
+			if !found && (turnedIn || !isCourseworkVisible(a, now) || !filter.match(a)) {
+				continue
+			}
+			fp := a.fingerprint()
+			if found && st.Fingerprint == fp {
+				continue
+			}
+			props := notionProperties(&nc.Properties, a)
+			if found {
+				err := client.do(ctx, http.MethodPatch, "/pages/"+st.ID, map[string]any{"properties": props}, nil)
+				if err == nil {
+					st.Fingerprint, st.Completed = fp, turnedIn
+					updated++
+					continue
+				}
+				if !isRESTNotFound(err) {
+					return fmt.Errorf("ページを更新できませんでした (%s): %w", a.CourseWork.Title, err)
+				}
+				delete(state, key)
+				if turnedIn {
+					continue
+				}
+				// ページが手動で削除されていた場合は追加し直す
+			}
+			body := map[string]any{
+				"parent":     map[string]string{"database_id": nc.Database},
+				"properties": props,
+			}
+			var page notionPage
+			if err := client.do(ctx, http.MethodPost, "/pages", body, &page); err != nil {
+				return fmt.Errorf("ページを追加できませんでした (%s): %w", a.CourseWork.Title, err)
+			}
+			state[key] = &syncedTask{ID: page.ID, Fingerprint: fp}
+			added++
+		}
+		return nil
+	}()
+	// 途中で失敗しても、それまでに作ったページは記録して重複を防ぐ
+	if werr := writeJSONFile(path, state); werr != nil && err == nil {
+		err = fmt.Errorf("Notionの同期の状態を保存できませんでした: %w", werr)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Notionに書き出しました (追加 %d件, 更新 %d件)", added, updated)
+	return nil
+}
+
+// notionProperties は課題をデータベースのプロパティの値に変換します。
+func notionProperties(p *NotionProperties, a *Assignment) map[string]any {
+	c := a.CourseWork
+	// セレクトの選択肢にはカンマを使えない
+	selectName := func(s string) map[string]any {
+		return map[string]any{"select": map[string]string{"name": strings.ReplaceAll(s, ",", " ")}}
+	}
+	state, ok := submissionStates[a.State()]
+	if !ok {
+		state = "未提出"
+	}
+	props := map[string]any{
+		p.Title:  map[string]any{"title": []map[string]any{{"text": map[string]string{"content": c.Title}}}},
+		p.Course: selectName(a.Course.Name),
+		p.Status: selectName(state),
+		p.URL:    map[string]any{"url": c.AlternateLink},
+		p.Due:    map[string]any{"date": nil},
+	}
+	if due, ok := a.Due(); ok {
+		props[p.Due] = map[string]any{"date": map[string]string{"start": due.Format(time.RFC3339)}}
+	}
+	return props
+}
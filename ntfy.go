@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultNtfyServer = "https://ntfy.sh"
+	// defaultNtfyUrgent は NtfyConfig.Urgent を省略したときに優先度を上げる締切までの時間です。
+	defaultNtfyUrgent = 24 * time.Hour
+	// ntfyDefaultPriority と ntfyUrgentPriority は ntfy の優先度 (1〜5) です。
+	ntfyDefaultPriority = 3
+	ntfyUrgentPriority  = 5
+)
+
+// NtfyConfig は ntfy (https://ntfy.sh) でのプッシュ通知の設定です。
+// スマートフォンの ntfy アプリでトピックを購読すると、アカウントなしで通知を受け取れます。
+type NtfyConfig struct {
+	// Server は ntfy のサーバーです。省略した場合は https://ntfy.sh です。
+	Server string `json:"server,omitempty"`
+	// Topic は送るトピックです。ntfy.sh ではだれでも購読できるため、推測されにくい名前にします。
+	Topic string `json:"topic"`
+	// Token はアクセストークン (tk_) です。省略した場合は環境変数 NTFY_TOKEN を使います。
+	// トークンの代わりに Username と Password でも認証できます。
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Events は送るできごとの種類です。省略した場合は新しい課題、締切の変更、締切が近い課題、毎日のまとめ、
+	// リマインダー、授業後のまとめを送ります。
+	Events []string `json:"events,omitempty"`
+	// Urgent は通知の優先度を最高にする締切までの時間です。省略した場合は "24h" です。
+	Urgent Duration `json:"urgent,omitempty"`
+}
+
+// defaultNtfyEvents は NtfyConfig.Events を省略したときに送るできごとです。
+var defaultNtfyEvents = []string{"created", "due_changed", "due_soon", "daily_digest", "reminder", "digest"}
+
+// ntfyTags はできごとの種類ごとに通知に付ける絵文字のタグです。
+var ntfyTags = map[string]string{
+	"created":      "memo",
+	"due_changed":  "calendar",
+	"due_soon":     "alarm_clock",
+	"daily_digest": "clipboard",
+	"reminder":     "bell",
+	"digest":       "clipboard",
+}
+
+// ntfyNotifier は通知を ntfy のトピックに送ります。
+type ntfyNotifier struct {
+	cfg  NtfyConfig
+	rest *restClient
+}
+
+func newNtfyNotifier(cfg NtfyConfig) *ntfyNotifier {
+	if cfg.Server == "" {
+		cfg.Server = defaultNtfyServer
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("NTFY_TOKEN")
+	}
+	if len(cfg.Events) == 0 {
+		cfg.Events = defaultNtfyEvents
+	}
+	if cfg.Urgent == 0 {
+		cfg.Urgent = Duration(defaultNtfyUrgent)
+	}
+	rest := &restClient{name: "ntfy", base: strings.TrimSuffix(cfg.Server, "/"), token: cfg.Token, client: &http.Client{Timeout: webhookTimeout}}
+	if cfg.Token == "" && cfg.Username != "" {
+		basic := base64.StdEncoding.EncodeToString([]byte(cfg.Username + ":" + cfg.Password))
+		rest.header = map[string]string{"Authorization": "Basic " + basic}
+	}
+	return &ntfyNotifier{cfg: cfg, rest: rest}
+}
+
+func (n *ntfyNotifier) Name() string { return "ntfy" }
+
+func (n *ntfyNotifier) accepts(typ string) bool { return acceptsEvent(n.cfg.Events, typ) }
+
+func (n *ntfyNotifier) Notify(ctx context.Context, ev *Event) error {
+	if !n.accepts(ev.Type) {
+		return nil
+	}
+	if n.cfg.Topic == "" {
+		return errors.New("ntfyのトピックがありません (notify.ntfy.topic)")
+	}
+	// 本文に日本語を含むため、ヘッダーではなくJSONで送る
+	msg := map[string]any{
+		"topic":    n.cfg.Topic,
+		"title":    ev.Title,
+		"message":  ev.Body,
+		"priority": n.priority(ev),
+	}
+	if tag, ok := ntfyTags[ev.Type]; ok {
+		msg["tags"] = []string{tag}
+	}
+	if a := ev.Assignment; a != nil && a.CourseWork.AlternateLink != "" {
+		msg["click"] = a.CourseWork.AlternateLink
+	}
+	return n.rest.do(ctx, http.MethodPost, "/", msg, nil)
+}
+
+// priority は締切まで n.cfg.Urgent を切った課題の通知の優先度を上げます。
+func (n *ntfyNotifier) priority(ev *Event) int {
+	a := ev.Assignment
+	if a == nil {
+		return ntfyDefaultPriority
+	}
+	due, ok := a.Due()
+	if !ok || a.External() || due.Before(ev.Time) || due.Sub(ev.Time) > time.Duration(n.cfg.Urgent) {
+		return ntfyDefaultPriority
+	}
+	return ntfyUrgentPriority
+}
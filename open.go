@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+func runCourseworkOpen(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("coursework open", flag.ExitOnError)
+	// 番号で指定する場合は list と同じ絞り込みを指定する
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var a *Assignment
+	// 数字だけの短い引数は list の表示番号として扱う
+	if n, err := strconv.Atoi(fs.Arg(0)); err == nil && fs.NArg() == 1 && len(fs.Arg(0)) < 5 {
+		pending := filter.apply(pendingAssignments(items, time.Now()))
+		if n < 1 || n > len(pending) {
+			log.Fatalf("番号 %d の課題はありません (未提出の課題は %d件です)", n, len(pending))
+		}
+		a = pending[n-1]
+	} else if a, err = findAssignmentArgs(items, fs.Args()); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s を開いています: %s\n", a.CourseWork.Title, a.CourseWork.AlternateLink)
+	if err := openBrowser(a.CourseWork.AlternateLink); err != nil {
+		log.Fatalf("ブラウザを開けませんでした: %v", err)
+	}
+}
+
+// openBrowser は既定のブラウザでURLを開きます。
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
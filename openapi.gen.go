@@ -0,0 +1,791 @@
+//go:build go1.22
+
+// Package main provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.4.1 DO NOT EDIT.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/oapi-codegen/runtime"
+)
+
+const (
+	BearerAuthScopes = "bearerAuth.Scopes"
+	KeyQueryScopes   = "keyQuery.Scopes"
+)
+
+// ApiCourse defines model for ApiCourse.
+type ApiCourse struct {
+	Alias string `json:"alias,omitempty"`
+	Id    string `json:"id"`
+	Link  string `json:"link"`
+	Name  string `json:"name"`
+
+	// Pending 未提出の課題の件数 (外部ツールの課題を除く)
+	Pending int    `json:"pending"`
+	Section string `json:"section,omitempty"`
+}
+
+// ApiCourseWork 課題 (list -template のデータと同じ)
+type ApiCourseWork = TemplateData
+
+// ApiMintKeyRequest defines model for ApiMintKeyRequest.
+type ApiMintKeyRequest struct {
+	Name string `json:"name,omitempty"`
+
+	// Scopes 権限 (count, titles, coursework, calendar, keys, debug)
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Ttl 有効期間 (例 "720h")。省略した場合は期限がありません。
+	Ttl Duration `json:"ttl,omitempty"`
+}
+
+// ApiMintKeyResponse defines model for ApiMintKeyResponse.
+type ApiMintKeyResponse struct {
+	Expires time.Time `json:"expires,omitempty"`
+	Id      string    `json:"id"`
+	Key     string    `json:"key"`
+	Scopes  []string  `json:"scopes"`
+}
+
+// ApiPendingCount defines model for ApiPendingCount.
+type ApiPendingCount struct {
+	Count int `json:"count"`
+}
+
+// ApiPendingTitle defines model for ApiPendingTitle.
+type ApiPendingTitle struct {
+	Due   *time.Time `json:"due,omitempty"`
+	Title string     `json:"title"`
+}
+
+// ApiReadiness defines model for ApiReadiness.
+type ApiReadiness struct {
+	// Problems 準備ができていない理由
+	Problems []string `json:"problems,omitempty"`
+	Ready    bool     `json:"ready"`
+}
+
+// CourseFilter defines model for CourseFilter.
+type CourseFilter = []string
+
+// DueBeforeFilter defines model for DueBeforeFilter.
+type DueBeforeFilter = string
+
+// ExcludeTopicFilter defines model for ExcludeTopicFilter.
+type ExcludeTopicFilter = []string
+
+// PageSizeParam defines model for PageSizeParam.
+type PageSizeParam = int
+
+// PageTokenParam defines model for PageTokenParam.
+type PageTokenParam = string
+
+// StateFilter defines model for StateFilter.
+type StateFilter = []string
+
+// TopicFilter defines model for TopicFilter.
+type TopicFilter = []string
+
+// TypeFilter defines model for TypeFilter.
+type TypeFilter = []string
+
+// CourseWorkPage defines model for CourseWorkPage.
+type CourseWorkPage = []ApiCourseWork
+
+// ListCourseCourseworkParams defines parameters for ListCourseCoursework.
+type ListCourseCourseworkParams struct {
+	// Course コースのID・別名・名前
+	Course *CourseFilter `form:"course,omitempty" json:"course,omitempty"`
+
+	// Type 課題の種類 (ASSIGNMENT, SHORT_ANSWER_QUESTION, MULTIPLE_CHOICE_QUESTION)
+	Type *TypeFilter `form:"type,omitempty" json:"type,omitempty"`
+
+	// State 提出物の状態 (NEW, CREATED, TURNED_IN, RETURNED, RECLAIMED_BY_STUDENT)
+	State *StateFilter `form:"state,omitempty" json:"state,omitempty"`
+
+	// Topic トピックの名前またはID
+	Topic *TopicFilter `form:"topic,omitempty" json:"topic,omitempty"`
+
+	// ExcludeTopic 除くトピックの名前またはID
+	ExcludeTopic *ExcludeTopicFilter `form:"exclude_topic,omitempty" json:"exclude_topic,omitempty"`
+
+	// DueBefore 猶予期間を含む締切がこの日時より前の課題だけを返します (2006-01-02、RFC 3339 または 7d のような今からの期間)
+	DueBefore *DueBeforeFilter `form:"due_before,omitempty" json:"due_before,omitempty"`
+
+	// PageSize 1ページの件数。省略した場合はすべてを返します。
+	PageSize *PageSizeParam `form:"page_size,omitempty" json:"page_size,omitempty"`
+
+	// PageToken 前のページの X-Next-Page-Token
+	PageToken *PageTokenParam `form:"page_token,omitempty" json:"page_token,omitempty"`
+}
+
+// ListCourseworkParams defines parameters for ListCoursework.
+type ListCourseworkParams struct {
+	// DueWithin 締切切れを含めて、この期間内に締切がある課題だけを返します (例 7d, 12h)
+	DueWithin *string `form:"due_within,omitempty" json:"due_within,omitempty"`
+
+	// Course コースのID・別名・名前
+	Course *CourseFilter `form:"course,omitempty" json:"course,omitempty"`
+
+	// Type 課題の種類 (ASSIGNMENT, SHORT_ANSWER_QUESTION, MULTIPLE_CHOICE_QUESTION)
+	Type *TypeFilter `form:"type,omitempty" json:"type,omitempty"`
+
+	// State 提出物の状態 (NEW, CREATED, TURNED_IN, RETURNED, RECLAIMED_BY_STUDENT)
+	State *StateFilter `form:"state,omitempty" json:"state,omitempty"`
+
+	// Topic トピックの名前またはID
+	Topic *TopicFilter `form:"topic,omitempty" json:"topic,omitempty"`
+
+	// ExcludeTopic 除くトピックの名前またはID
+	ExcludeTopic *ExcludeTopicFilter `form:"exclude_topic,omitempty" json:"exclude_topic,omitempty"`
+
+	// DueBefore 猶予期間を含む締切がこの日時より前の課題だけを返します (2006-01-02、RFC 3339 または 7d のような今からの期間)
+	DueBefore *DueBeforeFilter `form:"due_before,omitempty" json:"due_before,omitempty"`
+
+	// PageSize 1ページの件数。省略した場合はすべてを返します。
+	PageSize *PageSizeParam `form:"page_size,omitempty" json:"page_size,omitempty"`
+
+	// PageToken 前のページの X-Next-Page-Token
+	PageToken *PageTokenParam `form:"page_token,omitempty" json:"page_token,omitempty"`
+}
+
+// CalendarParams defines parameters for Calendar.
+type CalendarParams struct {
+	// Todo true の場合は予定 (VEVENT) の代わりにタスク (VTODO) として返します。省略時は ical.todo です。
+	Todo *bool `form:"todo,omitempty" json:"todo,omitempty"`
+
+	// Alarm 締切の何時間前に通知するか (例 1d,1h)。省略時は ical.alarms です。
+	Alarm *string `form:"alarm,omitempty" json:"alarm,omitempty"`
+
+	// Course コースのID・別名・名前
+	Course *CourseFilter `form:"course,omitempty" json:"course,omitempty"`
+
+	// Type 課題の種類 (ASSIGNMENT, SHORT_ANSWER_QUESTION, MULTIPLE_CHOICE_QUESTION)
+	Type *TypeFilter `form:"type,omitempty" json:"type,omitempty"`
+
+	// State 提出物の状態 (NEW, CREATED, TURNED_IN, RETURNED, RECLAIMED_BY_STUDENT)
+	State *StateFilter `form:"state,omitempty" json:"state,omitempty"`
+
+	// Topic トピックの名前またはID
+	Topic *TopicFilter `form:"topic,omitempty" json:"topic,omitempty"`
+
+	// ExcludeTopic 除くトピックの名前またはID
+	ExcludeTopic *ExcludeTopicFilter `form:"exclude_topic,omitempty" json:"exclude_topic,omitempty"`
+
+	// DueBefore 猶予期間を含む締切がこの日時より前の課題だけを返します (2006-01-02、RFC 3339 または 7d のような今からの期間)
+	DueBefore *DueBeforeFilter `form:"due_before,omitempty" json:"due_before,omitempty"`
+}
+
+// MintKeyJSONRequestBody defines body for MintKey for application/json ContentType.
+type MintKeyJSONRequestBody = ApiMintKeyRequest
+
+// ServerInterface represents all server handlers.
+type ServerInterface interface {
+	// コースの一覧
+	// (GET /api/courses)
+	ListCourses(w http.ResponseWriter, r *http.Request)
+	// コースの未提出の課題
+	// (GET /api/courses/{id}/coursework)
+	ListCourseCoursework(w http.ResponseWriter, r *http.Request, id string, params ListCourseCourseworkParams)
+	// 未提出の課題の絞り込み
+	// (GET /api/coursework)
+	ListCoursework(w http.ResponseWriter, r *http.Request, params ListCourseworkParams)
+	// APIキーの発行
+	// (POST /api/keys)
+	MintKey(w http.ResponseWriter, r *http.Request)
+	// 未提出の課題
+	// (GET /api/pending)
+	ListPending(w http.ResponseWriter, r *http.Request)
+	// 未提出の課題の件数 (外部ツールの課題を除く)
+	// (GET /api/pending/count)
+	CountPending(w http.ResponseWriter, r *http.Request)
+	// 未提出の課題のタイトルと締切
+	// (GET /api/pending/titles)
+	ListPendingTitles(w http.ResponseWriter, r *http.Request)
+	// 締切のiCalendarフィード
+	// (GET /calendar.ics)
+	Calendar(w http.ResponseWriter, r *http.Request, params CalendarParams)
+	// プロセスが動いているかどうか (liveness)
+	// (GET /healthz)
+	Healthz(w http.ResponseWriter, r *http.Request)
+	// 課題を返せるかどうか (readiness)
+	// (GET /readyz)
+	Readyz(w http.ResponseWriter, r *http.Request)
+}
+
+// ServerInterfaceWrapper converts contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler            ServerInterface
+	HandlerMiddlewares []MiddlewareFunc
+	ErrorHandlerFunc   func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+type MiddlewareFunc func(http.Handler) http.Handler
+
+// ListCourses operation middleware
+func (siw *ServerInterfaceWrapper) ListCourses(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{"titles"})
+
+	ctx = context.WithValue(ctx, KeyQueryScopes, []string{"titles"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListCourses(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListCourseCoursework operation middleware
+func (siw *ServerInterfaceWrapper) ListCourseCoursework(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{"coursework"})
+
+	ctx = context.WithValue(ctx, KeyQueryScopes, []string{"coursework"})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListCourseCourseworkParams
+
+	// ------------- Optional query parameter "course" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "course", r.URL.Query(), &params.Course)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "course", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "type" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "type", r.URL.Query(), &params.Type)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "type", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "state" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "state", r.URL.Query(), &params.State)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "state", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "topic" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "topic", r.URL.Query(), &params.Topic)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "topic", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "exclude_topic" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "exclude_topic", r.URL.Query(), &params.ExcludeTopic)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "exclude_topic", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "due_before" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "due_before", r.URL.Query(), &params.DueBefore)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "due_before", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "page_size" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "page_size", r.URL.Query(), &params.PageSize)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "page_size", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "page_token" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "page_token", r.URL.Query(), &params.PageToken)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "page_token", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListCourseCoursework(w, r, id, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListCoursework operation middleware
+func (siw *ServerInterfaceWrapper) ListCoursework(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{"coursework"})
+
+	ctx = context.WithValue(ctx, KeyQueryScopes, []string{"coursework"})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListCourseworkParams
+
+	// ------------- Optional query parameter "due_within" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "due_within", r.URL.Query(), &params.DueWithin)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "due_within", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "course" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "course", r.URL.Query(), &params.Course)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "course", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "type" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "type", r.URL.Query(), &params.Type)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "type", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "state" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "state", r.URL.Query(), &params.State)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "state", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "topic" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "topic", r.URL.Query(), &params.Topic)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "topic", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "exclude_topic" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "exclude_topic", r.URL.Query(), &params.ExcludeTopic)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "exclude_topic", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "due_before" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "due_before", r.URL.Query(), &params.DueBefore)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "due_before", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "page_size" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "page_size", r.URL.Query(), &params.PageSize)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "page_size", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "page_token" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "page_token", r.URL.Query(), &params.PageToken)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "page_token", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListCoursework(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// MintKey operation middleware
+func (siw *ServerInterfaceWrapper) MintKey(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{"keys"})
+
+	ctx = context.WithValue(ctx, KeyQueryScopes, []string{"keys"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.MintKey(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListPending operation middleware
+func (siw *ServerInterfaceWrapper) ListPending(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{"coursework"})
+
+	ctx = context.WithValue(ctx, KeyQueryScopes, []string{"coursework"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListPending(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CountPending operation middleware
+func (siw *ServerInterfaceWrapper) CountPending(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{"count"})
+
+	ctx = context.WithValue(ctx, KeyQueryScopes, []string{"count"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CountPending(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListPendingTitles operation middleware
+func (siw *ServerInterfaceWrapper) ListPendingTitles(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{"titles"})
+
+	ctx = context.WithValue(ctx, KeyQueryScopes, []string{"titles"})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListPendingTitles(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// Calendar operation middleware
+func (siw *ServerInterfaceWrapper) Calendar(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{"calendar"})
+
+	ctx = context.WithValue(ctx, KeyQueryScopes, []string{"calendar"})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CalendarParams
+
+	// ------------- Optional query parameter "todo" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "todo", r.URL.Query(), &params.Todo)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "todo", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "alarm" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "alarm", r.URL.Query(), &params.Alarm)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "alarm", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "course" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "course", r.URL.Query(), &params.Course)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "course", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "type" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "type", r.URL.Query(), &params.Type)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "type", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "state" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "state", r.URL.Query(), &params.State)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "state", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "topic" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "topic", r.URL.Query(), &params.Topic)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "topic", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "exclude_topic" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "exclude_topic", r.URL.Query(), &params.ExcludeTopic)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "exclude_topic", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "due_before" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "due_before", r.URL.Query(), &params.DueBefore)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "due_before", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.Calendar(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// Healthz operation middleware
+func (siw *ServerInterfaceWrapper) Healthz(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.Healthz(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// Readyz operation middleware
+func (siw *ServerInterfaceWrapper) Readyz(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.Readyz(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+type UnescapedCookieParamError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *UnescapedCookieParamError) Error() string {
+	return fmt.Sprintf("error unescaping cookie parameter '%s'", e.ParamName)
+}
+
+func (e *UnescapedCookieParamError) Unwrap() error {
+	return e.Err
+}
+
+type UnmarshalingParamError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *UnmarshalingParamError) Error() string {
+	return fmt.Sprintf("Error unmarshaling parameter %s as JSON: %s", e.ParamName, e.Err.Error())
+}
+
+func (e *UnmarshalingParamError) Unwrap() error {
+	return e.Err
+}
+
+type RequiredParamError struct {
+	ParamName string
+}
+
+func (e *RequiredParamError) Error() string {
+	return fmt.Sprintf("Query argument %s is required, but not found", e.ParamName)
+}
+
+type RequiredHeaderError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *RequiredHeaderError) Error() string {
+	return fmt.Sprintf("Header parameter %s is required, but not found", e.ParamName)
+}
+
+func (e *RequiredHeaderError) Unwrap() error {
+	return e.Err
+}
+
+type InvalidParamFormatError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *InvalidParamFormatError) Error() string {
+	return fmt.Sprintf("Invalid format for parameter %s: %s", e.ParamName, e.Err.Error())
+}
+
+func (e *InvalidParamFormatError) Unwrap() error {
+	return e.Err
+}
+
+type TooManyValuesForParamError struct {
+	ParamName string
+	Count     int
+}
+
+func (e *TooManyValuesForParamError) Error() string {
+	return fmt.Sprintf("Expected one value for %s, got %d", e.ParamName, e.Count)
+}
+
+// Handler creates http.Handler with routing matching OpenAPI spec.
+func Handler(si ServerInterface) http.Handler {
+	return HandlerWithOptions(si, StdHTTPServerOptions{})
+}
+
+// ServeMux is an abstraction of http.ServeMux.
+type ServeMux interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+	ServeHTTP(w http.ResponseWriter, r *http.Request)
+}
+
+type StdHTTPServerOptions struct {
+	BaseURL          string
+	BaseRouter       ServeMux
+	Middlewares      []MiddlewareFunc
+	ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// HandlerFromMux creates http.Handler with routing matching OpenAPI spec based on the provided mux.
+func HandlerFromMux(si ServerInterface, m ServeMux) http.Handler {
+	return HandlerWithOptions(si, StdHTTPServerOptions{
+		BaseRouter: m,
+	})
+}
+
+func HandlerFromMuxWithBaseURL(si ServerInterface, m ServeMux, baseURL string) http.Handler {
+	return HandlerWithOptions(si, StdHTTPServerOptions{
+		BaseURL:    baseURL,
+		BaseRouter: m,
+	})
+}
+
+// HandlerWithOptions creates http.Handler with additional options
+func HandlerWithOptions(si ServerInterface, options StdHTTPServerOptions) http.Handler {
+	m := options.BaseRouter
+
+	if m == nil {
+		m = http.NewServeMux()
+	}
+	if options.ErrorHandlerFunc == nil {
+		options.ErrorHandlerFunc = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}
+
+	wrapper := ServerInterfaceWrapper{
+		Handler:            si,
+		HandlerMiddlewares: options.Middlewares,
+		ErrorHandlerFunc:   options.ErrorHandlerFunc,
+	}
+
+	m.HandleFunc("GET "+options.BaseURL+"/api/courses", wrapper.ListCourses)
+	m.HandleFunc("GET "+options.BaseURL+"/api/courses/{id}/coursework", wrapper.ListCourseCoursework)
+	m.HandleFunc("GET "+options.BaseURL+"/api/coursework", wrapper.ListCoursework)
+	m.HandleFunc("POST "+options.BaseURL+"/api/keys", wrapper.MintKey)
+	m.HandleFunc("GET "+options.BaseURL+"/api/pending", wrapper.ListPending)
+	m.HandleFunc("GET "+options.BaseURL+"/api/pending/count", wrapper.CountPending)
+	m.HandleFunc("GET "+options.BaseURL+"/api/pending/titles", wrapper.ListPendingTitles)
+	m.HandleFunc("GET "+options.BaseURL+"/calendar.ics", wrapper.Calendar)
+	m.HandleFunc("GET "+options.BaseURL+"/healthz", wrapper.Healthz)
+	m.HandleFunc("GET "+options.BaseURL+"/readyz", wrapper.Readyz)
+
+	return m
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+func runExportOrg(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export org", flag.ExitOnError)
+	out := fs.String("out", "", "出力ファイル (省略時は標準出力)")
+	lead := fs.String("lead", "", "締切のどれだけ前を SCHEDULED にするか (例: 2d)。スヌーズを設定した課題はスヌーズの期限を使います")
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	var leadTime time.Duration
+	if *lead != "" {
+		var err error
+		if leadTime, err = parseOffset(*lead); err != nil {
+			log.Fatal(err)
+		}
+	}
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	meta, err := loadMeta(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("出力ファイルを作成できませんでした: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	pending := filter.apply(pendingAssignments(items, time.Now()))
+	if err := writeOrg(w, pending, meta, leadTime); err != nil {
+		log.Fatalf("Orgファイルを書き込めませんでした: %v", err)
+	}
+}
+
+// writeOrg は課題をOrgの見出しとして書き出します。
+// 締切は DEADLINE に、スヌーズの期限または締切の lead 前を SCHEDULED にします。
+func writeOrg(w io.Writer, items []*Assignment, meta *metaStore, lead time.Duration) error {
+	var b strings.Builder
+	b.WriteString("#+TITLE: Classroom の課題\n\n")
+	for _, a := range items {
+		c := a.CourseWork
+		tags := []string{orgTag(courseLabel(a))}
+		if v, ok := meta.get(a, "tags"); ok {
+			for _, t := range splitList(v) {
+				tags = append(tags, orgTag(t))
+			}
+		}
+		fmt.Fprintf(&b, "* TODO %s :%s:\n", c.Title, strings.Join(tags, ":"))
+
+		var planning []string
+		if v, ok := meta.get(a, "snooze"); ok {
+			if t, err := parseSnooze(v); err == nil {
+				planning = append(planning, "SCHEDULED: "+orgTimestamp(t))
+			}
+		} else if due, ok := a.EffectiveDue(); ok && lead > 0 {
+			planning = append(planning, "SCHEDULED: "+orgTimestamp(due.Add(-lead)))
+		}
+		if due, ok := a.EffectiveDue(); ok {
+			planning = append(planning, "DEADLINE: "+orgTimestamp(due))
+		}
+		if len(planning) > 0 {
+			fmt.Fprintf(&b, "  %s\n", strings.Join(planning, " "))
+		}
+
+		b.WriteString("  :PROPERTIES:\n")
+		fmt.Fprintf(&b, "  :ID:       %s\n", c.Id)
+		fmt.Fprintf(&b, "  :COURSE:   %s\n", a.Course.Name)
+		fmt.Fprintf(&b, "  :LINK:     [[%s][Classroomで開く]]\n", c.AlternateLink)
+		if c.MaxPoints > 0 {
+			fmt.Fprintf(&b, "  :POINTS:   %g\n", c.MaxPoints)
+		}
+		b.WriteString("  :END:\n")
+		if note, ok := meta.get(a, "note"); ok {
+			fmt.Fprintf(&b, "  %s\n", note)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// orgTimestamp は日時をOrgのアクティブなタイムスタンプ (<2026-10-20 火 23:59>) にします。
+func orgTimestamp(t time.Time) string {
+	return fmt.Sprintf("<%s %s %s>", t.Format("2006-01-02"), weekdays[t.Weekday()], t.Format("15:04"))
+}
+
+// orgTag はOrgのタグに使えない文字を _ に置き換えます。
+func orgTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_@#%", r) {
+			return r
+		}
+		return '_'
+	}, s)
+}
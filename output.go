@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+)
+
+const (
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+)
+
+// outputOptions は一覧の表示方法の設定です。
+type outputOptions struct {
+	noColor  bool
+	relative bool
+	now      time.Time
+}
+
+// addOutputFlags は一覧の表示方法のフラグを登録します。
+func addOutputFlags(fs *flag.FlagSet) *outputOptions {
+	o := &outputOptions{now: time.Now()}
+	fs.BoolVar(&o.noColor, "no-color", false, "締切の近さによる色分けをしません (環境変数 NO_COLOR でも無効になります)")
+	fs.BoolVar(&o.relative, "relative", false, "締切を「あと6時間」のような残り時間で表示します")
+	return o
+}
+
+// color は色分けするかどうかを返します。端末以外への出力と NO_COLOR が設定されている場合は色分けしません。
+func (o *outputOptions) color() bool {
+	if o == nil || o.noColor {
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return isTerminal(os.Stdout)
+}
+
+// urgency は締切の近さです。
+type urgency int
+
+const (
+	urgencyNone urgency = iota
+	// urgencySoon は3日以内に締切があることを表します。
+	urgencySoon
+	// urgencyUrgent は24時間以内に締切があること (締切を過ぎている場合を含む) を表します。
+	urgencyUrgent
+)
+
+// urgencyOf は課題の締切の近さを返します。
+func urgencyOf(a *Assignment, now time.Time) urgency {
+	due, ok := a.EffectiveDue()
+	if !ok {
+		return urgencyNone
+	}
+	switch left := due.Sub(now); {
+	case left < 24*time.Hour:
+		return urgencyUrgent
+	case left < 3*24*time.Hour:
+		return urgencySoon
+	}
+	return urgencyNone
+}
+
+// urgencyColor は締切の近さに応じた色を返します。
+// 24時間以内は赤、3日以内は黄色で、それ以外は色を付けません。
+func urgencyColor(a *Assignment, now time.Time) string {
+	switch urgencyOf(a, now) {
+	case urgencyUrgent:
+		return colorRed
+	case urgencySoon:
+		return colorYellow
+	}
+	return ""
+}
+
+// dueLabel は一覧に表示する締切を返します。
+func (o *outputOptions) dueLabel(a *Assignment) string {
+	due, ok := a.EffectiveDue()
+	if !ok {
+		return "なし"
+	}
+	if o != nil && o.relative {
+		return formatRemaining(due.Sub(o.now))
+	}
+	return due.Format("1/2 15:04")
+}
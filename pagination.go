@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxPageSize は page_size に指定できる最大の件数です。
+	maxPageSize = 500
+	// nextPageTokenHeader は次のページのトークンを返すヘッダーです。
+	// 応答の本文は配列のままにするため、トークンは本文ではなくヘッダーと Link で返します。
+	nextPageTokenHeader = "X-Next-Page-Token"
+)
+
+// page は page_size と page_token で指定したページです。Size が 0 の場合はすべてを返します。
+type page struct {
+	Size   int
+	Offset int
+}
+
+// pageFromQuery は page_size と page_token を解析します。
+func pageFromQuery(r *http.Request) (page, error) {
+	q := r.URL.Query()
+	return parsePage(q.Get("page_size"), q.Get("page_token"))
+}
+
+// parsePage はページの大きさとトークンを解析します。どちらも空の場合は最初のページです。
+func parsePage(size, token string) (page, error) {
+	var pg page
+	if size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 1 || n > maxPageSize {
+			return pg, fmt.Errorf("page_size は 1 から %d の整数で指定してください", maxPageSize)
+		}
+		pg.Size = n
+	}
+	if token != "" {
+		b, err := base64.RawURLEncoding.DecodeString(token)
+		n, err2 := strconv.Atoi(string(b))
+		if err != nil || err2 != nil || n < 0 {
+			return pg, fmt.Errorf("page_token が正しくありません")
+		}
+		pg.Offset = n
+	}
+	return pg, nil
+}
+
+// slice はページの範囲の要素を返します。続きがあれば次のページのトークンも返します。
+func (pg page) slice(n int) (start, end int, next string) {
+	start = min(pg.Offset, n)
+	end = n
+	if pg.Size > 0 && start+pg.Size < n {
+		end = start + pg.Size
+		next = base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end)))
+	}
+	return start, end, next
+}
+
+// courseworkQuery は課題のエンドポイントの絞り込みとページを解析します。
+// 解析できなかった場合はエラーを書き込んで false を返します。
+func courseworkQuery(w http.ResponseWriter, r *http.Request, now time.Time) (*Filter, page, bool) {
+	filter, err := filterFromQuery(r.URL.Query(), now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, page{}, false
+	}
+	pg, err := pageFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, page{}, false
+	}
+	return filter, pg, true
+}
+
+// writeCourseworkPage は課題のページを GET /api/pending と同じ形式で書き込みます。
+// 続きがある場合は X-Next-Page-Token と Link: <...>; rel="next" で次のページを示します。
+func writeCourseworkPage(w http.ResponseWriter, r *http.Request, pg page, items []*Assignment, now time.Time) {
+	start, end, next := pg.slice(len(items))
+	if next != "" {
+		u := *r.URL
+		q := u.Query()
+		q.Set("page_token", next)
+		u.RawQuery = q.Encode()
+		w.Header().Set(nextPageTokenHeader, next)
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+	}
+	res := []*TemplateData{}
+	for _, a := range items[start:end] {
+		res = append(res, newTemplateData(a, now))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
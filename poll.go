@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"google.golang.org/api/pubsub/v1"
+	"time"
+)
+
+// PushConfig はClassroomのプッシュ通知 (Cloud Pub/Sub) の設定です。
+type PushConfig struct {
+	// Topic は通知を受け取るPub/Subのトピックです (projects/<プロジェクト>/topics/<トピック>)。
+	Topic string `json:"topic"`
+	// Subscription は Topic の pull 型のサブスクリプションです (projects/<プロジェクト>/subscriptions/<名前>)。
+	// ログインしたアカウントにサブスクライバーの権限が必要です。
+	Subscription string `json:"subscription"`
+	// Token と Audience は serve の /pubsub/push でプッシュ型のサブスクリプションから通知を受け取るための設定です。
+	// Token を設定した場合は、エンドポイントのURLのクエリパラメーター token が一致する配信だけを受け付けます。
+	Token string `json:"token,omitempty"`
+	// Audience を設定した場合は、サブスクリプションの認証で付けたGoogleのOIDCトークン (JWT) を確かめます。
+	// ServiceAccount を設定した場合は、トークンのメールアドレスがそのサービスアカウントであることも確かめます。
+	Audience       string `json:"audience,omitempty"`
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+}
+
+// enabled はサーバーでプッシュ通知を使う設定があるかどうかを返します。
+func (c *PushConfig) enabled() bool {
+	return c != nil && c.Topic != "" && (c.Subscription != "" || c.endpoint())
+}
+
+// endpoint は /pubsub/push で配信を受け付けるかどうかを返します。
+// 誰でも通知を送れてしまわないよう、Token か Audience のどちらかが必要です。
+func (c *PushConfig) endpoint() bool {
+	return c != nil && (c.Token != "" || c.Audience != "")
+}
+
+// PollingConfig はポーリング間隔の設定です。
+// 締切が近い課題があるときは短い間隔で、夜間は長い間隔で取得します。
+type PollingConfig struct {
+	// Urgent は UrgentWindow 以内に締切がある課題があるときの間隔です。
+	Urgent Duration `json:"urgent"`
+	// Normal は通常の間隔です。
+	Normal Duration `json:"normal"`
+	// Idle は未提出の課題がないコースの間隔です。
+	Idle Duration `json:"idle"`
+	// Night は夜間の間隔です。プッシュ通知が使えるときの念のための間隔にも使います。
+	Night Duration `json:"night"`
+	// UrgentWindow は締切が近いとみなす期間です。
+	UrgentWindow Duration `json:"urgentWindow"`
+	// NightStart と NightEnd は夜間とみなす時間帯 (時) です。両方を省略した場合は 0 時から 6 時です。
+	// 同じ時にした場合 (例: 両方 0) は夜間の間隔を使いません。
+	NightStart *int `json:"nightStart,omitempty"`
+	NightEnd   *int `json:"nightEnd,omitempty"`
+
+	// nightStart と nightEnd は既定値を当てはめた夜間の時間帯です。
+	nightStart, nightEnd int
+}
+
+func (p *PollingConfig) setDefaults() {
+	if p.Urgent == 0 {
+		p.Urgent = Duration(2 * time.Minute)
+	}
+	if p.Normal == 0 {
+		p.Normal = Duration(15 * time.Minute)
+	}
+	if p.Idle == 0 {
+		p.Idle = Duration(time.Hour)
+	}
+	if p.Night == 0 {
+		p.Night = Duration(time.Hour)
+	}
+	if p.UrgentWindow == 0 {
+		p.UrgentWindow = Duration(24 * time.Hour)
+	}
+	p.nightStart, p.nightEnd = 0, 6
+	if p.NightStart != nil || p.NightEnd != nil {
+		// 片方だけを書いた場合、もう片方は 0 時
+		p.nightStart, p.nightEnd = 0, 0
+		if p.NightStart != nil {
+			p.nightStart = *p.NightStart
+		}
+		if p.NightEnd != nil {
+			p.nightEnd = *p.NightEnd
+		}
+	}
+}
+
+// validate は夜間の時間帯が 0 から 23 の時になっているかを確かめます。
+func (p *PollingConfig) validate() error {
+	for _, f := range []struct {
+		name string
+		hour *int
+	}{{"nightStart", p.NightStart}, {"nightEnd", p.NightEnd}} {
+		if f.hour != nil && (*f.hour < 0 || *f.hour > 23) {
+			return fmt.Errorf("polling.%s は 0 から 23 の時にしてください: %d", f.name, *f.hour)
+		}
+	}
+	return nil
+}
+
+// isNight は now が夜間の時間帯に含まれるかどうかを返します。始まりと終わりが同じ場合はいつも false です。
+func (p *PollingConfig) isNight(now time.Time) bool {
+	h := now.Hour()
+	if p.nightStart <= p.nightEnd {
+		return p.nightStart <= h && h < p.nightEnd
+	}
+	return h >= p.nightStart || h < p.nightEnd
+}
+
+// next は次の取得までの間隔を返します。
+func (p *PollingConfig) next(mode syncMode, now time.Time, pending []*Assignment) time.Duration {
+	if mode.Push || p.isNight(now) {
+		return time.Duration(p.Night)
+	}
+	for _, a := range pending {
+		if due, ok := a.Due(); ok && due.After(now) && due.Sub(now) < time.Duration(p.UrgentWindow) {
+			return time.Duration(p.Urgent)
+		}
+	}
+	return time.Duration(p.Normal)
+}
+
+// syncMode は課題の変更をどのように検出しているかを表します。
+type syncMode struct {
+	// Push はプッシュ通知の登録に成功したかどうかです。
+	Push bool
+	// Reason はポーリングに切り替えた理由です。
+	Reason string
+}
+
+func (m syncMode) String() string {
+	if m.Push {
+		return "プッシュ通知"
+	}
+	return "ポーリング (" + m.Reason + ")"
+}
+
+// setupSyncMode はコースごとにプッシュ通知の登録を試み、
+// 登録できない場合や通知を受け取れない場合はポーリングに切り替えます。
+// プッシュ通知の登録には管理者による設定が必要なことが多いため、失敗しても終了しません。
+// プッシュ通知を使う場合は登録と通知を受け取るクライアントも返します。登録は使い終わったら close で削除してください。
+func setupSyncMode(ctx context.Context, srv *classroom.Service, cfg *Config, courses []*classroom.Course) (syncMode, *pushRegistrations, *pubsub.Service) {
+	switch {
+	case cfg.Push == nil || cfg.Push.Topic == "":
+		return syncMode{Reason: "Pub/Subのトピックが設定されていません"}, nil, nil
+	case cfg.Push.Subscription == "":
+		return syncMode{Reason: "Pub/Subのサブスクリプションが設定されていません"}, nil, nil
+	}
+	psrv, err := newPubsubService(ctx, newHTTPClient(cfg), cfg.Push.Subscription)
+	if err != nil {
+		return syncMode{Reason: err.Error()}, nil, nil
+	}
+	regs := newPushRegistrations(srv, cfg.Push.Topic)
+	if err := regs.sync(ctx, courses, time.Now()); err != nil {
+		regs.close()
+		return syncMode{Reason: err.Error()}, nil, nil
+	}
+	return syncMode{Push: true}, regs, psrv
+}
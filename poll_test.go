@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPollingConfigIsNight(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2026, 10, 16, h, 30, 0, 0, time.UTC) }
+	tests := []struct {
+		name  string
+		json  string
+		night []int
+	}{
+		{"省略した場合は0時から6時", `{}`, []int{0, 1, 2, 3, 4, 5}},
+		{"日をまたぐ", `{"nightStart": 22, "nightEnd": 2}`, []int{0, 1, 22, 23}},
+		{"終わりだけを省略", `{"nightStart": 22}`, []int{22, 23}},
+		{"両方 0 は使わない", `{"nightStart": 0, "nightEnd": 0}`, nil},
+		{"同じ時は使わない", `{"nightStart": 3, "nightEnd": 3}`, nil},
+	}
+	for _, tt := range tests {
+		var p PollingConfig
+		if err := json.Unmarshal([]byte(tt.json), &p); err != nil {
+			t.Fatal(err)
+		}
+		p.setDefaults()
+		night := map[int]bool{}
+		for _, h := range tt.night {
+			night[h] = true
+		}
+		for h := 0; h < 24; h++ {
+			if got := p.isNight(at(h)); got != night[h] {
+				t.Errorf("%s: isNight(%d時) = %v, want %v", tt.name, h, got, night[h])
+			}
+		}
+	}
+}
+
+func TestPollingConfigValidate(t *testing.T) {
+	for _, js := range []string{`{"nightStart": 24}`, `{"nightEnd": -1}`, `{"nightStart": 22, "nightEnd": 30}`} {
+		var p PollingConfig
+		if err := json.Unmarshal([]byte(js), &p); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.validate(); err == nil {
+			t.Errorf("%s はエラーになるはずです", js)
+		}
+	}
+	var p PollingConfig
+	if err := json.Unmarshal([]byte(`{"nightStart": 23, "nightEnd": 0}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// registerPprof は /debug/pprof にプロファイルのハンドラーを登録します。
+// プロファイルには全ユーザーの処理が含まれるため、ログインしたセッションでは見られず、debug の権限を持つAPIキーが必要です。
+func (s *server) registerPprof(mux *http.ServeMux) {
+	mux.Handle("GET /debug/pprof/", s.requireDebugKey(http.HandlerFunc(pprof.Index)))
+	mux.Handle("GET /debug/pprof/cmdline", s.requireDebugKey(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("GET /debug/pprof/profile", s.requireDebugKey(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("GET /debug/pprof/symbol", s.requireDebugKey(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("POST /debug/pprof/symbol", s.requireDebugKey(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("GET /debug/pprof/trace", s.requireDebugKey(http.HandlerFunc(pprof.Trace)))
+}
+
+func (s *server) requireDebugKey(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.checkAPIKey(r, "debug"); err != nil {
+			err.write(w)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
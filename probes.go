@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// handleHealthz はプロセスが動いていればいつでも 200 を返します (liveness)。
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz はトークンがあり、最後のバックグラウンドでの取得が成功していれば 200 を、そうでなければ 503 を返します (readiness)。
+// 複数ユーザーモードでは、これまでにログインしたすべてのユーザーについて確かめます。
+// 認証なしで呼び出せるため、応答にはユーザーやエラーの内容を含めず、一般的な理由だけを返します。詳しい理由はログに書きます。
+func (s *server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	res := &ApiReadiness{}
+	if s.ctx.Err() != nil {
+		res.Problems = append(res.Problems, "終了しています")
+	}
+	for _, a := range s.allAccounts() {
+		problem, detail := a.readinessProblem()
+		if problem == "" {
+			continue
+		}
+		log.Printf("/readyz: %s", detail)
+		if !slices.Contains(res.Problems, problem) {
+			res.Problems = append(res.Problems, problem)
+		}
+	}
+	res.Ready = len(res.Problems) == 0
+	status := http.StatusOK
+	if !res.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, res)
+}
+
+// readinessProblem はアカウントが課題を返せない場合に、応答に含める一般的な理由とログに書く詳しい理由を返します。
+// 課題を返せる場合は空です。
+func (a *account) readinessProblem() (problem, detail string) {
+	name := "アカウント"
+	if a.key != "" {
+		name = "ユーザー " + a.key
+	}
+	if a.tokens != nil && !a.tokens.loggedIn() {
+		problem = "ログインしていないアカウントがあります"
+		return problem, name + ": ログインしていません"
+	}
+	t, err := a.status()
+	switch {
+	case t.IsZero():
+		problem = "まだ課題を取得していないアカウントがあります"
+		return problem, name + ": まだ課題を取得していません"
+	case err != nil:
+		problem = "課題の取得に失敗したアカウントがあります"
+		return problem, fmt.Sprintf("%s: %s の取得に失敗しました: %v", name, t.Format(time.RFC3339), err)
+	}
+	return "", ""
+}
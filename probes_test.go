@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadyzHidesDetails(t *testing.T) {
+	a := &account{key: "student@example.com"}
+	a.setStatus(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), errors.New("oauth2: token expired for student@example.com"))
+	s := &server{ctx: context.Background(), single: a}
+	w := httptest.NewRecorder()
+	s.handleReadyz(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	body := w.Body.String()
+	if strings.Contains(body, "example.com") || strings.Contains(body, "oauth2") {
+		t.Errorf("応答にユーザーやエラーの内容が含まれています: %s", body)
+	}
+	if !strings.Contains(body, "課題の取得に失敗したアカウントがあります") {
+		t.Errorf("応答に理由がありません: %s", body)
+	}
+}
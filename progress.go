@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// fetchProgress は課題の取得中にコースごとの進捗を1行で表示します。
+// メソッドは nil でも呼び出せ、その場合は何も表示しません。
+type fetchProgress struct {
+	mu      sync.Mutex
+	w       io.Writer
+	total   int
+	done    int
+	failed  int
+	running map[string]bool
+	frame   int
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// newFetchProgress は標準出力と標準エラー出力が端末の場合だけ進捗表示を作ります。
+// 進捗は標準エラー出力に描くため、パイプで渡す出力には混ざりません。
+func newFetchProgress(total int) *fetchProgress {
+	if total == 0 || !isTerminal(os.Stdout) || !isTerminal(os.Stderr) {
+		return nil
+	}
+	p := &fetchProgress{
+		w:       os.Stderr,
+		total:   total,
+		running: map[string]bool{},
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go p.loop()
+	return p
+}
+
+// isTerminal はファイルが端末かどうかを返します。
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *fetchProgress) loop() {
+	defer close(p.stopped)
+	t := time.NewTicker(100 * time.Millisecond)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.stop:
+			fmt.Fprint(p.w, "\r\033[K")
+			return
+		case <-t.C:
+			p.mu.Lock()
+			p.frame++
+			p.render()
+			p.mu.Unlock()
+		}
+	}
+}
+
+func (p *fetchProgress) render() {
+	names := make([]string, 0, len(p.running))
+	for name := range p.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	status := strings.Join(names, ", ")
+	if r := []rune(status); len(r) > 50 {
+		status = string(r[:50]) + "…"
+	}
+	line := fmt.Sprintf("%s 課題を取得中 [%d/%d]", spinnerFrames[p.frame%len(spinnerFrames)], p.done, p.total)
+	if p.failed > 0 {
+		line += fmt.Sprintf(" 失敗 %d", p.failed)
+	}
+	if status != "" {
+		line += " " + status
+	}
+	fmt.Fprint(p.w, "\r\033[K"+line)
+}
+
+// courseStarted はコースの取得を始めたことを記録します。
+func (p *fetchProgress) courseStarted(name string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running[name] = true
+}
+
+// courseDone はコースの取得が終わったことを記録します。
+func (p *fetchProgress) courseDone(name string, err error) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.running, name)
+	p.done++
+	if err != nil {
+		p.failed++
+	}
+}
+
+// finish は進捗表示を消して終了します。
+func (p *fetchProgress) finish() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.stopped
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"google.golang.org/api/idtoken"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// pushRenewBefore は登録の期限のどれだけ前に登録し直すかです。登録は約1週間で期限が切れます。
+	pushRenewBefore = 24 * time.Hour
+	// pushRenewInterval はサーバーで登録の期限とコースの増減を確かめる間隔です。
+	pushRenewInterval = time.Hour
+	// pushRetryInterval は通知の受信に失敗したときに待つ時間です。
+	pushRetryInterval = time.Minute
+	// pushCloseTimeout は終了時に登録を削除するのに待つ時間です。
+	pushCloseTimeout = 10 * time.Second
+)
+
+// pushRegistrations はコースごとの COURSE_WORK_CHANGES の登録を管理します。
+// 登録していないコースと期限が近い登録は sync で登録し直し、なくなったコースの登録と終了時の登録は削除します。
+type pushRegistrations struct {
+	srv   *classroom.Service
+	topic string
+	mu    sync.Mutex
+	// regs はコースIDごとの登録です。
+	regs map[string]*classroom.Registration
+}
+
+func newPushRegistrations(srv *classroom.Service, topic string) *pushRegistrations {
+	return &pushRegistrations{srv: srv, topic: topic, regs: map[string]*classroom.Registration{}}
+}
+
+// sync は courses のすべてに期限の近くない登録があるようにし、courses にないコースの登録を削除します。
+func (p *pushRegistrations) sync(ctx context.Context, courses []*classroom.Course, now time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make(map[string]bool, len(courses))
+	for _, c := range courses {
+		ids[c.Id] = true
+		if old, ok := p.regs[c.Id]; ok && !registrationExpiring(old, now) {
+			continue
+		}
+		reg, err := p.srv.Registrations.Create(&classroom.Registration{
+			Feed: &classroom.Feed{
+				FeedType:              "COURSE_WORK_CHANGES",
+				CourseWorkChangesInfo: &classroom.CourseWorkChangesInfo{CourseId: c.Id},
+			},
+			CloudPubsubTopic: &classroom.CloudPubsubTopic{TopicName: p.topic},
+		}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("%s のPub/Sub登録に失敗しました: %w", c.Name, err)
+		}
+		// 登録し直した場合は古い登録を削除する。同じ登録が返された場合は削除しない
+		if old, ok := p.regs[c.Id]; ok && old.RegistrationId != reg.RegistrationId {
+			p.delete(ctx, old)
+		}
+		p.regs[c.Id] = reg
+	}
+	for id, reg := range p.regs {
+		if !ids[id] {
+			p.delete(ctx, reg)
+			delete(p.regs, id)
+		}
+	}
+	return nil
+}
+
+// close はすべての登録を削除します。ctx が終了していても削除できるよう、別のコンテキストを使います。
+func (p *pushRegistrations) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), pushCloseTimeout)
+	defer cancel()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, reg := range p.regs {
+		p.delete(ctx, reg)
+		delete(p.regs, id)
+	}
+}
+
+// delete は登録を削除します。削除できなくても期限が来れば無効になるため、ログに書くだけにします。
+func (p *pushRegistrations) delete(ctx context.Context, reg *classroom.Registration) {
+	if _, err := p.srv.Registrations.Delete(reg.RegistrationId).Context(ctx).Do(); err != nil {
+		log.Printf("Pub/Subの登録 %s を削除できませんでした: %v", reg.RegistrationId, err)
+	}
+}
+
+// registrationExpiring は登録の期限が pushRenewBefore 以内かどうかを返します。期限が分からない場合も登録し直します。
+func registrationExpiring(reg *classroom.Registration, now time.Time) bool {
+	expiry, err := time.Parse(time.RFC3339, reg.ExpiryTime)
+	return err != nil || expiry.Sub(now) < pushRenewBefore
+}
+
+// pushNotification はClassroomがPub/Subに送る通知の本文です。
+type pushNotification struct {
+	Collection string `json:"collection"`
+	EventType  string `json:"eventType"`
+	ResourceID struct {
+		CourseID string `json:"courseId"`
+		ID       string `json:"id"`
+	} `json:"resourceId"`
+}
+
+// newPubsubService はPub/Subのサブスクリプションから通知を受け取るクライアントを作り、サブスクリプションを読めることを確かめます。
+func newPubsubService(ctx context.Context, client *http.Client, subscription string) (*pubsub.Service, error) {
+	psrv, err := pubsub.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("Pub/Subクライアントを作成できませんでした: %w", err)
+	}
+	if _, err := psrv.Projects.Subscriptions.Get(subscription).Context(ctx).Do(); err != nil {
+		return nil, fmt.Errorf("Pub/Subのサブスクリプション %s を読めません (Pub/Subの権限を追加したため、古いトークンの場合はログインし直してください): %w", subscription, err)
+	}
+	return psrv, nil
+}
+
+// receivePush は ctx が終了するまでサブスクリプションから通知を受け取り、課題の変更があったコースのIDを changed に渡します。
+func receivePush(ctx context.Context, psrv *pubsub.Service, subscription string, changed func(courseID string)) {
+	for ctx.Err() == nil {
+		res, err := psrv.Projects.Subscriptions.Pull(subscription, &pubsub.PullRequest{MaxMessages: 100}).Context(ctx).Do()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Pub/Subから通知を受け取れませんでした: %v", err)
+				sleepContext(ctx, pushRetryInterval)
+			}
+			continue
+		}
+		var ackIDs []string
+		for _, m := range res.ReceivedMessages {
+			ackIDs = append(ackIDs, m.AckId)
+			n, err := decodePushNotification(m.Message)
+			if err != nil {
+				log.Print(err)
+				continue
+			}
+			changed(n.ResourceID.CourseID)
+		}
+		if len(ackIDs) == 0 {
+			continue
+		}
+		if _, err := psrv.Projects.Subscriptions.Acknowledge(subscription, &pubsub.AcknowledgeRequest{AckIds: ackIDs}).Context(ctx).Do(); err != nil && ctx.Err() == nil {
+			log.Printf("Pub/Subの通知を確認済みにできませんでした: %v", err)
+		}
+	}
+}
+
+func decodePushNotification(m *pubsub.PubsubMessage) (*pushNotification, error) {
+	if m == nil {
+		return nil, errors.New("Pub/Subの通知に本文がありません")
+	}
+	b, err := base64.StdEncoding.DecodeString(m.Data)
+	if err != nil {
+		return nil, fmt.Errorf("Pub/Subの通知 %s を解析できませんでした: %w", m.MessageId, err)
+	}
+	n := &pushNotification{}
+	if err := json.Unmarshal(b, n); err != nil {
+		return nil, fmt.Errorf("Pub/Subの通知 %s を解析できませんでした: %w", m.MessageId, err)
+	}
+	if n.ResourceID.CourseID == "" {
+		return nil, fmt.Errorf("Pub/Subの通知 %s にコースIDがありません", m.MessageId)
+	}
+	return n, nil
+}
+
+// startPush はサーバーのアカウントについてプッシュ通知を登録し、通知を受け取ったらすぐに課題を取得し直すようにします。
+// 通知は Subscription があればそこから受け取り、プッシュ型のサブスクリプションの場合は /pubsub/push で受け取ります。
+// コースの一覧を取得できるまで (ログインするまで) は登録せず、登録後は pushRenewInterval ごとに登録を更新します。
+// 終了時には登録を削除します。
+func (s *server) startPush(a *account) {
+	topic, subscription := s.cfg.Push.Topic, s.cfg.Push.Subscription
+	regs := newPushRegistrations(a.srv, topic)
+	s.refreshers.Add(1)
+	go func() {
+		defer s.refreshers.Done()
+		defer regs.close()
+		var psrv *pubsub.Service
+		var registered bool
+		for {
+			if courses, err := a.courseList(s.ctx); err == nil {
+				if subscription != "" && psrv == nil {
+					if psrv, err = newPubsubService(s.ctx, a.client, subscription); err != nil {
+						log.Print(err)
+					}
+				}
+				if subscription == "" || psrv != nil {
+					if err := regs.sync(s.ctx, courses, time.Now()); err != nil {
+						if s.ctx.Err() == nil {
+							log.Print(err)
+						}
+					} else if !registered {
+						registered = true
+						log.Printf("%d コースの課題の変更のプッシュ通知を登録しました", len(courses))
+						if psrv != nil {
+							s.refreshers.Add(1)
+							go func() {
+								defer s.refreshers.Done()
+								receivePush(s.ctx, psrv, subscription, s.wakeCourse)
+							}()
+						}
+					}
+				}
+			}
+			// 登録するまではログインやコースの取得を待つため短い間隔で試す
+			wait := pushRenewInterval
+			if !registered {
+				wait = pushRetryInterval
+			}
+			if sleepContext(s.ctx, wait) != nil {
+				return
+			}
+		}
+	}()
+}
+
+// wakeCourse はコースを含むアカウントに課題を取得し直させます。まだ取得していないアカウントは run が取得するため対象にしません。
+func (s *server) wakeCourse(courseID string) {
+	for _, a := range s.allAccounts() {
+		v, ok := a.cache.get(a.key)
+		if !ok {
+			continue
+		}
+		for _, c := range v.Courses {
+			if c.Id == courseID {
+				a.wakeUp()
+				break
+			}
+		}
+	}
+}
+
+// pushEnvelope はPub/Subのプッシュ型のサブスクリプションが送る本文です。
+type pushEnvelope struct {
+	Message      *pubsub.PubsubMessage `json:"message"`
+	Subscription string                `json:"subscription"`
+}
+
+// handlePush はPub/Subのプッシュ配信を受け取り、通知のあったコースを含むアカウントに課題を取得し直させます。
+// 取得し直して見つかった変更は /ws と /events、server.notify が有効な場合は通知の送信先に送られます。
+// 2xx を返すと配信は確認済みになり、それ以外はPub/Subが再送します。
+func (s *server) handlePush(w http.ResponseWriter, r *http.Request) {
+	if err := s.verifyPush(r); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var env pushEnvelope
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&env); err != nil {
+		http.Error(w, "Pub/Subの配信を解析できませんでした: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := decodePushNotification(env.Message)
+	if err != nil {
+		// 再送しても解析できないため、ログに書いて確認済みにする
+		log.Print(err)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.wakeCourse(n.ResourceID.CourseID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verifyPush は設定に従って配信のトークンとOIDCトークンを確かめます。
+func (s *server) verifyPush(r *http.Request) error {
+	pc := s.cfg.Push
+	if pc.Token != "" && subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(pc.Token)) != 1 {
+		return errors.New("トークンが一致しません")
+	}
+	if pc.Audience == "" {
+		return nil
+	}
+	raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return errors.New("OIDCトークンがありません")
+	}
+	p, err := idtoken.Validate(r.Context(), raw, pc.Audience)
+	if err != nil {
+		return fmt.Errorf("OIDCトークンを確認できませんでした: %w", err)
+	}
+	if pc.ServiceAccount != "" && (p.Claims["email"] != pc.ServiceAccount || p.Claims["email_verified"] != true) {
+		return errors.New("OIDCトークンのサービスアカウントが一致しません")
+	}
+	return nil
+}
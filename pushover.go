@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+const (
+	pushoverAPI = "https://api.pushover.net/1"
+	// pushoverMaxTitle と pushoverMaxMessage はタイトルと本文の最大の文字数です。
+	pushoverMaxTitle   = 250
+	pushoverMaxMessage = 1024
+	// pushoverRetry と pushoverExpire は優先度 2 (緊急) の通知を確認されるまで繰り返す間隔と期間の秒数です。
+	pushoverRetry  = 300
+	pushoverExpire = 3600
+)
+
+// PushoverConfig はPushoverでのプッシュ通知の設定です。
+type PushoverConfig struct {
+	// Token はアプリケーションのAPIトークンです。省略した場合は環境変数 PUSHOVER_TOKEN を使います。
+	Token string `json:"token,omitempty"`
+	// User は送り先のユーザーキーまたはグループキーです。省略した場合は環境変数 PUSHOVER_USER を使います。
+	User string `json:"user,omitempty"`
+	// Device は送り先の端末の名前です。省略した場合はすべての端末に送ります。
+	Device string `json:"device,omitempty"`
+	// Events は送るできごとの種類です。省略した場合は新しい課題、締切の変更、締切が近い課題、毎日のまとめ、
+	// リマインダー、授業後のまとめを送ります。
+	Events []string `json:"events,omitempty"`
+	// Priorities はできごとの種類ごとの優先度 (-2〜2) です。省略した種類は、締切が近い課題 (due_soon) が 1、
+	// ほかは 0 です。2 (緊急) の場合は確認するまで5分ごとに1時間通知を繰り返します。
+	Priorities map[string]int `json:"priorities,omitempty"`
+}
+
+// defaultPushoverEvents は PushoverConfig.Events を省略したときに送るできごとです。
+var defaultPushoverEvents = []string{"created", "due_changed", "due_soon", "daily_digest", "reminder", "digest"}
+
+// defaultPushoverPriorities は PushoverConfig.Priorities を省略した種類の優先度です。
+var defaultPushoverPriorities = map[string]int{"due_soon": 1}
+
+// pushoverNotifier は通知をPushoverで送ります。
+type pushoverNotifier struct {
+	cfg  PushoverConfig
+	rest *restClient
+}
+
+func newPushoverNotifier(cfg PushoverConfig) *pushoverNotifier {
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("PUSHOVER_TOKEN")
+	}
+	if cfg.User == "" {
+		cfg.User = os.Getenv("PUSHOVER_USER")
+	}
+	if len(cfg.Events) == 0 {
+		cfg.Events = defaultPushoverEvents
+	}
+	return &pushoverNotifier{cfg: cfg, rest: &restClient{name: "Pushover", base: pushoverAPI, client: &http.Client{Timeout: webhookTimeout}}}
+}
+
+func (n *pushoverNotifier) Name() string { return "pushover" }
+
+func (n *pushoverNotifier) accepts(typ string) bool { return acceptsEvent(n.cfg.Events, typ) }
+
+func (n *pushoverNotifier) Notify(ctx context.Context, ev *Event) error {
+	if !n.accepts(ev.Type) {
+		return nil
+	}
+	if n.cfg.Token == "" || n.cfg.User == "" {
+		return errors.New("PushoverのAPIトークンとユーザーキーを設定してください (notify.pushover.token と user、または環境変数 PUSHOVER_TOKEN と PUSHOVER_USER)")
+	}
+	priority := n.priority(ev.Type)
+	if priority < -2 || priority > 2 {
+		return fmt.Errorf("Pushoverの優先度は -2 から 2 です: %d", priority)
+	}
+	msg := map[string]any{
+		"token":    n.cfg.Token,
+		"user":     n.cfg.User,
+		"title":    truncateRunes(ev.Title, pushoverMaxTitle),
+		"message":  truncateRunes(ev.Body, pushoverMaxMessage),
+		"priority": priority,
+	}
+	if ev.Body == "" {
+		// 本文は必須のため、タイトルを本文にも使う
+		msg["message"] = msg["title"]
+	}
+	if priority == 2 {
+		msg["retry"], msg["expire"] = pushoverRetry, pushoverExpire
+	}
+	if n.cfg.Device != "" {
+		msg["device"] = n.cfg.Device
+	}
+	if a := ev.Assignment; a != nil && a.CourseWork.AlternateLink != "" {
+		msg["url"], msg["url_title"] = a.CourseWork.AlternateLink, "Classroomで開く"
+	}
+	return n.rest.do(ctx, http.MethodPost, "/messages.json", msg, nil)
+}
+
+// priority はできごとの種類の優先度を返します。
+func (n *pushoverNotifier) priority(typ string) int {
+	if p, ok := n.cfg.Priorities[typ]; ok {
+		return p
+	}
+	return defaultPushoverPriorities[typ]
+}
+
+// truncateRunes は s が max 文字を超える場合に切り詰め、末尾を「…」にします。
+func truncateRunes(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max-1]) + "…"
+	}
+	return s
+}
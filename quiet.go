@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// quietStateFile は静かな時間にためた通知を記録するファイルです。再起動してもためた通知を失わないために使います。
+const quietStateFile = "quiet.json"
+
+// QuietHoursConfig は通知を送らない時間帯の設定です。静かな時間の通知はためておき、
+// 終わった後に送信先ごとに1つのまとめ (quiet_digest) にして送ります。
+type QuietHoursConfig struct {
+	// Start と End は静かな時間の始まりと終わりの時刻 ("23:00" の形式) です。Start が End より遅い場合は日をまたぎます。
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Weekdays は静かな時間が始まる曜日です ("月" または "mon" の形式)。省略した場合は毎日です。
+	Weekdays []string `json:"weekdays,omitempty"`
+	// Sinks は対象の送信先の名前 (slack, line, desktop など) です。省略した場合はすべての送信先です。
+	Sinks []string `json:"sinks,omitempty"`
+	// Allow は静かな時間でもすぐに送るできごとの種類です (例: ["due_soon"])。
+	Allow []string `json:"allow,omitempty"`
+	// Drop が true の場合は静かな時間の通知をためずに捨てます。
+	Drop bool `json:"drop,omitempty"`
+}
+
+// quietRule は解析した静かな時間の設定です。start と end は0時からの経過時間です。
+type quietRule struct {
+	start, end time.Duration
+	// weekdays は静かな時間が始まる曜日です。nil の場合は毎日です。
+	weekdays map[time.Weekday]bool
+	sinks    []string
+	allow    []string
+	drop     bool
+}
+
+// parseQuietHours は notify.quietHours を解析します。
+func parseQuietHours(qcs []QuietHoursConfig) ([]*quietRule, error) {
+	var rules []*quietRule
+	for i, qc := range qcs {
+		r := &quietRule{sinks: qc.Sinks, allow: qc.Allow, drop: qc.Drop}
+		for _, f := range []struct {
+			dst  *time.Duration
+			text string
+			name string
+		}{{&r.start, qc.Start, "start"}, {&r.end, qc.End, "end"}} {
+			t, err := time.Parse("15:04", f.text)
+			if err != nil {
+				return nil, fmt.Errorf("notify.quietHours[%d].%s の時刻を解析できませんでした: %s", i, f.name, f.text)
+			}
+			*f.dst = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		}
+		if r.start == r.end {
+			return nil, fmt.Errorf("notify.quietHours[%d] の start と end が同じです", i)
+		}
+		for _, w := range qc.Weekdays {
+			wd, ok := weekdayNames[strings.ToLower(strings.TrimSuffix(w, "曜日"))]
+			if !ok {
+				return nil, fmt.Errorf("notify.quietHours[%d].weekdays の曜日を解析できませんでした: %s", i, w)
+			}
+			if r.weekdays == nil {
+				r.weekdays = map[time.Weekday]bool{}
+			}
+			r.weekdays[wd] = true
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// active は t が静かな時間に含まれるかどうかを返します。
+func (r *quietRule) active(t time.Time) bool {
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	tod := t.Sub(midnight)
+	startsOn := func(day time.Time) bool { return r.weekdays == nil || r.weekdays[day.Weekday()] }
+	if r.start < r.end {
+		return startsOn(t) && tod >= r.start && tod < r.end
+	}
+	// 日をまたぐ場合は、今日始まった静かな時間か、前の日に始まった静かな時間の続き
+	return (startsOn(t) && tod >= r.start) || (startsOn(midnight.AddDate(0, 0, -1)) && tod < r.end)
+}
+
+// eventFilter は送るできごとの種類を選べる送信先です。
+type eventFilter interface {
+	accepts(typ string) bool
+}
+
+// acceptsEvent は送信先の設定の events に typ が含まれるかどうかを返します。
+// テストの通知と静かな時間のまとめはいつも送ります (まとめには送信先が送る種類のできごとだけが入ります)。
+func acceptsEvent(events []string, typ string) bool {
+	return typ == "test" || typ == "quiet_digest" || slices.Contains(events, typ)
+}
+
+// quietMu はプロセス内で quiet.json の読み書きを1つずつにします。
+var quietMu sync.Mutex
+
+// quietState は送信先の名前ごとの、静かな時間にためた通知です。
+type quietState struct {
+	Queued map[string][]*queuedEvent `json:"queued"`
+}
+
+// queuedEvent はためた通知です。
+type queuedEvent struct {
+	Type       string      `json:"type"`
+	Key        string      `json:"key,omitempty"`
+	Title      string      `json:"title"`
+	Body       string      `json:"body"`
+	Time       time.Time   `json:"time"`
+	Assignment *Assignment `json:"assignment,omitempty"`
+	Previous   *Assignment `json:"previous,omitempty"`
+}
+
+// quietNotifier は静かな時間の通知をためておき、静かな時間が終わった後にまとめて送る送信先です。
+type quietNotifier struct {
+	Notifier
+	// id は記録に使う送信先の名前です。
+	id     string
+	rules  []*quietRule
+	filter eventFilter
+	path   string
+	dryRun bool
+}
+
+// newQuietNotifier は n に当てはまる静かな時間があれば n を quietNotifier で包みます。filter は n の元の送信先です。
+func newQuietNotifier(cfg *Config, n Notifier, id string, filter Notifier) Notifier {
+	var rules []*quietRule
+	for _, r := range cfg.Notify.quietHours {
+		if len(r.sinks) == 0 || slices.Contains(r.sinks, filter.Name()) {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return n
+	}
+	q := &quietNotifier{Notifier: n, id: id, rules: rules, path: cfg.dataPath(quietStateFile), dryRun: cfg.dryRun}
+	q.filter, _ = filter.(eventFilter)
+	return q
+}
+
+// rule は t に有効な静かな時間のうち、typ のできごとをすぐに送らないものを返します。なければ nil です。
+func (n *quietNotifier) rule(typ string, t time.Time) *quietRule {
+	for _, r := range n.rules {
+		if r.active(t) && !slices.Contains(r.allow, typ) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (n *quietNotifier) Notify(ctx context.Context, ev *Event) error {
+	if ev.Type == "test" || (n.filter != nil && !n.filter.accepts(ev.Type)) {
+		return n.Notifier.Notify(ctx, ev)
+	}
+	r := n.rule(ev.Type, ev.Time)
+	switch {
+	case r == nil:
+		// 静かな時間が終わっていれば、ためた通知を先に送る
+		if err := n.flush(ctx, ev.Time); err != nil {
+			log.Print(err)
+		}
+		return n.Notifier.Notify(ctx, ev)
+	case r.drop:
+		return nil
+	}
+	return n.update(func(st *quietState) bool {
+		st.Queued[n.id] = append(st.Queued[n.id], &queuedEvent{
+			Type: ev.Type, Key: ev.Key, Title: ev.Title, Body: ev.Body, Time: ev.Time,
+			Assignment: ev.Assignment, Previous: ev.Previous,
+		})
+		return true
+	})
+}
+
+// flush は静かな時間が終わっていれば、ためた通知を送ります。2件以上の場合は1つのまとめにします。
+func (n *quietNotifier) flush(ctx context.Context, now time.Time) error {
+	if n.rule("", now) != nil {
+		return nil
+	}
+	var queued []*queuedEvent
+	err := n.update(func(st *quietState) bool {
+		queued = st.Queued[n.id]
+		delete(st.Queued, n.id)
+		return len(queued) > 0
+	})
+	if err != nil || len(queued) == 0 {
+		return err
+	}
+	var ev *Event
+	if len(queued) == 1 {
+		q := queued[0]
+		ev = &Event{Type: q.Type, Key: q.Key, Title: q.Title, Body: q.Body, Time: q.Time, Assignment: q.Assignment, Previous: q.Previous}
+	} else {
+		ev = quietDigest(queued, now)
+	}
+	if err := n.Notifier.Notify(ctx, ev); err != nil {
+		// 送れなかった通知は次の機会に送り直す
+		if err := n.update(func(st *quietState) bool { st.Queued[n.id] = append(queued, st.Queued[n.id]...); return true }); err != nil {
+			log.Print(err)
+		}
+		return fmt.Errorf("%s に静かな時間の通知を送れませんでした: %w", n.Name(), err)
+	}
+	return nil
+}
+
+// quietDigest は静かな時間にためた通知を1つのまとめにします。
+func quietDigest(queued []*queuedEvent, now time.Time) *Event {
+	ev := &Event{Type: "quiet_digest", Time: now, Title: fmt.Sprintf("静かな時間の通知 (%d件)", len(queued))}
+	var lines []string
+	seen := map[string]bool{}
+	for _, q := range queued {
+		lines = append(lines, fmt.Sprintf("・%s %s", q.Time.Format("01/02 15:04"), q.Title))
+		if a := q.Assignment; a != nil && !seen[assignmentKey(a)] {
+			seen[assignmentKey(a)] = true
+			ev.Items = append(ev.Items, a)
+		}
+	}
+	ev.Body = strings.Join(lines, "\n")
+	return ev
+}
+
+// update は quiet.json を読み込んで f で書き換え、f が true を返した場合は保存します。
+func (n *quietNotifier) update(f func(st *quietState) bool) error {
+	quietMu.Lock()
+	defer quietMu.Unlock()
+	st := &quietState{}
+	if err := readJSONFile(n.path, st); err != nil {
+		return fmt.Errorf("%s を読み取れませんでした: %w", quietStateFile, err)
+	}
+	if st.Queued == nil {
+		st.Queued = map[string][]*queuedEvent{}
+	}
+	if !f(st) || n.dryRun {
+		return nil
+	}
+	if err := writeJSONFile(n.path, st); err != nil {
+		return fmt.Errorf("%s を保存できませんでした: %w", quietStateFile, err)
+	}
+	return nil
+}
+
+// flushQuiet は静かな時間が終わった送信先にためた通知を送ります。watch、serve、daemon が課題を取得するたびに呼び出します。
+func flushQuiet(ctx context.Context, ns []Notifier, now time.Time) {
+	for _, n := range ns {
+		if d, ok := n.(*dedupNotifier); ok {
+			n = d.Notifier
+		}
+		if q, ok := n.(*quietNotifier); ok {
+			if err := q.flush(ctx, now); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQuietRuleActive(t *testing.T) {
+	// 2026-10-16 は金曜日
+	at := func(d, h, m int) time.Time { return time.Date(2026, 10, d, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		name string
+		qc   QuietHoursConfig
+		t    time.Time
+		want bool
+	}{
+		{"日中の静かな時間の中", QuietHoursConfig{Start: "12:00", End: "13:00"}, at(16, 12, 30), true},
+		{"日中の静かな時間の始まり", QuietHoursConfig{Start: "12:00", End: "13:00"}, at(16, 12, 0), true},
+		{"日中の静かな時間の終わり", QuietHoursConfig{Start: "12:00", End: "13:00"}, at(16, 13, 0), false},
+		{"日中の静かな時間の前", QuietHoursConfig{Start: "12:00", End: "13:00"}, at(16, 11, 59), false},
+		{"日をまたぐ静かな時間の夜", QuietHoursConfig{Start: "23:00", End: "7:00"}, at(16, 23, 30), true},
+		{"日をまたぐ静かな時間の0時", QuietHoursConfig{Start: "23:00", End: "7:00"}, at(17, 0, 0), true},
+		{"日をまたぐ静かな時間の朝", QuietHoursConfig{Start: "23:00", End: "7:00"}, at(17, 6, 59), true},
+		{"日をまたぐ静かな時間の終わり", QuietHoursConfig{Start: "23:00", End: "7:00"}, at(17, 7, 0), false},
+		{"日をまたぐ静かな時間の外", QuietHoursConfig{Start: "23:00", End: "7:00"}, at(16, 12, 0), false},
+		{"曜日が一致する", QuietHoursConfig{Start: "12:00", End: "13:00", Weekdays: []string{"金"}}, at(16, 12, 30), true},
+		{"曜日が一致しない", QuietHoursConfig{Start: "12:00", End: "13:00", Weekdays: []string{"sat"}}, at(16, 12, 30), false},
+		// 金曜日の夜に始まった静かな時間は土曜日の朝まで続く
+		{"始まった曜日の翌朝", QuietHoursConfig{Start: "23:00", End: "7:00", Weekdays: []string{"金曜日"}}, at(17, 6, 0), true},
+		{"始まっていない曜日の朝", QuietHoursConfig{Start: "23:00", End: "7:00", Weekdays: []string{"金曜日"}}, at(16, 6, 0), false},
+		{"始まっていない曜日の夜", QuietHoursConfig{Start: "23:00", End: "7:00", Weekdays: []string{"金曜日"}}, at(17, 23, 0), false},
+	}
+	for _, tt := range tests {
+		rules, err := parseQuietHours([]QuietHoursConfig{tt.qc})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := rules[0].active(tt.t); got != tt.want {
+			t.Errorf("%s: active(%s) = %v, want %v", tt.name, tt.t.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestParseQuietHoursError(t *testing.T) {
+	for _, qc := range []QuietHoursConfig{
+		{Start: "", End: "7:00"},
+		{Start: "23:00", End: "25:00"},
+		{Start: "7:00", End: "7:00"},
+		{Start: "23:00", End: "7:00", Weekdays: []string{"someday"}},
+	} {
+		if _, err := parseQuietHours([]QuietHoursConfig{qc}); err == nil {
+			t.Errorf("parseQuietHours(%+v) はエラーになるはずです", qc)
+		}
+	}
+}
+
+func TestQuietNotifier(t *testing.T) {
+	rules, err := parseQuietHours([]QuietHoursConfig{{Start: "23:00", End: "7:00", Allow: []string{"due_soon"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{DataDir: t.TempDir()}
+	cfg.Notify.quietHours = rules
+	rec := &recordingNotifier{}
+	n := newQuietNotifier(cfg, rec, "rec", rec)
+	ctx := context.Background()
+	night := time.Date(2026, 10, 16, 23, 30, 0, 0, time.UTC)
+	for _, ev := range []*Event{
+		{Type: "new_assignment", Title: "課題1", Time: night},
+		{Type: "due_soon", Title: "締切", Time: night},
+		{Type: "new_assignment", Title: "課題2", Time: night.Add(time.Hour)},
+	} {
+		if err := n.Notify(ctx, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Allow のできごとだけがすぐに送られる
+	if got := eventTitles(rec.events); len(got) != 1 || got[0] != "締切" {
+		t.Fatalf("静かな時間に送った通知 = %v, want [締切]", got)
+	}
+	flushQuiet(ctx, []Notifier{n}, night.Add(2*time.Hour))
+	if len(rec.events) != 1 {
+		t.Fatalf("静かな時間の中でためた通知を送りました: %v", eventTitles(rec.events))
+	}
+	morning := time.Date(2026, 10, 17, 7, 0, 0, 0, time.UTC)
+	flushQuiet(ctx, []Notifier{n}, morning)
+	if len(rec.events) != 2 || rec.events[1].Type != "quiet_digest" {
+		t.Fatalf("静かな時間の後に送った通知 = %v, want まとめ1件", eventTitles(rec.events))
+	}
+	if want := "静かな時間の通知 (2件)"; rec.events[1].Title != want {
+		t.Errorf("まとめのタイトル = %q, want %q", rec.events[1].Title, want)
+	}
+	flushQuiet(ctx, []Notifier{n}, morning.Add(time.Hour))
+	if len(rec.events) != 2 {
+		t.Errorf("送ったまとめをもう一度送りました: %v", eventTitles(rec.events))
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig はローカルAPIのリクエスト数の制限です。
+// クライアントはIPアドレスで区別します。Clients に書いたBearerトークンを送るクライアントだけはトークンで区別します。
+type RateLimitConfig struct {
+	// Disabled が true の場合はリクエスト数を制限しません。
+	Disabled bool `json:"disabled,omitempty"`
+	// Rate は1秒あたりに許可するリクエスト数です。省略した場合は 2 です。
+	Rate float64 `json:"rate"`
+	// Burst は一度に許可するリクエスト数の上限です。省略した場合は 10 です。
+	Burst int `json:"burst"`
+	// Quota は QuotaWindow の間に許可するリクエスト数です。0 の場合は制限しません。
+	Quota       int      `json:"quota"`
+	QuotaWindow Duration `json:"quotaWindow"`
+	// Clients はIPアドレスまたはトークンごとに異なる制限を設定します。
+	Clients map[string]*ClientLimit `json:"clients,omitempty"`
+}
+
+// ClientLimit はクライアントごとの制限です。0 の項目は全体の設定を使います。
+type ClientLimit struct {
+	Rate  float64 `json:"rate,omitempty"`
+	Burst int     `json:"burst,omitempty"`
+	Quota int     `json:"quota,omitempty"`
+}
+
+func (c *RateLimitConfig) setDefaults() {
+	if c.Rate == 0 {
+		c.Rate = 2
+	}
+	if c.Burst == 0 {
+		c.Burst = 10
+	}
+	if c.QuotaWindow == 0 {
+		c.QuotaWindow = Duration(time.Hour)
+	}
+}
+
+// rateLimiter はクライアントごとのトークンバケットと割り当て数でリクエストを制限します。
+type rateLimiter struct {
+	cfg     RateLimitConfig
+	mu      sync.Mutex
+	clients map[string]*clientState
+	// pruned は使われなくなったクライアントを最後に削除した時刻です。
+	pruned time.Time
+}
+
+type clientState struct {
+	tokens      float64
+	last        time.Time
+	windowStart time.Time
+	count       int
+	// seen は最後にリクエストを受けた時刻です。
+	seen time.Time
+}
+
+// rateLimitPruneInterval は使われなくなったクライアントの状態を削除する間隔です。
+const rateLimitPruneInterval = 10 * time.Minute
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	return &rateLimiter{cfg: cfg, clients: map[string]*clientState{}}
+}
+
+// limits はクライアントに適用する制限を返します。
+func (l *rateLimiter) limits(key string) (rate float64, burst, quota int) {
+	rate, burst, quota = l.cfg.Rate, l.cfg.Burst, l.cfg.Quota
+	if c, ok := l.cfg.Clients[key]; ok {
+		if c.Rate != 0 {
+			rate = c.Rate
+		}
+		if c.Burst != 0 {
+			burst = c.Burst
+		}
+		if c.Quota != 0 {
+			quota = c.Quota
+		}
+	}
+	return
+}
+
+// allow はクライアントのリクエストを許可するかどうかを返します。
+// 許可しない場合は、次に許可できるようになるまでの時間も返します。
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	if l.cfg.Disabled {
+		return true, 0
+	}
+	rate, burst, quota := l.limits(key)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+	c, ok := l.clients[key]
+	if !ok {
+		c = &clientState{tokens: float64(burst), last: now, windowStart: now}
+		l.clients[key] = c
+	}
+	c.seen = now
+	if quota > 0 {
+		window := time.Duration(l.cfg.QuotaWindow)
+		if now.Sub(c.windowStart) >= window {
+			c.windowStart, c.count = now, 0
+		}
+		if c.count >= quota {
+			return false, c.windowStart.Add(window).Sub(now)
+		}
+	}
+	if rate > 0 {
+		c.tokens += now.Sub(c.last).Seconds() * rate
+		if c.tokens > float64(burst) {
+			c.tokens = float64(burst)
+		}
+		c.last = now
+		if c.tokens < 1 {
+			return false, time.Duration((1 - c.tokens) / rate * float64(time.Second))
+		}
+		c.tokens--
+	}
+	c.count++
+	return true, 0
+}
+
+// prune は割り当て数の期間より長くリクエストのないクライアントの状態を削除します。
+// その間にトークンバケットも満杯に戻るため、削除しても制限は変わりません。
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < rateLimitPruneInterval {
+		return
+	}
+	l.pruned = now
+	idle := time.Duration(l.cfg.QuotaWindow)
+	if l.cfg.Rate > 0 {
+		idle = max(idle, time.Duration(float64(l.cfg.Burst)/l.cfg.Rate*float64(time.Second)))
+	}
+	for key, c := range l.clients {
+		if _, ok := l.cfg.Clients[key]; !ok && now.Sub(c.seen) > idle {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// middleware は制限を超えたリクエストに 429 Too Many Requests と Retry-After を返します (死活監視とPub/Subの配信を除く)。
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz", "/readyz", "/pubsub/push":
+			// 死活監視とPub/Subの配信は利用者のリクエストではなく、429 を返すと再起動や再送を招く
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ok, wait := l.allow(l.clientKey(r), time.Now()); !ok {
+			w.Header().Set("Retry-After", retryAfter(wait))
+			http.Error(w, "リクエストが多すぎます", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// retryAfter は Retry-After に書く、次に許可できるようになるまでの秒数です。1秒未満は1秒に切り上げます。
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(max(wait, time.Second).Seconds())))
+}
+
+// clientKey はリクエストを送ったクライアントを識別するキーを返します。
+// 任意のトークンを送るだけで別のクライアントとして扱われないよう、トークンで区別するのは Clients に書いたものだけです。
+func (l *rateLimiter) clientKey(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && token != "" {
+		if _, ok := l.cfg.Clients[token]; ok {
+			return token
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cfg := RateLimitConfig{Burst: 3}
+	cfg.setDefaults()
+	l := newRateLimiter(cfg)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("%d 件目のリクエストは Burst の範囲内です", i+1)
+		}
+	}
+	ok, wait := l.allow("a", now)
+	if ok {
+		t.Fatal("Burst を超えたリクエストを許可しました")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("wait = %s, want 500ms", wait)
+	}
+	// ほかのクライアントは制限されない
+	if ok, _ := l.allow("b", now); !ok {
+		t.Error("ほかのクライアントのリクエストを制限しました")
+	}
+	// 1秒あたり2件ずつ戻る
+	if ok, _ := l.allow("a", now.Add(500*time.Millisecond)); !ok {
+		t.Error("トークンが戻った後のリクエストを制限しました")
+	}
+	if ok, _ := l.allow("a", now.Add(500*time.Millisecond)); ok {
+		t.Error("戻ったトークンより多くのリクエストを許可しました")
+	}
+}
+
+func TestRateLimiterQuota(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cfg := RateLimitConfig{Rate: 100, Burst: 100, Quota: 2, Clients: map[string]*ClientLimit{"token": {Quota: 3}}}
+	cfg.setDefaults()
+	l := newRateLimiter(cfg)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("%d 件目のリクエストは割り当て数の範囲内です", i+1)
+		}
+	}
+	ok, wait := l.allow("a", now.Add(10*time.Minute))
+	if ok {
+		t.Fatal("割り当て数を超えたリクエストを許可しました")
+	}
+	if wait != 50*time.Minute {
+		t.Errorf("wait = %s, want 50m", wait)
+	}
+	if ok, _ := l.allow("a", now.Add(time.Hour)); !ok {
+		t.Error("次の期間のリクエストを制限しました")
+	}
+	// Clients の設定は全体の設定より優先する
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("token", now); !ok {
+			t.Fatalf("%d 件目のリクエストはクライアントの割り当て数の範囲内です", i+1)
+		}
+	}
+	if ok, _ := l.allow("token", now); ok {
+		t.Error("クライアントの割り当て数を超えたリクエストを許可しました")
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cfg := RateLimitConfig{Disabled: true, Burst: 1, Quota: 1}
+	cfg.setDefaults()
+	l := newRateLimiter(cfg)
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatal("制限しない設定でリクエストを制限しました")
+		}
+	}
+}
+
+func TestRateLimiterClientKey(t *testing.T) {
+	l := newRateLimiter(RateLimitConfig{Clients: map[string]*ClientLimit{"known": {}}})
+	tests := []struct {
+		auth string
+		want string
+	}{
+		{"", "192.0.2.1"},
+		{"Bearer known", "known"},
+		// Clients にないトークンではIPアドレスで区別する
+		{"Bearer unknown", "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		if tt.auth != "" {
+			r.Header.Set("Authorization", tt.auth)
+		}
+		if got := l.clientKey(r); got != tt.want {
+			t.Errorf("clientKey(%q) = %q, want %q", tt.auth, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimiterMiddlewareExempt(t *testing.T) {
+	cfg := RateLimitConfig{Rate: 1, Burst: 1}
+	cfg.setDefaults()
+	h := newRateLimiter(cfg).middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/coursework", http.StatusOK},
+		{"/api/coursework", http.StatusTooManyRequests},
+		{"/healthz", http.StatusOK},
+		{"/readyz", http.StatusOK},
+		{"/pubsub/push", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", tt.path, nil)
+		h.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+}
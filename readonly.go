@@ -0,0 +1,59 @@
+package main
+
+import (
+	"classroom-api/classroompb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	"net/http"
+)
+
+// mutatingCommands はClassroomのデータを変更するコマンドです。
+// 読み取り専用モードではディスパッチの時点で実行を拒否します。
+// 課題を変更するコマンドを追加した場合はここにも追加してください。
+var mutatingCommands = map[string]bool{
+	"e2e": true,
+}
+
+// checkReadOnly は読み取り専用モードで変更を伴うコマンドが指定された場合に終了します。
+// name はサブコマンドを含むコマンド名です (例: "coursework turnin")。
+func checkReadOnly(cfg *Config, name string) {
+	if cfg.ReadOnly && mutatingCommands[name] {
+		log.Fatalf("読み取り専用モードでは %s は使えません", name)
+	}
+}
+
+// readOnlyMiddleware は読み取り専用モードで GET と HEAD 以外のリクエストを拒否します (ログアウトと /graphql を除く)。
+// 個々のハンドラーの実装に関係なく、変更を伴うリクエストがハンドラーまで届かないようにします。
+func readOnlyMiddleware(cfg *Config, next http.Handler) http.Handler {
+	if !cfg.ReadOnly {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet, r.Method == http.MethodHead:
+			next.ServeHTTP(w, r)
+		case r.URL.Path == "/logout", r.URL.Path == "/graphql", r.URL.Path == "/pubsub/push":
+			// ログアウトとPub/Subの配信はClassroomのデータを変更せず、/graphql のスキーマには Mutation がない
+			next.ServeHTTP(w, r)
+		default:
+			http.Error(w, "読み取り専用モードのため変更できません", http.StatusForbidden)
+		}
+	})
+}
+
+// grpcReadOnlyMethods は読み取り専用モードでも使える gRPC のメソッドです。
+// HTTPの GET と同じくClassroomのデータを変更しないメソッドだけを書きます。ここにないメソッドは読み取り専用モードでは拒否します。
+var grpcReadOnlyMethods = map[string]bool{
+	classroompb.CourseWorkService_ListCourses_FullMethodName:           true,
+	classroompb.CourseWorkService_ListPendingCourseWork_FullMethodName: true,
+	classroompb.CourseWorkService_WatchCourseWork_FullMethodName:       true,
+}
+
+// checkGRPCReadOnly は読み取り専用モードで変更を伴うメソッドの呼び出しを拒否します。
+func checkGRPCReadOnly(cfg *Config, method string) error {
+	if cfg.ReadOnly && !grpcReadOnlyMethods[method] {
+		return status.Error(codes.PermissionDenied, "読み取り専用モードのため変更できません")
+	}
+	return nil
+}
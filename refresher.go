@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/api/classroom/v1"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultServerRefresh はバックグラウンドで課題を取得し直す既定の間隔です。
+	defaultServerRefresh = 5 * time.Minute
+	// defaultServerCacheTTL は取得済みの結果を使う既定の期限です。
+	defaultServerCacheTTL = 15 * time.Minute
+)
+
+// cachedAccount はアカウントの取得結果です。
+type cachedAccount struct {
+	Items   []*Assignment
+	Courses []*classroom.Course
+	Fetched time.Time
+}
+
+// serverCache はサーバーがアカウントごとの取得結果を保存する先です。
+// 既定はメモリですが、複数のサーバーで共有する場合などはこのインターフェースを実装して差し替えられます。
+type serverCache interface {
+	get(key string) (*cachedAccount, bool)
+	put(key string, v *cachedAccount)
+	remove(key string)
+}
+
+// memoryCache はメモリに保存する serverCache です。
+type memoryCache struct {
+	mu sync.Mutex
+	m  map[string]*cachedAccount
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{m: map[string]*cachedAccount{}}
+}
+
+func (c *memoryCache) get(key string) (*cachedAccount, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.m[key]
+	return v, ok
+}
+
+func (c *memoryCache) put(key string, v *cachedAccount) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[key] = v
+}
+
+func (c *memoryCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.m, key)
+}
+
+// startAccount はアカウントの取得結果の保存先を設定し、バックグラウンドでの取得を始めます。
+// 取得は s.ctx が取り消されると止まります。
+func (s *server) startAccount(a *account, key string) {
+	a.cache, a.key = s.cache, key
+	a.wake = make(chan struct{}, 1)
+	if s.cfg.Server.Notify {
+		a.notifiers = newNotifiers(s.cfg)
+		// 送った通知の記録はユーザーごとに分ける
+		stateFile := remindersStateFile
+		if key != "" {
+			stateFile = "reminders-" + key + ".json"
+		}
+		var err error
+		if a.alerts, err = newAlerter(s.cfg, stateFile); err != nil {
+			log.Print(err)
+		}
+	}
+	a.ttl = time.Duration(s.cfg.Server.CacheTTL)
+	if a.ttl <= 0 {
+		a.ttl = defaultServerCacheTTL
+	}
+	interval := time.Duration(s.cfg.Server.Refresh)
+	if interval <= 0 {
+		interval = defaultServerRefresh
+	}
+	s.refreshers.Add(1)
+	go func() {
+		defer s.refreshers.Done()
+		a.run(s.ctx, interval)
+	}()
+}
+
+// run はすぐに1回、その後は interval ごとと wakeUp が呼ばれたときに課題を取得し直します。ログインしていない間は取得しません。
+func (a *account) run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if a.tokens == nil || a.tokens.loggedIn() {
+			if _, err := a.refresh(ctx); err != nil && !errors.Is(err, errNotLoggedIn) && ctx.Err() == nil {
+				log.Printf("課題を取得し直せませんでした: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-a.wake:
+		}
+	}
+}
+
+// wakeUp は run に次の間隔を待たずに取得し直させます。すでに取得し直す予定の場合は何もしません。
+func (a *account) wakeUp() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// refresh は課題とコースを取得し直して保存し、前回との違いを /ws と /events に送ります。
+func (a *account) refresh(ctx context.Context) (v *cachedAccount, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if requestID(ctx) == "" {
+		// バックグラウンドでの取得にもIDを付け、APIの呼び出しのログと対応づけられるようにする
+		ctx = withRequestID(ctx, "refresh-"+newRequestID())
+	}
+	start := time.Now()
+	defer func() {
+		refreshLatency.observe(time.Since(start))
+		a.setStatus(start, err)
+	}()
+	courses, err := loadCourses(ctx, a.cfg, a.srv)
+	if err != nil {
+		return nil, err
+	}
+	items, err := loadAssignments(ctx, a.cfg, a.srv)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	v = &cachedAccount{Items: items, Courses: courses, Fetched: now}
+	a.cache.put(a.key, v)
+	// 最初の取得ではすべてが新しい課題になるため送らない
+	changes, seen := diffPending(a.seen, items, now)
+	if a.seen != nil && len(changes.Events) > 0 {
+		a.broadcast(changes)
+		a.notify(ctx, changes)
+	}
+	a.seen = seen
+	if a.alerts != nil {
+		a.alerts.deliver(ctx, a.notifiers, items, now)
+	}
+	flushQuiet(ctx, a.notifiers, now)
+	return v, nil
+}
+
+// setStatus は /readyz のために最後の取得の結果を記録します。
+func (a *account) setStatus(t time.Time, err error) {
+	a.statusMu.Lock()
+	defer a.statusMu.Unlock()
+	a.refreshed, a.refreshErr = t, err
+}
+
+// status は最後に取得した時刻とその結果を返します。まだ取得していない場合、時刻はゼロ値です。
+func (a *account) status() (time.Time, error) {
+	a.statusMu.Lock()
+	defer a.statusMu.Unlock()
+	return a.refreshed, a.refreshErr
+}
+
+// cached は期限内の取得結果を返します。なければ取得し直します。
+func (a *account) cached(ctx context.Context) (*cachedAccount, error) {
+	if v, ok := a.cache.get(a.key); ok && time.Since(v.Fetched) < a.ttl {
+		cacheLookups.inc("hit")
+		return v, nil
+	}
+	cacheLookups.inc("miss")
+	return a.refresh(ctx)
+}
+
+// assignments は課題の一覧を返します。
+func (a *account) assignments(ctx context.Context) ([]*Assignment, error) {
+	v, err := a.cached(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return v.Items, nil
+}
+
+// courseList はコースの一覧を返します。
+func (a *account) courseList(ctx context.Context) ([]*classroom.Course, error) {
+	v, err := a.cached(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return v.Courses, nil
+}
+
+// reset は保存した取得結果を捨て、次のリクエストで取得し直すようにします。
+func (a *account) reset() {
+	a.cache.remove(a.key)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+func runReport(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	days := fs.Int("days", 7, "今日から何日分の課題を載せるか")
+	out := fs.String("out", "", "出力ファイル (省略時は標準出力)")
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("出力ファイルを作成できませんでした: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeReport(w, filter.apply(items), *days, time.Now()); err != nil {
+		log.Fatalf("レポートを書き込めませんでした: %v", err)
+	}
+}
+
+// reportGroup は日またはコースでまとめた課題です。
+type reportGroup struct {
+	title string
+	items []*Assignment
+}
+
+// writeReport は締切切れの課題と、今日から days 日分の課題を日ごと・コースごとにMarkdownで書き出します。
+// 提出済みの課題は載せません。
+func writeReport(w io.Writer, items []*Assignment, days int, now time.Time) error {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	end := today.AddDate(0, 0, days)
+
+	var overdue []*Assignment
+	var byDay []*reportGroup
+	dayIndex := map[string]*reportGroup{}
+	for _, a := range items {
+		if s := a.State(); s == "TURNED_IN" || s == "RETURNED" {
+			continue
+		}
+		due, ok := a.EffectiveDue()
+		switch {
+		case !ok || !due.Before(end):
+			continue
+		case due.Before(now):
+			overdue = append(overdue, a)
+			continue
+		}
+		key := due.Format("2006-01-02")
+		g, ok := dayIndex[key]
+		if !ok {
+			g = &reportGroup{title: fmt.Sprintf("%s (%s)", due.Format("1/2"), weekdays[due.Weekday()])}
+			dayIndex[key] = g
+			byDay = append(byDay, g)
+		}
+		g.items = append(g.items, a)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 課題の予定 (%s 〜 %s)\n\n", today.Format("1/2"), end.AddDate(0, 0, -1).Format("1/2"))
+	if len(overdue) == 0 && len(byDay) == 0 {
+		b.WriteString("この期間に締切の課題はありません。\n")
+	}
+	if len(overdue) > 0 {
+		b.WriteString("## 締切切れ\n\n")
+		writeReportCourses(&b, overdue, "1/2 15:04")
+	}
+	for _, g := range byDay {
+		fmt.Fprintf(&b, "## %s\n\n", g.title)
+		writeReportCourses(&b, g.items, "15:04")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeReportCourses は課題をコースごとの箇条書きで書き出します。
+func writeReportCourses(b *strings.Builder, items []*Assignment, layout string) {
+	var groups []*reportGroup
+	index := map[string]*reportGroup{}
+	for _, a := range items {
+		g, ok := index[a.Course.Id]
+		if !ok {
+			g = &reportGroup{title: a.Course.Name}
+			index[a.Course.Id] = g
+			groups = append(groups, g)
+		}
+		g.items = append(g.items, a)
+	}
+	for _, g := range groups {
+		fmt.Fprintf(b, "### %s\n\n", markdownEscape(g.title))
+		for _, a := range g.items {
+			c := a.CourseWork
+			due, _ := a.EffectiveDue()
+			fmt.Fprintf(b, "- [ ] [%s](%s) — %s 締切", markdownEscape(c.Title), c.AlternateLink, due.Format(layout))
+			if c.MaxPoints > 0 {
+				fmt.Fprintf(b, " (%g点)", c.MaxPoints)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+}
+
+// markdownEscape はMarkdownの記法として解釈される文字をエスケープします。
+func markdownEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`", "<", `\<`, "#", `\#`)
+	return r.Replace(s)
+}
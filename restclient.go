@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// restClient はJSONでやり取りする外部サービスのREST APIのクライアントです。
+type restClient struct {
+	// name はエラーメッセージに使うサービスの名前です。
+	name  string
+	base  string
+	token string
+	// header はすべてのリクエストに付けるヘッダーです。
+	header map[string]string
+	client *http.Client
+}
+
+// restError はREST APIのエラー応答です。
+type restError struct {
+	Service string
+	Status  int
+	Body    string
+}
+
+func (e *restError) Error() string {
+	return fmt.Sprintf("%s APIがエラーを返しました (%d): %s", e.Service, e.Status, e.Body)
+}
+
+// do はAPIを呼び出します。body があればJSONで送り、out があれば応答をJSONとして読み込みます。
+func (c *restClient) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, r)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	for k, v := range c.header {
+		req.Header.Set(k, v)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := c.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return &restError{Service: c.name, Status: res.StatusCode, Body: string(b)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// isRESTNotFound は対象が削除されていたことを示すエラーかどうかを返します。
+func isRESTNotFound(err error) bool {
+	var e *restError
+	return errors.As(err, &e) && (e.Status == http.StatusNotFound || e.Status == http.StatusGone)
+}
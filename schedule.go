@@ -0,0 +1,113 @@
+package main
+
+import (
+	"google.golang.org/api/classroom/v1"
+	"time"
+)
+
+// courseScheduler はコースごとに次の取得時刻を決め、取得した課題を覚えておきます。
+// 締切が近い課題のあるコースは短い間隔で、未提出の課題がないコースは長い間隔で取得するため、
+// すべてのコースを同じ間隔で取得するより少ないAPI呼び出しで、必要なコースを新しく保てます。
+type courseScheduler struct {
+	polling *PollingConfig
+	mode    syncMode
+	// fixed が 0 でない場合はすべてのコースをこの間隔で取得します。
+	fixed time.Duration
+	items map[string][]*Assignment
+	next  map[string]time.Time
+}
+
+func newCourseScheduler(polling *PollingConfig, mode syncMode, fixed time.Duration) *courseScheduler {
+	return &courseScheduler{
+		polling: polling,
+		mode:    mode,
+		fixed:   fixed,
+		items:   map[string][]*Assignment{},
+		next:    map[string]time.Time{},
+	}
+}
+
+// due は取得の時刻になったコースを返します。まだ取得していないコースも含みます。
+func (s *courseScheduler) due(courses []*classroom.Course, now time.Time) []*classroom.Course {
+	var due []*classroom.Course
+	for _, c := range courses {
+		if next, ok := s.next[c.Id]; !ok || !now.Before(next) {
+			due = append(due, c)
+		}
+	}
+	return due
+}
+
+// store は取得したコースの課題を覚え、次の取得時刻を決めます。
+func (s *courseScheduler) store(courses []*classroom.Course, items []*Assignment, now time.Time) {
+	byCourse := make(map[string][]*Assignment, len(courses))
+	for _, a := range items {
+		byCourse[a.Course.Id] = append(byCourse[a.Course.Id], a)
+	}
+	for _, c := range courses {
+		s.items[c.Id] = byCourse[c.Id]
+		s.next[c.Id] = now.Add(s.interval(byCourse[c.Id], now))
+	}
+}
+
+// expire はコースを次の due で取得するようにします。プッシュ通知で変更を知らされたときに使います。
+func (s *courseScheduler) expire(courseID string) {
+	delete(s.next, courseID)
+}
+
+// expireAll はすべてのコースを次の due で取得するようにします。daemon の全件の同期で使います。
+func (s *courseScheduler) expireAll() {
+	clear(s.next)
+}
+
+// retry は取得に失敗したコースを短い間隔で取得し直すようにします。
+func (s *courseScheduler) retry(courses []*classroom.Course, now time.Time) {
+	for _, c := range courses {
+		s.next[c.Id] = now.Add(time.Duration(s.polling.Urgent))
+	}
+}
+
+// interval はコースの課題から次の取得までの間隔を決めます。
+func (s *courseScheduler) interval(items []*Assignment, now time.Time) time.Duration {
+	if s.fixed != 0 {
+		return s.fixed
+	}
+	pending := pendingAssignments(items, now)
+	d := s.polling.next(s.mode, now, pending)
+	if d == time.Duration(s.polling.Normal) && len(pending) == 0 {
+		return time.Duration(s.polling.Idle)
+	}
+	return d
+}
+
+// assignments は courses の覚えている課題を締切順に返します。在籍しなくなったコースは忘れます。
+func (s *courseScheduler) assignments(courses []*classroom.Course) []*Assignment {
+	current := make(map[string]bool, len(courses))
+	var items []*Assignment
+	for _, c := range courses {
+		current[c.Id] = true
+		items = append(items, s.items[c.Id]...)
+	}
+	for id := range s.items {
+		if !current[id] {
+			delete(s.items, id)
+			delete(s.next, id)
+		}
+	}
+	sortAssignments(items)
+	return items
+}
+
+// wait は次にいずれかのコースを取得するまでの時間を返します。
+func (s *courseScheduler) wait(now time.Time) time.Duration {
+	var first time.Time
+	for _, next := range s.next {
+		if first.IsZero() || next.Before(first) {
+			first = next
+		}
+	}
+	if first.IsZero() {
+		return time.Duration(s.polling.Normal)
+	}
+	return max(first.Sub(now), time.Second)
+}
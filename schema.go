@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// schemaDocs はスキーマを出力する出力形式です。
+// JSONを出力する形式を追加した場合はここにも追加してください。
+var schemaDocs = []struct {
+	name        string
+	description string
+	value       any
+}{
+	{"pending", "GET /api/pending の応答 (list -template のデータと同じ)", []*TemplateData{}},
+	{"pending-count", "GET /api/pending/count の応答", &ApiPendingCount{}},
+	{"pending-titles", "GET /api/pending/titles の応答", []*ApiPendingTitle{}},
+	{"courses", "GET /api/courses の応答", []*ApiCourse{}},
+	{"coursework", "GET /api/coursework と GET /api/courses/{id}/coursework の応答 (pending と同じ)", []*TemplateData{}},
+	{"keys-mint-request", "POST /api/keys の本文", &ApiMintKeyRequest{}},
+	{"keys-mint", "POST /api/keys の応答", &ApiMintKeyResponse{}},
+	{"health", "health コマンドが読む health.json", &Health{}},
+	{"stats", "export stats の出力", &StatsExport{}},
+	{"snapshot", "snapshot export の出力", &Snapshot{}},
+}
+
+func runSchema(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	list := fs.Bool("list", false, "スキーマを出力できる形式の一覧を表示します")
+	fs.Parse(args)
+
+	if *list {
+		for _, d := range schemaDocs {
+			fmt.Printf("%-18s %s\n", d.name, d.description)
+		}
+		return
+	}
+	var doc map[string]any
+	switch fs.NArg() {
+	case 0:
+		g := newSchemaGenerator()
+		props := map[string]any{}
+		for _, d := range schemaDocs {
+			s := g.schema(reflect.TypeOf(d.value))
+			s["description"] = d.description
+			props[d.name] = s
+		}
+		doc = map[string]any{"type": "object", "properties": props}
+		g.addDefs(doc)
+	case 1:
+		for _, d := range schemaDocs {
+			if d.name == fs.Arg(0) {
+				g := newSchemaGenerator()
+				doc = g.schema(reflect.TypeOf(d.value))
+				doc["description"] = d.description
+				g.addDefs(doc)
+			}
+		}
+		if doc == nil {
+			log.Fatalf("不明な形式です: %s (schema -list で一覧を表示)", fs.Arg(0))
+		}
+	default:
+		log.Fatal("形式は1つだけ指定してください")
+	}
+	doc["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// schemaGenerator はGoの型からJSON Schemaを作ります。
+// 名前のある構造体は $defs にまとめ、$ref で参照します。
+type schemaGenerator struct {
+	defs map[string]map[string]any
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{defs: map[string]map[string]any{}}
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(Duration(0))
+)
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]any{"type": "string", "description": `Goの時間の長さの形式 (例: "15m")`}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		s := map[string]any{"type": "array", "items": g.schema(t.Elem())}
+		if t.Kind() == reflect.Array {
+			s["minItems"], s["maxItems"] = t.Len(), t.Len()
+		}
+		return s
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // 再帰している型のための仮の値
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	return map[string]any{}
+}
+
+// structSchema は encoding/json と同じ規則でフィールドをプロパティにします。
+// omitempty のないフィールドは必須として扱います。
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	s := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// addDefs は参照された構造体の定義を doc に加えます。
+func (g *schemaGenerator) addDefs(doc map[string]any) {
+	if len(g.defs) > 0 {
+		doc["$defs"] = g.defs
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// searchResult は検索に一致した課題です。
+type searchResult struct {
+	a       *Assignment
+	score   int
+	context string
+}
+
+func runSearch(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := fs.Int("n", 20, "表示する最大件数")
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		log.Fatal("使い方: classroom-api search [オプション] <検索語>")
+	}
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	results := searchAssignments(filter.apply(items), query)
+	if len(results) == 0 {
+		fmt.Println("一致する課題はありません")
+		return
+	}
+	if len(results) > *limit {
+		results = results[:*limit]
+	}
+	for _, r := range results {
+		c := r.a.CourseWork
+		fmt.Printf("%s (%s) [%s] %s\n", c.Title, r.a.Slug, r.a.Course.Name, submissionLabel(r.a.Submission))
+		if r.context != "" {
+			fmt.Printf("    %s\n", r.context)
+		}
+	}
+}
+
+// searchAssignments はタイトルと説明に対してあいまい検索を行い、一致度の高い順に返します。
+// 検索語を空白で区切った語がすべてタイトルか説明のどちらかに一致する課題だけを返します。
+func searchAssignments(items []*Assignment, query string) []searchResult {
+	words := strings.Fields(strings.ToLower(query))
+	var results []searchResult
+	for _, a := range items {
+		title := []rune(strings.ToLower(a.CourseWork.Title))
+		desc := []rune(strings.ToLower(a.CourseWork.Description))
+		total, ctxPos := 0, -1
+		for _, w := range words {
+			q := []rune(w)
+			ts, _ := fuzzyMatch(q, title)
+			ds, dpos := fuzzyMatch(q, desc)
+			if ts == 0 && ds == 0 {
+				total = 0
+				break
+			}
+			// タイトルでの一致を説明での一致より重く扱う
+			if ts*2 >= ds {
+				total += ts * 2
+			} else {
+				total += ds
+				if ctxPos < 0 {
+					ctxPos = dpos
+				}
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		r := searchResult{a: a, score: total}
+		if ctxPos >= 0 {
+			r.context = snippet([]rune(a.CourseWork.Description), ctxPos, 30)
+		}
+		results = append(results, r)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].score > results[j].score })
+	return results
+}
+
+// fuzzyMatch は q が text にどの程度一致するかを返します。一致しない場合は 0 を返します。
+// 部分文字列として含まれる場合が最も高く、間に少しだけ別の文字を挟んで
+// q の文字が順に現れる場合 (タイプミスや表記ゆれ) はそれより低い点数になります。
+func fuzzyMatch(q, text []rune) (score, pos int) {
+	if len(q) == 0 {
+		return 0, -1
+	}
+	if i := indexRunes(text, q); i >= 0 {
+		score = 100
+		if i == 0 || !isWordRune(text[i-1]) {
+			score += 20
+		}
+		return score, i
+	}
+	// q の文字が q の長さの2倍以内の範囲に順に現れるかを調べる
+	maxSpan := len(q) * 2
+	best := 0
+	for start := 0; start < len(text); start++ {
+		if text[start] != q[0] {
+			continue
+		}
+		qi, end := 1, start+1
+		for ; end < len(text) && end-start < maxSpan && qi < len(q); end++ {
+			if text[end] == q[qi] {
+				qi++
+			}
+		}
+		if qi == len(q) {
+			s := 80 - (end-start-len(q))*5
+			if s > best {
+				best, pos = s, start
+			}
+		}
+	}
+	if best <= 0 {
+		return 0, -1
+	}
+	return best, pos
+}
+
+func indexRunes(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// snippet は pos の前後 width 文字を1行にまとめて返します。
+func snippet(text []rune, pos, width int) string {
+	start, end := pos-width, pos+width
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(text) {
+		end, suffix = len(text), ""
+	}
+	s := strings.Join(strings.Fields(string(text[start:end])), " ")
+	return prefix + s + suffix
+}
@@ -0,0 +1,533 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/graph-gophers/graphql-go"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/classroom/v1"
+	"google.golang.org/grpc"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ServerConfig はローカルのAPIサーバーの設定です。
+type ServerConfig struct {
+	// RateLimit はクライアントごとのリクエスト数の制限です。
+	RateLimit RateLimitConfig `json:"rateLimit"`
+	// RequireAPIKey が true の場合は keys create で発行したAPIキーを必須にし、キーの権限で使えるAPIを制限します。
+	RequireAPIKey bool `json:"requireApiKey,omitempty"`
+	// APIKeys は設定ファイルに書いたAPIキーです。1つでもあればAPIキーを必須にします (RequireAPIKey と同じ)。
+	APIKeys []ConfigAPIKey `json:"apiKeys,omitempty"`
+	// MultiUser が true の場合は複数の生徒がそれぞれのGoogleアカウントで /login からログインして使えます。
+	// トークンはユーザーごとに users.json に保存し、応答はログインしたユーザーの課題だけになります。
+	MultiUser bool `json:"multiUser,omitempty"`
+	// Refresh はバックグラウンドで課題を取得し直す間隔です。既定は5分です。
+	// リクエストには取得済みの結果を返すため、Classroom APIの呼び出しはこの間隔とユーザー数で決まります。
+	Refresh Duration `json:"refresh,omitempty"`
+	// CacheTTL は取得済みの結果を使う期限です。これより古い場合はリクエストのときに取得し直します。既定は15分です。
+	CacheTTL Duration `json:"cacheTtl,omitempty"`
+	// Listen は待ち受けるアドレスです。既定は ":8000" で、Autocert を使う場合は ":443" です。
+	Listen string `json:"listen,omitempty"`
+	// TLSCert と TLSKey はHTTPSで待ち受けるための証明書と秘密鍵のファイルです。
+	TLSCert string `json:"tlsCert,omitempty"`
+	TLSKey  string `json:"tlsKey,omitempty"`
+	// Autocert はLet's Encryptから証明書を自動で取得するドメインです。
+	// 証明書の取得 (TLS-ALPN-01) のため、インターネットから443番ポートに届く必要があります。
+	Autocert []string `json:"autocert,omitempty"`
+	// CORS は別のオリジンのWebページから /api と /graphql を呼ぶための設定です。
+	CORS CORSConfig `json:"cors"`
+	// GRPCListen は gRPC で待ち受けるアドレスです (例: ":9000")。空の場合は gRPC を使いません。
+	// 証明書は HTTP と同じものを使います。
+	GRPCListen string `json:"grpcListen,omitempty"`
+	// AccessLog が true の場合はリクエストごとにメソッド・パス・状態・所要時間・利用者をJSONで標準エラー出力に書きます。
+	AccessLog bool `json:"accessLog,omitempty"`
+	// Notify が true の場合はバックグラウンドでの取得やプッシュ通知で見つかった新しい課題と変更された課題を notify の送信先にも送ります。
+	Notify bool `json:"notify,omitempty"`
+	// Pprof が true の場合は /debug/pprof でプロファイルを取得できるようにします。
+	// requireApiKey の設定にかかわらず、debug の権限を持つAPIキーが必要です。
+	Pprof bool `json:"pprof,omitempty"`
+}
+
+// server はローカルのAPIサーバーです。
+type server struct {
+	cfg   *Config
+	keys  *apiKeyStore
+	oauth *oauth2.Config
+	// users は複数ユーザーモードのユーザーとセッションです。1人で使う場合は nil です。
+	users *userStore
+
+	// cache はアカウントごとの取得結果の保存先です。
+	cache serverCache
+	// single は1人で使う場合のアカウントです。
+	single   *account
+	mu       sync.Mutex
+	accounts map[string]*account
+
+	// graphql は /graphql のスキーマです。
+	graphql *graphql.Schema
+	// limiter はHTTPと gRPC で共有するリクエスト数の制限です。
+	limiter *rateLimiter
+	// oauthStates は /login で発行したログインの state です。
+	oauthStates oauthStateStore
+
+	// ctx はサーバーを終了するときに取り消され、バックグラウンドでの取得と /ws・/events の接続を止めます。
+	// refreshers は終了を待つための実行中の取得の数です。
+	ctx        context.Context
+	refreshers sync.WaitGroup
+}
+
+// account はサーバーが課題を取得するGoogleアカウントです。
+type account struct {
+	cfg    *Config
+	client *http.Client
+	srv    *classroom.Service
+	tokens *serverTokenStore
+	// cache は取得結果の保存先で、key はその中でのこのアカウントのキーです。
+	cache serverCache
+	key   string
+	ttl   time.Duration
+
+	// mu は取得を1つずつ行うためのロックで、seen は前回取得したときの未提出の課題です。
+	mu   sync.Mutex
+	seen map[string]*Assignment
+
+	// /ws と /events で変更を受け取るチャネル
+	liveMu sync.Mutex
+	subs   map[chan *liveChanges]struct{}
+
+	// 最後の取得の結果 (/readyz で使う)
+	statusMu   sync.Mutex
+	refreshed  time.Time
+	refreshErr error
+
+	// notifiers は server.notify が有効な場合に変更を送る通知の送信先で、
+	// alerts は締切の前のリマインダーと毎日のまとめを送る時期を決めます (notify.reminders と notify.dailyDigest)。
+	notifiers []Notifier
+	alerts    *alerter
+	// wake はプッシュ通知を受け取ったときに、次の間隔を待たずに取得し直すためのチャネルです。
+	wake chan struct{}
+}
+
+func runServe(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	readOnly := fs.Bool("read-only", cfg.ReadOnly, "変更を伴うエンドポイントをすべて無効にします")
+	listen := fs.String("listen", cfg.Server.Listen, "待ち受けるアドレス (例: 127.0.0.1:8000)")
+	tlsCert := fs.String("tls-cert", cfg.Server.TLSCert, "HTTPSの証明書のファイル")
+	tlsKey := fs.String("tls-key", cfg.Server.TLSKey, "HTTPSの秘密鍵のファイル")
+	grpcListen := fs.String("grpc-listen", cfg.Server.GRPCListen, "gRPC で待ち受けるアドレス (例: :9000)")
+	accessLog := fs.Bool("access-log", cfg.Server.AccessLog, "リクエストごとのアクセスログをJSONで出力します")
+	pprof := fs.Bool("pprof", cfg.Server.Pprof, "debug の権限を持つAPIキーで /debug/pprof からプロファイルを取得できるようにします")
+	autocertDomains := fs.String("autocert", strings.Join(cfg.Server.Autocert, ","), "Let's Encryptから証明書を自動で取得するカンマ区切りのドメイン")
+	fs.Parse(args)
+	cfg.ReadOnly = *readOnly
+	cfg.Server.Listen, cfg.Server.TLSCert, cfg.Server.TLSKey = *listen, *tlsCert, *tlsKey
+	cfg.Server.Autocert = splitList(*autocertDomains)
+	cfg.Server.AccessLog = *accessLog
+	cfg.Server.GRPCListen = *grpcListen
+	cfg.Server.Pprof = *pprof
+	if err := cfg.Server.checkTLS(); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := newAlerter(cfg, ""); err != nil {
+		log.Fatal(err)
+	}
+
+	keys, err := loadAPIKeys(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s := &server{cfg: cfg, keys: keys, cache: newMemoryCache(), accounts: map[string]*account{}, graphql: newGraphQLSchema(), limiter: newRateLimiter(cfg.Server.RateLimit), ctx: ctx}
+	switch {
+	case cfg.Server.MultiUser && cfg.Offline:
+		log.Fatal("複数ユーザーモードではオフラインモードを使えません")
+	case cfg.Offline:
+		client := newHTTPClient(cfg)
+		s.single = &account{cfg: cfg, client: client, srv: newClassroomService(ctx, client)}
+	default:
+		if s.oauth, err = oauthConfig(cfg); err != nil {
+			log.Fatal(err)
+		}
+		if cfg.Server.MultiUser {
+			if s.users, err = loadUserStore(cfg); err != nil {
+				log.Fatal(err)
+			}
+			s.oauth.Scopes = append(s.oauth.Scopes, "openid", "email")
+		} else {
+			tok, _ := tokenFromFile(cfg.TokenFile)
+			s.single = newAccount(ctx, cfg, s.oauth, tok, func(tok *oauth2.Token) error {
+				return writeJSONFile(cfg.TokenFile, tok)
+			})
+		}
+	}
+
+	if s.single != nil {
+		s.startAccount(s.single, "")
+	}
+	if cfg.Push.enabled() && !cfg.Offline {
+		if s.single != nil {
+			s.startPush(s.single)
+		} else {
+			log.Print("複数ユーザーモードではプッシュ通知を使いません")
+		}
+	}
+
+	mux := http.NewServeMux()
+	if s.users != nil {
+		mux.HandleFunc("GET /login", s.handleLogin)
+		mux.HandleFunc("POST /logout", s.handleLogout)
+	} else {
+		mux.HandleFunc("GET /login", s.requireScope("keys", s.handleLogin))
+	}
+	mux.HandleFunc("GET /oauth2/callback", s.handleOAuthCallback)
+	mux.HandleFunc("GET /{$}", s.requireScope("coursework", s.handleDashboard))
+	mux.HandleFunc("GET /ws", s.requireScope("coursework", s.handleLive))
+	mux.HandleFunc("GET /events", s.requireScope("coursework", s.handleEvents))
+	s.registerAPI(mux)
+	mux.HandleFunc("GET /graphql", s.requireScope("coursework", s.handleGraphQL))
+	mux.HandleFunc("POST /graphql", s.requireScope("coursework", s.handleGraphQL))
+	mux.HandleFunc("GET /metrics", s.requireScope("count", s.handleMetrics))
+	if cfg.Push.endpoint() {
+		mux.HandleFunc("POST /pubsub/push", s.handlePush)
+	}
+	if cfg.Server.Pprof {
+		s.registerPprof(mux)
+	}
+
+	// 429 にもCORSのヘッダーが付くよう、CORSをアクセスログの次に外側にする
+	handler := s.accessLogMiddleware(corsMiddleware(cfg.Server.CORS, s.limiter.middleware(readOnlyMiddleware(cfg, mux))))
+	if cfg.ReadOnly {
+		log.Printf("読み取り専用モードで起動します")
+	}
+	switch {
+	case s.users != nil:
+		log.Printf("複数ユーザーモードで起動します (登録済みのユーザー %d 人)", s.users.count())
+	case s.single.tokens != nil && !s.single.tokens.loggedIn():
+		log.Printf("トークンがありません。%s/login でログインしてください", cfg.Server.baseURL())
+	}
+	log.Printf("%s で待ち受けています (ダッシュボード: %s/)", cfg.Server.listenAddr(), cfg.Server.baseURL())
+	tlsConfig, err := serverTLSConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// systemd に準備ができたと知らせる前に待ち受けを始める
+	l, err := listenSocket("http", cfg.Server.listenAddr())
+	if err != nil {
+		log.Fatal(err)
+	}
+	hs := &http.Server{Handler: handler}
+	errc := make(chan error, 2)
+	go func() { errc <- serveHTTP(hs, l, cfg, tlsConfig) }()
+	var gs *grpc.Server
+	if cfg.Server.GRPCListen != "" {
+		gl, err := listenSocket("grpc", cfg.Server.GRPCListen)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gs = s.newGRPCServer(tlsConfig)
+		go func() { errc <- serveGRPC(gs, gl) }()
+		log.Printf("gRPC を %s で待ち受けています", cfg.Server.GRPCListen)
+	}
+	sdNotify("READY=1\nSTATUS=" + cfg.Server.baseURL() + " で待ち受けています")
+	select {
+	case err := <-errc:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop()
+	sdNotify("STOPPING=1")
+	s.shutdown(hs, gs)
+}
+
+// shutdownTimeout は終了するときに処理中のリクエストを待つ時間です。
+const shutdownTimeout = 30 * time.Second
+
+// shutdown は新しい接続の受け付けをやめ、処理中のリクエストとバックグラウンドでの取得が終わるのを待ちます。
+// gs は gRPC を使わない場合は nil です。s.ctx は取り消されている必要があります。
+func (s *server) shutdown(hs *http.Server, gs *grpc.Server) {
+	log.Printf("終了しています (処理中のリクエストを最大 %s 待ちます)", shutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if gs != nil {
+		go func() {
+			// WatchCourseWork は s.ctx が取り消されると終わるため、通常は期限より前に止まる
+			<-ctx.Done()
+			gs.Stop()
+		}()
+		gs.GracefulStop()
+	}
+	if err := hs.Shutdown(ctx); err != nil {
+		log.Printf("処理中のリクエストを待ちきれませんでした: %v", err)
+	}
+	s.refreshers.Wait()
+	log.Printf("終了しました")
+}
+
+// newAccount はトークン tok (なければ nil) で課題を取得するアカウントを作ります。
+// トークンが更新されたときは save で保存します。
+func newAccount(ctx context.Context, cfg *Config, config *oauth2.Config, tok *oauth2.Token, save func(*oauth2.Token) error) *account {
+	tokens := newServerTokenStore(config, tok, save)
+	client := oauth2.NewClient(context.Background(), tokens)
+	client.Transport = newClientTransport(client.Transport, cfg.Client)
+	return &account{cfg: cfg, client: client, srv: newClassroomService(ctx, client), tokens: tokens}
+}
+
+// requestAccount はリクエストの課題を取得するアカウントを返します。
+// 複数ユーザーモードではセッションのクッキーまたはユーザーに紐づいたAPIキーからユーザーを決めます。
+func (s *server) requestAccount(r *http.Request) (*account, error) {
+	if s.users == nil {
+		if s.single.tokens != nil && !s.single.tokens.loggedIn() {
+			return nil, errNotLoggedIn
+		}
+		return s.single, nil
+	}
+	u := s.sessionUser(r)
+	if u == nil {
+		if k := s.keys.lookup(requestAPIKey(r), time.Now()); k != nil && k.User != "" {
+			u = s.users.find(k.User)
+		}
+	}
+	if u == nil {
+		return nil, errNotLoggedIn
+	}
+	return s.userAccount(u), nil
+}
+
+// userAccount はユーザーのアカウントを返します。初めて使うときに作ります。
+func (s *server) userAccount(u *serverUser) *account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.accounts[u.ID]; ok {
+		return a
+	}
+	// ローカルのキャッシュは1人分なので、ユーザーごとのアカウントでは使わない
+	cfg := *s.cfg
+	cfg.Cache = CacheConfig{}
+	id := u.ID
+	a := newAccount(s.ctx, &cfg, s.oauth, s.users.token(id), func(tok *oauth2.Token) error {
+		return s.users.setToken(id, tok)
+	})
+	s.startAccount(a, id)
+	s.accounts[id] = a
+	return a
+}
+
+// account はリクエストのアカウントを返します。ログインしていない場合はエラーを書き込んで false を返します。
+func (s *server) account(w http.ResponseWriter, r *http.Request) (*account, bool) {
+	a, err := s.requestAccount(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+	return a, true
+}
+
+// pending は未提出の課題を返します。取得できなかった場合はエラーを書き込んで false を返します。
+func (s *server) pending(w http.ResponseWriter, r *http.Request, now time.Time) ([]*Assignment, bool) {
+	a, ok := s.account(w, r)
+	if !ok {
+		return nil, false
+	}
+	items, err := a.assignments(r.Context())
+	if errors.Is(err, errNotLoggedIn) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("課題を取得できませんでした: %v", err)
+		http.Error(w, "課題を取得できませんでした", http.StatusBadGateway)
+		return nil, false
+	}
+	return pendingAssignments(items, now), true
+}
+
+// courseList はリクエストのアカウントのコースの一覧を返します。取得できなかった場合はエラーを書き込んで false を返します。
+func (s *server) courseList(w http.ResponseWriter, r *http.Request) ([]*classroom.Course, bool) {
+	a, ok := s.account(w, r)
+	if !ok {
+		return nil, false
+	}
+	courses, err := a.courseList(r.Context())
+	if err != nil {
+		log.Printf("コースを取得できませんでした: %v", err)
+		http.Error(w, "コースを取得できませんでした", http.StatusBadGateway)
+		return nil, false
+	}
+	return courses, true
+}
+
+func (s *server) handlePending(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	pending, ok := s.pending(w, r, now)
+	if !ok {
+		return
+	}
+	res := []*TemplateData{}
+	for _, a := range pending {
+		res = append(res, newTemplateData(a, now))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *server) handlePendingCount(w http.ResponseWriter, r *http.Request) {
+	pending, ok := s.pending(w, r, time.Now())
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, &ApiPendingCount{Count: countSubmittable(pending)})
+}
+
+func (s *server) handlePendingTitles(w http.ResponseWriter, r *http.Request) {
+	pending, ok := s.pending(w, r, time.Now())
+	if !ok {
+		return
+	}
+	res := []*ApiPendingTitle{}
+	for _, a := range pending {
+		t := &ApiPendingTitle{Title: a.CourseWork.Title}
+		if due, ok := a.EffectiveDue(); ok {
+			t.Due = &due
+		}
+		res = append(res, t)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// apiCourses はコースごとに未提出の課題の件数を数えます。
+func apiCourses(cfg *Config, courses []*classroom.Course, pending []*Assignment) []*ApiCourse {
+	res := []*ApiCourse{}
+	for _, c := range courses {
+		ac := &ApiCourse{Id: c.Id, Name: c.Name, Section: c.Section, Alias: cfg.course(c.Id).Alias, Link: c.AlternateLink}
+		var items []*Assignment
+		for _, a := range pending {
+			if a.Course.Id == c.Id {
+				items = append(items, a)
+				if ac.Alias == "" {
+					ac.Alias = courseLabel(a)
+				}
+			}
+		}
+		ac.Pending = countSubmittable(items)
+		res = append(res, ac)
+	}
+	return res
+}
+
+func (s *server) handleCourses(w http.ResponseWriter, r *http.Request) {
+	courses, ok := s.courseList(w, r)
+	if !ok {
+		return
+	}
+	pending, ok := s.pending(w, r, time.Now())
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, apiCourses(s.cfg, courses, pending))
+}
+
+// handleCoursework は未提出の課題を返します。
+// due_within (例: 7d, 12h) を指定すると、締切切れを含めてその期間内に締切がある課題だけを返します。
+// course、type、state、topic、due_before で list と同じ条件で絞り込み、page_size と page_token でページに分けられます。
+func (s *server) handleCoursework(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	var within time.Duration
+	if v := r.URL.Query().Get("due_within"); v != "" {
+		d, err := parseOffset(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "due_within は 7d や 12h の形式で指定してください", http.StatusBadRequest)
+			return
+		}
+		within = d
+	}
+	filter, pg, ok := courseworkQuery(w, r, now)
+	if !ok {
+		return
+	}
+	pending, ok := s.pending(w, r, now)
+	if !ok {
+		return
+	}
+	var matched []*Assignment
+	for _, a := range filter.apply(pending) {
+		if within > 0 {
+			due, ok := a.EffectiveDue()
+			if !ok || due.Sub(now) > within {
+				continue
+			}
+		}
+		matched = append(matched, a)
+	}
+	writeCourseworkPage(w, r, pg, matched, now)
+}
+
+// handleCourseCoursework はコースの未提出の課題を返します。{id} にはコースIDまたはコースの別名を指定できます。
+// クエリパラメーターは GET /api/coursework と同じです。
+func (s *server) handleCourseCoursework(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	filter, pg, ok := courseworkQuery(w, r, now)
+	if !ok {
+		return
+	}
+	courses, ok := s.courseList(w, r)
+	if !ok {
+		return
+	}
+	pending, ok := s.pending(w, r, now)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+	courseID := ""
+	for _, c := range courses {
+		if c.Id == id || s.cfg.course(c.Id).Alias == id {
+			courseID = c.Id
+		}
+	}
+	for _, a := range pending {
+		if courseLabel(a) == id {
+			courseID = a.Course.Id
+		}
+	}
+	if courseID == "" {
+		http.Error(w, "コースが見つかりません", http.StatusNotFound)
+		return
+	}
+	var matched []*Assignment
+	for _, a := range filter.apply(pending) {
+		if a.Course.Id == courseID {
+			matched = append(matched, a)
+		}
+	}
+	writeCourseworkPage(w, r, pg, matched, now)
+}
+
+// decodeJSONBody はリクエストの本文をJSONとして読み込みます。
+func decodeJSONBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("リクエストの本文を解析できませんでした: %w", err)
+	}
+	return nil
+}
+
+// writeJSON は値をJSONで書き込みます。
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("レスポンスを書き込めませんでした: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+)
+
+// defaultShareTemplate はクラスのグループチャットに投稿する文面の既定のテンプレートです。
+const defaultShareTemplate = "【締切 {{.DueText}}】{{.Title}}: {{.Link}}\n"
+
+func runCourseworkShare(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("coursework share", flag.ExitOnError)
+	text := fs.String("template", "", "文面のテンプレート (省略時はコースまたは全体の設定)")
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	a, err := findAssignmentArgs(items, fs.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *text == "" {
+		*text = cfg.shareTemplate(a.Course.Id)
+	}
+	tmpl, err := parseTemplate("share", *text)
+	if err != nil {
+		log.Fatalf("テンプレートを解析できませんでした: %v", err)
+	}
+	if err := tmpl.Execute(os.Stdout, newTemplateData(a, time.Now())); err != nil {
+		log.Fatalf("文面を作成できませんでした: %v", err)
+	}
+}
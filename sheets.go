@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+	"log"
+	"strings"
+)
+
+// SheetsConfig はGoogleスプレッドシートへの書き出しの設定です。
+type SheetsConfig struct {
+	// SpreadsheetID は書き出すスプレッドシートのIDです。省略した場合は新しく作成します。
+	SpreadsheetID string `json:"spreadsheetId,omitempty"`
+}
+
+func runExportSheets(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export sheets", flag.ExitOnError)
+	id := fs.String("id", cfg.Sheets.SpreadsheetID, "書き出すスプレッドシートのID (省略時は新しく作成)")
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	client := newHTTPClient(cfg)
+	srv := newClassroomService(ctx, client)
+	ssrv, err := sheets.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		log.Fatalf("Sheetsクライアントを作成できませんでした: %v", err)
+	}
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *id == "" {
+		s, err := ssrv.Spreadsheets.Create(&sheets.Spreadsheet{
+			Properties: &sheets.SpreadsheetProperties{Title: "Classroom の課題"},
+		}).Context(ctx).Do()
+		if err != nil {
+			log.Fatalf("スプレッドシートを作成できませんでした: %v", err)
+		}
+		*id = s.SpreadsheetId
+		log.Printf("スプレッドシートを作成しました。次回からは設定ファイルの sheets.spreadsheetId に %s を指定してください", *id)
+	}
+	if err := writeSheets(ctx, cfg, ssrv, *id, filter.apply(items)); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("https://docs.google.com/spreadsheets/d/%s に書き出しました", *id)
+}
+
+// writeSheets はコースごとのシートに課題と提出状況を書き出します。
+// シートがなければ追加し、あれば内容を書き換えます。ほかのシートには触れません。
+func writeSheets(ctx context.Context, cfg *Config, ssrv *sheets.Service, id string, items []*Assignment) error {
+	var titles []string
+	rows := map[string][][]any{}
+	for _, a := range items {
+		title := sheetTitle(cfg.courseAlias(a.Course.Id, a.Course.Name))
+		if _, ok := rows[title]; !ok {
+			titles = append(titles, title)
+			rows[title] = [][]any{toRow(csvHeader)}
+		}
+		rows[title] = append(rows[title], toRow(csvRecord(a)))
+	}
+
+	s, err := ssrv.Spreadsheets.Get(id).Fields("sheets.properties.title").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("スプレッドシートを取得できませんでした: %w", err)
+	}
+	existing := map[string]bool{}
+	for _, sh := range s.Sheets {
+		existing[sh.Properties.Title] = true
+	}
+	var add []*sheets.Request
+	for _, t := range titles {
+		if !existing[t] {
+			add = append(add, &sheets.Request{AddSheet: &sheets.AddSheetRequest{Properties: &sheets.SheetProperties{Title: t}}})
+		}
+	}
+	if len(add) > 0 {
+		req := &sheets.BatchUpdateSpreadsheetRequest{Requests: add}
+		if _, err := ssrv.Spreadsheets.BatchUpdate(id, req).Context(ctx).Do(); err != nil {
+			return fmt.Errorf("シートを追加できませんでした: %w", err)
+		}
+	}
+	for _, t := range titles {
+		rng := "'" + strings.ReplaceAll(t, "'", "''") + "'"
+		if _, err := ssrv.Spreadsheets.Values.Clear(id, rng, &sheets.ClearValuesRequest{}).Context(ctx).Do(); err != nil {
+			return fmt.Errorf("シート %s を消去できませんでした: %w", t, err)
+		}
+		vr := &sheets.ValueRange{Values: rows[t]}
+		if _, err := ssrv.Spreadsheets.Values.Update(id, rng+"!A1", vr).ValueInputOption("RAW").Context(ctx).Do(); err != nil {
+			return fmt.Errorf("シート %s に書き込めませんでした: %w", t, err)
+		}
+	}
+	return nil
+}
+
+// sheetTitle はシート名に使えない文字を取り除き、100文字までに切り詰めます。
+func sheetTitle(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]*?/\:`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	if r := []rune(s); len(r) > 100 {
+		s = string(r[:100])
+	}
+	return s
+}
+
+func toRow(values []string) []any {
+	row := make([]any, len(values))
+	for i, v := range values {
+		row[i] = v
+	}
+	return row
+}
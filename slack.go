@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+const slackAPI = "https://slack.com/api"
+
+// SlackConfig はSlackへの通知の設定です。
+// Incoming Webhook の URL か、chat:write の権限を持つボットのトークンのどちらかを設定します。
+type SlackConfig struct {
+	WebhookURL string `json:"webhookUrl,omitempty"`
+	// Token はボットのトークン (xoxb-) です。省略した場合は環境変数 SLACK_BOT_TOKEN を使います。
+	Token string `json:"token,omitempty"`
+	// Channel はボットのトークンを使う場合の既定の投稿先です (例: "#classroom")。
+	Channel string `json:"channel,omitempty"`
+	// Channels はコースのID・別名・名前ごとの投稿先です。
+	// ボットのトークンを使う場合はチャンネルを、Incoming Webhook の場合はそのチャンネルの Webhook の URL を書きます。
+	// コースに関係しない通知と、一致するコースがない通知は既定の投稿先に送ります。
+	Channels map[string]string `json:"channels,omitempty"`
+	// Events は送るできごとの種類です。省略した場合は新しい課題、締切の変更、締切が近い課題、毎日のまとめ、
+	// リマインダー、授業後のまとめを送ります。
+	Events []string `json:"events,omitempty"`
+}
+
+// defaultSlackEvents は SlackConfig.Events を省略したときに送るできごとです。
+var defaultSlackEvents = []string{"created", "due_changed", "due_soon", "daily_digest", "reminder", "digest"}
+
+// slackNotifier は通知をSlackに投稿します。
+type slackNotifier struct {
+	cfg  SlackConfig
+	rest *restClient
+}
+
+func newSlackNotifier(cfg SlackConfig) *slackNotifier {
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("SLACK_BOT_TOKEN")
+	}
+	if len(cfg.Events) == 0 {
+		cfg.Events = defaultSlackEvents
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	return &slackNotifier{cfg: cfg, rest: &restClient{name: "Slack", base: slackAPI, token: cfg.Token, client: client}}
+}
+
+func (n *slackNotifier) Name() string { return "slack" }
+
+func (n *slackNotifier) accepts(typ string) bool { return acceptsEvent(n.cfg.Events, typ) }
+
+func (n *slackNotifier) Notify(ctx context.Context, ev *Event) error {
+	if !n.accepts(ev.Type) {
+		return nil
+	}
+	text := slackMessage(ev)
+	dest := n.destination(ev.Assignment)
+	if strings.HasPrefix(dest, "https://") {
+		return n.postWebhook(ctx, dest, text)
+	}
+	if n.cfg.Token == "" {
+		return fmt.Errorf("Slackの投稿先がありません (notify.slack.webhookUrl または notify.slack.token)")
+	}
+	if dest == "" {
+		return fmt.Errorf("Slackのチャンネルがありません (notify.slack.channel)")
+	}
+	var res struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := n.rest.do(ctx, http.MethodPost, "/chat.postMessage", map[string]any{"channel": dest, "text": text}, &res); err != nil {
+		return err
+	}
+	if !res.OK {
+		return fmt.Errorf("Slackに投稿できませんでした: %s", res.Error)
+	}
+	return nil
+}
+
+// destination は課題のコースに対応する投稿先を返します。対応する投稿先がない場合は既定の投稿先です。
+func (n *slackNotifier) destination(a *Assignment) string {
+	if a != nil {
+		keys := make([]string, 0, len(n.cfg.Channels))
+		for k := range n.cfg.Channels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if matchCourse([]string{k}, a) {
+				return n.cfg.Channels[k]
+			}
+		}
+	}
+	if n.cfg.Token != "" && n.cfg.Channel != "" {
+		return n.cfg.Channel
+	}
+	return n.cfg.WebhookURL
+}
+
+// postWebhook は Incoming Webhook に投稿します。
+func (n *slackNotifier) postWebhook(ctx context.Context, url, text string) error {
+	b, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.rest.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return &restError{Service: "Slack", Status: res.StatusCode, Body: string(body)}
+	}
+	return nil
+}
+
+// slackMessage は通知をSlackのmrkdwnの本文にします。課題の通知ではタイトルを課題へのリンクにします。
+func slackMessage(ev *Event) string {
+	title := "*" + slackEscape(ev.Title) + "*"
+	if a := ev.Assignment; a != nil && a.CourseWork.AlternateLink != "" {
+		title = "*<" + a.CourseWork.AlternateLink + "|" + slackEscape(ev.Title) + ">*"
+	}
+	body := slackEscape(ev.Body)
+	if a := ev.Assignment; a != nil && !ev.Templated && (ev.Type == "created" || ev.Type == "due_changed") {
+		// 本文の末尾のURLはタイトルのリンクと重複するため、コースと締切だけにする
+		due := slackEscape(formatDue(a))
+		if t, ok := a.Due(); ok && t.After(ev.Time) {
+			due += " (" + formatRemaining(t.Sub(ev.Time)) + ")"
+		}
+		if ev.Type == "due_changed" && ev.Previous != nil {
+			due = "~" + slackEscape(formatDue(ev.Previous)) + "~ → " + due
+		}
+		body = slackEscape(a.Course.Name) + "\n締切: " + due
+	}
+	return title + "\n" + body
+}
+
+// slackEscape はSlackのmrkdwnで特別な意味を持つ &、<、> をエスケープします。
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// slugStateFile は一度割り当てたスラッグを記録するファイルです。
+// タイトルが変わってもスラッグが変わらないように、割り当ては永続化します。
+const slugStateFile = "slugs.json"
+
+type slugState struct {
+	// Courses はコースIDとコース別名の対応です。
+	Courses map[string]string `json:"courses"`
+	// CourseWork は "コースID/課題ID" と課題スラッグの対応です。
+	CourseWork map[string]string `json:"coursework"`
+}
+
+// assignSlugs は課題に「コース別名/課題スラッグ」形式のスラッグを割り当てます。
+func assignSlugs(cfg *Config, items []*Assignment) error {
+	st := &slugState{}
+	if err := readJSONFile(cfg.dataPath(slugStateFile), st); err != nil {
+		return fmt.Errorf("スラッグの対応を読み取れませんでした: %w", err)
+	}
+	if st.Courses == nil {
+		st.Courses = map[string]string{}
+	}
+	if st.CourseWork == nil {
+		st.CourseWork = map[string]string{}
+	}
+
+	usedAliases := map[string]bool{}
+	for _, alias := range st.Courses {
+		usedAliases[alias] = true
+	}
+	usedSlugs := map[string]bool{}
+	for key, slug := range st.CourseWork {
+		courseId, _, _ := strings.Cut(key, "/")
+		usedSlugs[courseId+"/"+slug] = true
+	}
+
+	changed := false
+	for _, a := range items {
+		course := a.Course
+		if _, ok := st.Courses[course.Id]; !ok {
+			base := slugify(course.Name)
+			if base == "" {
+				base = "course-" + lastN(course.Id, 4)
+			}
+			st.Courses[course.Id] = uniqueSlug(base, func(s string) bool { return usedAliases[s] })
+			usedAliases[st.Courses[course.Id]] = true
+			changed = true
+		}
+		key := assignmentKey(a)
+		if _, ok := st.CourseWork[key]; !ok {
+			base := slugify(a.CourseWork.Title)
+			if base == "" {
+				base = "work-" + lastN(a.CourseWork.Id, 4)
+			}
+			slug := uniqueSlug(base, func(s string) bool { return usedSlugs[course.Id+"/"+s] })
+			st.CourseWork[key] = slug
+			usedSlugs[course.Id+"/"+slug] = true
+			changed = true
+		}
+		a.Slug = cfg.courseAlias(course.Id, st.Courses[course.Id]) + "/" + st.CourseWork[key]
+	}
+	if changed && !cfg.dryRun {
+		if err := writeJSONFile(cfg.dataPath(slugStateFile), st); err != nil {
+			return fmt.Errorf("スラッグの対応を保存できませんでした: %w", err)
+		}
+	}
+	return nil
+}
+
+// findAssignment は識別子に一致する課題を探します。
+// 識別子にはスラッグ、課題ID、"コースID/課題ID" のいずれも使えます。
+// コース別名を省いた課題スラッグだけでも、一意に決まれば受け付けます。
+func findAssignment(items []*Assignment, ident string) (*Assignment, error) {
+	for _, a := range items {
+		if ident == a.Slug || ident == a.CourseWork.Id || ident == assignmentKey(a) {
+			return a, nil
+		}
+	}
+	var found *Assignment
+	for _, a := range items {
+		if strings.HasSuffix(a.Slug, "/"+ident) {
+			if found != nil {
+				return nil, fmt.Errorf("識別子が複数の課題に一致します: %s (%s, %s)", ident, found.Slug, a.Slug)
+			}
+			found = a
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("課題が見つかりません: %s", ident)
+	}
+	return found, nil
+}
+
+// slugify はタイトルから英数字とハイフンだけのスラッグを作ります。
+// 英数字を含まないタイトル (日本語のみなど) の場合は空文字列を返します。
+func slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		hyphen = true
+	}
+	slug := b.String()
+	if len(slug) > 40 {
+		slug = strings.TrimRight(slug[:40], "-")
+	}
+	return slug
+}
+
+// uniqueSlug は used で使用済みと判定されない base, base-2, base-3, ... のいずれかを返します。
+func uniqueSlug(base string, used func(string) bool) string {
+	slug := base
+	for i := 2; used(slug); i++ {
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+	return slug
+}
+
+func lastN(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
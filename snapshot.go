@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// snapshotVersion はスナップショットの形式の版です。形式を変えた場合は上げてください。
+const snapshotVersion = 1
+
+// Snapshot は取得したコース、課題、提出物をすべてまとめたものです。
+type Snapshot struct {
+	Version int                 `json:"version"`
+	Created time.Time           `json:"created"`
+	Courses []*classroom.Course `json:"courses"`
+	Items   []*SnapshotItem     `json:"items"`
+}
+
+// SnapshotItem はスナップショットの課題1件です。
+type SnapshotItem struct {
+	CourseID   string                       `json:"courseId"`
+	Topic      string                       `json:"topic,omitempty"`
+	CourseWork *classroom.CourseWork        `json:"courseWork"`
+	Submission *classroom.StudentSubmission `json:"submission,omitempty"`
+}
+
+const snapshotUsageText = `使い方: classroom-api snapshot <サブコマンド> [オプション]
+
+  export [-out ファイル]  取得したコース・課題・提出物をJSONのスナップショットに書き出します
+  import ファイル         スナップショットをキャッシュに読み込みます (-offline で使えます)
+`
+
+func runSnapshot(ctx context.Context, cfg *Config, args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, snapshotUsageText)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("snapshot export", flag.ExitOnError)
+		out := fs.String("out", "", "出力ファイル (省略時は標準出力)")
+		fs.Parse(args[1:])
+
+		srv := newClassroomService(ctx, newHTTPClient(cfg))
+		courses, err := listCourses(ctx, srv, cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		items, err := fetchAssignments(ctx, srv, courses)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var w io.Writer = os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := writeSnapshot(w, newSnapshot(courses, items, time.Now())); err != nil {
+			log.Fatalf("スナップショットを書き出せませんでした: %v", err)
+		}
+	case "import":
+		fs := flag.NewFlagSet("snapshot import", flag.ExitOnError)
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			log.Fatal("スナップショットのファイルを指定してください")
+		}
+		snap, err := readSnapshot(fs.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		courses, items := snap.assignments()
+		db, err := openCache(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		if err := restoreCache(ctx, db, courses, items, snap.Created); err != nil {
+			log.Fatalf("キャッシュに保存できませんでした: %v", err)
+		}
+		log.Printf("%s のスナップショット (コース %d 件、課題 %d 件) をキャッシュに読み込みました", formatTime(snap.Created), len(courses), len(items))
+	default:
+		fmt.Fprintf(os.Stderr, "不明なサブコマンドです: %s\n\n", args[0])
+		fmt.Fprint(os.Stderr, snapshotUsageText)
+		os.Exit(2)
+	}
+}
+
+// newSnapshot はコースと課題からスナップショットを作ります。
+func newSnapshot(courses []*classroom.Course, items []*Assignment, now time.Time) *Snapshot {
+	s := &Snapshot{Version: snapshotVersion, Created: now, Courses: courses, Items: []*SnapshotItem{}}
+	for _, a := range items {
+		s.Items = append(s.Items, &SnapshotItem{
+			CourseID:   a.Course.Id,
+			Topic:      a.Topic,
+			CourseWork: a.CourseWork,
+			Submission: a.Submission,
+		})
+	}
+	return s
+}
+
+// assignments はスナップショットのコースと課題を返します。コースのない課題は除きます。
+func (s *Snapshot) assignments() ([]*classroom.Course, []*Assignment) {
+	byID := make(map[string]*classroom.Course, len(s.Courses))
+	for _, c := range s.Courses {
+		byID[c.Id] = c
+	}
+	var items []*Assignment
+	for _, it := range s.Items {
+		c, ok := byID[it.CourseID]
+		if !ok || it.CourseWork == nil {
+			continue
+		}
+		items = append(items, &Assignment{Course: c, CourseWork: it.CourseWork, Submission: it.Submission, Topic: it.Topic})
+	}
+	sortAssignments(items)
+	return s.Courses, items
+}
+
+func writeSnapshot(w io.Writer, s *Snapshot) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// readSnapshot はスナップショットを読み込みます。新しい版の形式は読み込めません。
+func readSnapshot(path string) (*Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("スナップショットを読み取れませんでした: %w", err)
+	}
+	switch {
+	case s.Version == 0:
+		return nil, fmt.Errorf("スナップショットの版がありません: %s", path)
+	case s.Version > snapshotVersion:
+		return nil, fmt.Errorf("このバージョンでは読み込めない新しい形式のスナップショットです (版 %d)", s.Version)
+	}
+	return s, nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// curveOffsets は完了曲線を計算する締切からの相対時刻です。
+var curveOffsets = []time.Duration{
+	-7 * 24 * time.Hour, -3 * 24 * time.Hour, -24 * time.Hour, -12 * time.Hour,
+	-6 * time.Hour, -time.Hour, 0, 24 * time.Hour, 7 * 24 * time.Hour,
+}
+
+// timingBuckets は提出タイミングの分布の区切り (締切の何時間前か) です。
+var timingBuckets = []struct {
+	label string
+	min   time.Duration
+}{
+	{"7日以上前", 7 * 24 * time.Hour},
+	{"3〜7日前", 3 * 24 * time.Hour},
+	{"1〜3日前", 24 * time.Hour},
+	{"6〜24時間前", 6 * time.Hour},
+	{"1〜6時間前", time.Hour},
+	{"1時間以内", 0},
+	{"締切後", math.MinInt64},
+}
+
+// StatsExport は研究用の匿名化した集計統計です。生徒を識別する情報は含みません。
+type StatsExport struct {
+	GeneratedAt string `json:"generatedAt"`
+	// Epsilon はラプラスノイズのプライバシーパラメータです。0 の場合はノイズを加えていません。
+	Epsilon float64 `json:"epsilon"`
+	// Sensitivity は1人の生徒が出力全体の件数に与える影響の上限 (L1感度) です。ノイズの尺度は Sensitivity/Epsilon です。
+	Sensitivity int `json:"sensitivity"`
+	// MinCount より小さい件数は秘匿され、-1 として出力されます。
+	MinCount    int               `json:"minCount"`
+	Timing      map[string]int    `json:"submissionTiming"`
+	HourOfDay   [24]int           `json:"submissionHourOfDay"`
+	Assignments []AssignmentStats `json:"assignments"`
+}
+
+// AssignmentStats は課題ごとの集計です。
+type AssignmentStats struct {
+	Course      string `json:"course"`
+	Title       string `json:"title"`
+	WorkType    string `json:"workType"`
+	Due         string `json:"due,omitempty"`
+	Submissions int    `json:"submissions"`
+	TurnedIn    int    `json:"turnedIn"`
+	// CompletionCurve は締切からの相対時刻ごとの累積提出数です。
+	CompletionCurve map[string]int `json:"completionCurve,omitempty"`
+}
+
+func runExportStats(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export stats", flag.ExitOnError)
+	anonymized := fs.Bool("anonymized", false, "生徒を識別する情報を含まない集計の出力に同意します (必須)")
+	epsilon := fs.Float64("epsilon", 1.0, "件数に加えるラプラスノイズのε (0 でノイズなし)")
+	minCount := fs.Int("min-count", 5, "この件数未満の集計値は秘匿します")
+	out := fs.String("out", "", "出力ファイル (省略時は標準出力)")
+	fs.Parse(args)
+
+	if !*anonymized {
+		log.Fatal("研究用の統計を出力するには -anonymized を指定してください")
+	}
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	courses, err := listTaughtCourses(ctx, srv, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	stats, err := collectStats(ctx, srv, courses)
+	if err != nil {
+		log.Fatal(err)
+	}
+	stats.anonymize(*epsilon, *minCount)
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("出力ファイルを作成できませんでした: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(stats); err != nil {
+		log.Fatalf("統計を書き込めませんでした: %v", err)
+	}
+}
+
+// collectStats は担当コースのすべての提出物から統計を集計します。
+// 生徒のIDや提出物のIDは集計にだけ使い、結果には残しません。
+func collectStats(ctx context.Context, srv *classroom.Service, courses []*classroom.Course) (*StatsExport, error) {
+	stats := &StatsExport{
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		Timing:      map[string]int{},
+	}
+	for _, course := range courses {
+		err := srv.Courses.CourseWork.List(course.Id).Pages(ctx, func(r *classroom.ListCourseWorkResponse) error {
+			for _, c := range r.CourseWork {
+				as, err := collectAssignmentStats(ctx, srv, course, c, stats)
+				if err != nil {
+					return err
+				}
+				stats.Assignments = append(stats.Assignments, as)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("課題を取得できませんでした (%s): %w", course.Name, err)
+		}
+	}
+	return stats, nil
+}
+
+func collectAssignmentStats(ctx context.Context, srv *classroom.Service, course *classroom.Course, c *classroom.CourseWork, stats *StatsExport) (AssignmentStats, error) {
+	as := AssignmentStats{Course: course.Name, Title: c.Title, WorkType: c.WorkType}
+	due, hasDue := dueTime(c)
+	if hasDue {
+		as.Due = due.Format(time.RFC3339)
+		as.CompletionCurve = map[string]int{}
+	}
+	err := srv.Courses.CourseWork.StudentSubmissions.List(course.Id, c.Id).Pages(ctx, func(r *classroom.ListStudentSubmissionsResponse) error {
+		for _, s := range r.StudentSubmissions {
+			as.Submissions++
+			at, ok := firstTurnedIn(s)
+			if !ok {
+				continue
+			}
+			as.TurnedIn++
+			stats.HourOfDay[at.Hour()]++
+			if !hasDue {
+				continue
+			}
+			before := due.Sub(at)
+			for _, b := range timingBuckets {
+				if before >= b.min {
+					stats.Timing[b.label]++
+					break
+				}
+			}
+			for _, off := range curveOffsets {
+				if !at.After(due.Add(off)) {
+					as.CompletionCurve[curveLabel(off)]++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return as, fmt.Errorf("提出物を取得できませんでした (%s): %w", c.Title, err)
+	}
+	return as, nil
+}
+
+// firstTurnedIn は提出物が最初に提出された時刻を返します。
+func firstTurnedIn(s *classroom.StudentSubmission) (time.Time, bool) {
+	for _, h := range s.SubmissionHistory {
+		if h.StateHistory == nil || h.StateHistory.State != "TURNED_IN" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, h.StateHistory.StateTimestamp)
+		if err != nil {
+			continue
+		}
+		return t.Local(), true
+	}
+	return time.Time{}, false
+}
+
+func curveLabel(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "締切"
+	case d < 0:
+		return fmt.Sprintf("締切%s前", formatHours(-d))
+	default:
+		return fmt.Sprintf("締切%s後", formatHours(d))
+	}
+}
+
+func formatHours(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d日", d/(24*time.Hour))
+	}
+	return fmt.Sprintf("%d時間", d/time.Hour)
+}
+
+// anonymize はすべての件数にラプラスノイズを加え、少数の件数を秘匿します。
+// 1人の生徒は課題ごとに複数の集計へ寄与するため、出力全体の感度に比例した尺度のノイズを加えます。
+func (s *StatsExport) anonymize(epsilon float64, minCount int) {
+	s.Epsilon = epsilon
+	s.MinCount = minCount
+	s.Sensitivity = s.sensitivity()
+	noisy := func(n int) int {
+		if epsilon > 0 {
+			n += int(math.Round(laplace(float64(s.Sensitivity) / epsilon)))
+		}
+		if n < minCount {
+			return -1
+		}
+		return n
+	}
+	for k, v := range s.Timing {
+		s.Timing[k] = noisy(v)
+	}
+	for i, v := range s.HourOfDay {
+		s.HourOfDay[i] = noisy(v)
+	}
+	for i := range s.Assignments {
+		a := &s.Assignments[i]
+		a.Submissions = noisy(a.Submissions)
+		a.TurnedIn = noisy(a.TurnedIn)
+		for k, v := range a.CompletionCurve {
+			a.CompletionCurve[k] = noisy(v)
+		}
+	}
+}
+
+// sensitivity は1人の生徒を加えたり除いたりしたときに変わる件数の合計の上限を返します。
+// 生徒の提出物は課題ごとに1件で、Submissions・TurnedIn・HourOfDay に1ずつ、
+// 締切のある課題ではさらに Timing に1、CompletionCurve の各時刻に1ずつ寄与します。
+func (s *StatsExport) sensitivity() int {
+	n := 0
+	for _, a := range s.Assignments {
+		n += 3
+		if a.Due != "" {
+			n += 1 + len(curveOffsets)
+		}
+	}
+	return max(n, 1)
+}
+
+// laplace は尺度 b のラプラス分布に従う乱数を返します。
+func laplace(b float64) float64 {
+	u := rand.Float64() - 0.5
+	if u < 0 {
+		return b * math.Log(1+2*u)
+	}
+	return -b * math.Log(1-2*u)
+}
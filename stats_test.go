@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestStatsSensitivity(t *testing.T) {
+	s := &StatsExport{Assignments: []AssignmentStats{
+		{Title: "締切なし"},
+		{Title: "締切あり", Due: "2026-10-16T12:00:00Z"},
+	}}
+	// 締切のない課題は3件、締切のある課題は 3 + 1 + len(curveOffsets) 件に寄与する
+	if got, want := s.sensitivity(), 3+4+len(curveOffsets); got != want {
+		t.Errorf("sensitivity() = %d, want %d", got, want)
+	}
+	if got := (&StatsExport{}).sensitivity(); got != 1 {
+		t.Errorf("課題がない場合の sensitivity() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// systemd のサービスとして動かすための機能です。serve と daemon は次のようなユニットで起動できます。
+//
+//	# ~/.config/systemd/user/classroom-api.service
+//	[Service]
+//	Type=notify
+//	ExecStart=/usr/local/bin/classroom-api -config %h/.config/classroom-api/config.json daemon
+//	Restart=on-failure
+//
+// WatchdogSec= には対応していません。同期が止まっていないかは daemon.healthcheck の死活監視で確かめます。
+//
+// serve はソケットアクティベーションで待ち受けるソケットを受け取れます。gRPC のソケットには FileDescriptorName=grpc を付けます。
+//
+//	# classroom-api-serve.socket
+//	[Socket]
+//	ListenStream=127.0.0.1:8000
+
+// sdNotify は systemd にサービスの状態 ("READY=1" や "STATUS=..." など) を知らせます (sd_notify)。
+// Type=notify のユニットから起動されていない (NOTIFY_SOCKET がない) 場合は何もしません。
+func sdNotify(state string) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return
+	}
+	if addr[0] == '@' {
+		// 抽象名前空間のソケット
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		log.Printf("systemd に状態を知らせられませんでした: %v", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		log.Printf("systemd に状態を知らせられませんでした: %v", err)
+	}
+}
+
+// activatedSocket は systemd のソケットアクティベーションで受け取ったソケットです。
+type activatedSocket struct {
+	name     string
+	listener net.Listener
+	used     bool
+}
+
+var (
+	activatedOnce    sync.Once
+	activatedMu      sync.Mutex
+	activatedSockets []*activatedSocket
+	activatedErr     error
+)
+
+// loadActivatedSockets は LISTEN_FDS で渡されたソケットを読み取ります。子プロセスに渡さないよう環境変数は消します。
+func loadActivatedSockets() ([]*activatedSocket, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	var sockets []*activatedSocket
+	for i := 0; i < n; i++ {
+		// 最初のソケットのファイル記述子は 3 (SD_LISTEN_FDS_START)
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(3+i), name)
+		// FileListener は記述子を複製するため、元の記述子は閉じる
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("systemd から受け取ったソケット %s を使えませんでした: %w", name, err)
+		}
+		sockets = append(sockets, &activatedSocket{name: name, listener: l})
+	}
+	return sockets, nil
+}
+
+// listenSocket は systemd から name という名前のソケットを受け取っていればそれを、なければ addr で待ち受けるソケットを返します。
+// name が "http" の場合は、ほかの用途の名前の付いていない最初のソケットも使います。
+func listenSocket(name, addr string) (net.Listener, error) {
+	activatedOnce.Do(func() { activatedSockets, activatedErr = loadActivatedSockets() })
+	if activatedErr != nil {
+		return nil, activatedErr
+	}
+	activatedMu.Lock()
+	defer activatedMu.Unlock()
+	var found *activatedSocket
+	for _, s := range activatedSockets {
+		if !s.used && s.name == name {
+			found = s
+			break
+		}
+	}
+	if found == nil && name == "http" {
+		for _, s := range activatedSockets {
+			if !s.used && s.name != "grpc" {
+				found = s
+				break
+			}
+		}
+	}
+	if found == nil {
+		return net.Listen("tcp", addr)
+	}
+	found.used = true
+	log.Printf("systemd から受け取ったソケット %s (%s) を使います", found.listener.Addr(), found.name)
+	return found.listener, nil
+}
+
+// journalWriter はログの各行の先頭に journald の優先度 ("<3>" など) を付けます。
+// 失敗を表すメッセージ (「〜できませんでした」「〜に失敗しました」) はエラー、「警告:」で始まるものは警告、ほかは情報になります。
+type journalWriter struct {
+	w io.Writer
+}
+
+func (jw *journalWriter) Write(p []byte) (int, error) {
+	// log は1回の Write で1つのメッセージを書くため、複数行のメッセージはすべての行を同じ優先度にする
+	msg := string(p)
+	prio := journalPriority(msg)
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(msg, "\n") {
+		if line == "" {
+			continue
+		}
+		b.WriteString(prio)
+		b.WriteString(line)
+	}
+	if _, err := io.WriteString(jw.w, b.String()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// journalPriority はログのメッセージに付ける syslog の優先度です。
+func journalPriority(line string) string {
+	switch {
+	case strings.Contains(line, "ませんでした") || strings.Contains(line, "失敗しました"):
+		return "<3>"
+	case strings.HasPrefix(line, "警告:"):
+		return "<4>"
+	}
+	return "<6>"
+}
+
+// setupJournalLogging は標準エラー出力が journald につながっている場合に、
+// ログの各行に優先度を付けて journalctl -p で絞り込めるようにします。
+func setupJournalLogging() {
+	if os.Getenv("JOURNAL_STREAM") == "" {
+		return
+	}
+	// JOURNAL_STREAM は子プロセスに引き継がれるため、端末から実行した場合などソケットでなければ使わない
+	if fi, err := os.Stderr.Stat(); err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return
+	}
+	log.SetOutput(&journalWriter{w: os.Stderr})
+}
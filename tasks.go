@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/tasks/v1"
+	"log"
+	"net/http"
+	"time"
+)
+
+// TasksConfig はGoogle ToDoリストとの同期の設定です。
+type TasksConfig struct {
+	// List は課題を追加するリストの名前です。なければ作成します。
+	List string `json:"list,omitempty"`
+}
+
+const defaultTaskListName = "Classroom"
+
+// tasksStateFile は課題と作成したタスクの対応を記録するファイルです。
+const tasksStateFile = "tasks.json"
+
+// tasksState は同期の状態です。
+type tasksState struct {
+	ListID string `json:"listId"`
+	// Tasks は課題のキーごとの、作成したタスクです。
+	Tasks map[string]*syncedTask `json:"tasks"`
+}
+
+type syncedTask struct {
+	ID string `json:"id"`
+	// Fingerprint は最後に反映した課題の状態です。変わった場合だけ更新します。
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed,omitempty"`
+}
+
+func runTasks(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("tasks", flag.ExitOnError)
+	interval := fs.Duration("interval", 0, "指定した間隔で同期を繰り返します (例: 30m)")
+	fs.Parse(args)
+
+	client := newHTTPClient(cfg)
+	srv := newClassroomService(ctx, client)
+	tsrv, err := tasks.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		log.Fatalf("Tasksクライアントを作成できませんでした: %v", err)
+	}
+
+	for {
+		items, err := loadAssignments(ctx, cfg, srv)
+		if err == nil {
+			err = syncTasks(ctx, cfg, tsrv, items, time.Now())
+		}
+		if err != nil {
+			log.Printf("ToDoリストの同期に失敗しました: %v", err)
+		}
+		if *interval <= 0 {
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// syncTasks は未提出の課題をタスクとして追加・更新し、提出済みになった課題のタスクを完了にします。
+// 課題とタスクの対応は tasks.json に記録し、繰り返し実行しても重複して追加しません。
+func syncTasks(ctx context.Context, cfg *Config, tsrv *tasks.Service, items []*Assignment, now time.Time) error {
+	path := cfg.dataPath(tasksStateFile)
+	state := &tasksState{}
+	if err := readJSONFile(path, state); err != nil {
+		return fmt.Errorf("ToDoリストの状態を読み取れませんでした: %w", err)
+	}
+	if state.Tasks == nil {
+		state.Tasks = map[string]*syncedTask{}
+	}
+	name := cfg.Tasks.List
+	if name == "" {
+		name = defaultTaskListName
+	}
+	if state.ListID == "" {
+		id, err := ensureTaskList(ctx, tsrv, name)
+		if err != nil {
+			return err
+		}
+		state.ListID = id
+	}
+
+	var added, updated, completed int
+	err := func() error {
+		for _, a := range items {
+			key := assignmentKey(a)
+			st, found := state.Tasks[key]
+			turnedIn := a.State() == "TURNED_IN" || a.State() == "RETURNED"
+			switch {
+			case turnedIn && found && !st.Completed:
+				t := &tasks.Task{Status: "completed"}
+				if _, err := tsrv.Tasks.Patch(state.ListID, st.ID, t).Context(ctx).Do(); err != nil && !isNotFound(err) {
+					return fmt.Errorf("タスクを完了にできませんでした (%s): %w", a.CourseWork.Title, err)
+				}
+				st.Completed = true
+				completed++
+			case !turnedIn && isCourseworkVisible(a, now):
+				t := taskFromAssignment(a)
+				fp := a.fingerprint()
+				if found && st.Fingerprint == fp {
+					continue
+				}
+				if found {
+					_, err := tsrv.Tasks.Patch(state.ListID, st.ID, t).Context(ctx).Do()
+					if err == nil {
+						st.Fingerprint = fp
+						st.Completed = false // 再提出のために取り消した場合
+						updated++
+						continue
+					}
+					if !isNotFound(err) {
+						return fmt.Errorf("タスクを更新できませんでした (%s): %w", a.CourseWork.Title, err)
+					}
+					// タスクが手動で削除されていた場合は追加し直す
+				}
+				created, err := tsrv.Tasks.Insert(state.ListID, t).Context(ctx).Do()
+				if err != nil {
+					return fmt.Errorf("タスクを追加できませんでした (%s): %w", a.CourseWork.Title, err)
+				}
+				state.Tasks[key] = &syncedTask{ID: created.Id, Fingerprint: fp}
+				added++
+			}
+		}
+		return nil
+	}()
+	// 途中で失敗しても、それまでに作ったタスクは記録して重複を防ぐ
+	if werr := writeJSONFile(path, state); werr != nil && err == nil {
+		err = fmt.Errorf("ToDoリストの状態を保存できませんでした: %w", werr)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("ToDoリストを同期しました (追加 %d件, 更新 %d件, 完了 %d件)", added, updated, completed)
+	return nil
+}
+
+// ensureTaskList は名前が name のリストのIDを返します。なければ作成します。
+func ensureTaskList(ctx context.Context, tsrv *tasks.Service, name string) (string, error) {
+	var id string
+	err := tsrv.Tasklists.List().Pages(ctx, func(r *tasks.TaskLists) error {
+		for _, l := range r.Items {
+			if l.Title == name && id == "" {
+				id = l.Id
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("ToDoリストの一覧を取得できませんでした: %w", err)
+	}
+	if id != "" {
+		return id, nil
+	}
+	l, err := tsrv.Tasklists.Insert(&tasks.TaskList{Title: name}).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("ToDoリストを作成できませんでした: %w", err)
+	}
+	return l.Id, nil
+}
+
+// taskFromAssignment は課題のタスクを作ります。
+// Tasks APIは締切の日付だけを保持するため、時刻はメモに書きます。
+func taskFromAssignment(a *Assignment) *tasks.Task {
+	c := a.CourseWork
+	t := &tasks.Task{
+		Title:  fmt.Sprintf("%s (%s)", c.Title, a.Course.Name),
+		Notes:  icalDescription(a),
+		Status: "needsAction",
+	}
+	if due, ok := a.Due(); ok {
+		y, m, d := due.Date()
+		t.Due = time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+		t.Notes = "締切: " + formatTime(due) + "\n" + t.Notes
+	}
+	return t
+}
+
+// isNotFound はAPIのエラーが 404 または 410 かどうかを返します。
+func isNotFound(err error) bool {
+	var e *googleapi.Error
+	return errors.As(err, &e) && (e.Code == http.StatusNotFound || e.Code == http.StatusGone)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// taskwarriorNamespace はTaskwarriorのタスクのUUIDを課題のIDから決めるための名前空間です。
+var taskwarriorNamespace = [16]byte{0x6b, 0x1f, 0x3c, 0x52, 0x0e, 0x4a, 0x4d, 0x8e, 0x9a, 0x35, 0x1c, 0x6e, 0x22, 0xd1, 0x7b, 0x90}
+
+// taskwarriorTask は task import が読み込むタスクです。
+type taskwarriorTask struct {
+	UUID        string   `json:"uuid"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Project     string   `json:"project,omitempty"`
+	Due         string   `json:"due,omitempty"`
+	Entry       string   `json:"entry"`
+	End         string   `json:"end,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// ClassroomID はUDA classroomid で、課題のIDです。
+	ClassroomID  string `json:"classroomid"`
+	ClassroomURL string `json:"classroomurl,omitempty"`
+}
+
+const taskwarriorUDAHelp = `Taskwarrior の設定 (~/.taskrc) に次のUDAを追加してください:
+  uda.classroomid.type=string
+  uda.classroomid.label=Classroom
+  uda.classroomurl.type=string
+  uda.classroomurl.label=URL
+`
+
+func runExportTaskwarrior(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export taskwarrior", flag.ExitOnError)
+	out := fs.String("out", "", "出力ファイル (省略時は標準出力)")
+	run := fs.Bool("import", false, "出力せずに task import を実行して読み込みます")
+	filter := addFilterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "使い方: classroom-api export taskwarrior [オプション]")
+		fs.PrintDefaults()
+		fmt.Fprint(fs.Output(), "\n"+taskwarriorUDAHelp)
+	}
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tasks := taskwarriorTasks(filter.apply(items), time.Now())
+
+	if *run {
+		cmd := exec.CommandContext(ctx, "task", "rc.confirmation=off", "import", "-")
+		pr, pw := io.Pipe()
+		cmd.Stdin = pr
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+		go func() {
+			pw.CloseWithError(writeTaskwarrior(pw, tasks))
+		}()
+		if err := cmd.Run(); err != nil {
+			log.Fatalf("task import に失敗しました: %v", err)
+		}
+		return
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("出力ファイルを作成できませんでした: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeTaskwarrior(w, tasks); err != nil {
+		log.Fatalf("Taskwarriorのタスクを書き込めませんでした: %v", err)
+	}
+}
+
+// taskwarriorTasks は未提出の課題を pending、提出済みの課題を completed のタスクにします。
+// UUIDは課題のIDから決まるため、繰り返し読み込んでも同じタスクが更新されます。
+func taskwarriorTasks(items []*Assignment, now time.Time) []*taskwarriorTask {
+	var tasks []*taskwarriorTask
+	pending := map[*Assignment]bool{}
+	for _, a := range pendingAssignments(items, now) {
+		pending[a] = true
+	}
+	for _, a := range items {
+		turnedIn := a.State() == "TURNED_IN" || a.State() == "RETURNED"
+		if !pending[a] && !turnedIn {
+			continue
+		}
+		c := a.CourseWork
+		t := &taskwarriorTask{
+			UUID:         taskwarriorUUID(c.CourseId + "/" + c.Id),
+			Description:  c.Title,
+			Status:       "pending",
+			Project:      strings.ReplaceAll(courseLabel(a), " ", "_"),
+			Entry:        taskwarriorTime(c.CreationTime, now),
+			Tags:         []string{"classroom"},
+			ClassroomID:  c.Id,
+			ClassroomURL: c.AlternateLink,
+		}
+		if due, ok := a.EffectiveDue(); ok {
+			t.Due = due.UTC().Format("20060102T150405Z")
+		}
+		if a.External() {
+			t.Tags = append(t.Tags, "external")
+		}
+		if turnedIn {
+			t.Status = "completed"
+			t.End = taskwarriorTime(a.Submission.UpdateTime, now)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// taskwarriorTime はAPIのRFC 3339形式の日時をTaskwarriorの形式にします。解析できなければ now を使います。
+func taskwarriorTime(s string, now time.Time) string {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		t = now
+	}
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// taskwarriorUUID は name から名前ベース (SHA-1、バージョン5) のUUIDを作ります。
+func taskwarriorUUID(name string) string {
+	h := sha1.New()
+	h.Write(taskwarriorNamespace[:])
+	h.Write([]byte(name))
+	u := h.Sum(nil)[:16]
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// writeTaskwarrior はタスクを task import が読み込める1行1タスクのJSONで書き出します。
+func writeTaskwarrior(w io.Writer, tasks []*taskwarriorTask) error {
+	enc := json.NewEncoder(w)
+	for _, t := range tasks {
+		if err := enc.Encode(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// TeamsConfig はMicrosoft Teamsへの通知の設定です。
+// チャンネルの Incoming Webhook (またはワークフローの「Webhook 要求を受信したとき」) の URL に Adaptive Card を送ります。
+type TeamsConfig struct {
+	WebhookURL string `json:"webhookUrl"`
+	// Channels はコースのID・別名・名前ごとの送り先の Webhook の URL です。
+	// コースに関係しない通知と、一致するコースがない通知は WebhookURL に送ります。
+	Channels map[string]string `json:"channels,omitempty"`
+	// Events は送るできごとの種類です。省略した場合は新しい課題、締切の変更、締切が近い課題、毎日のまとめ、
+	// リマインダー、授業後のまとめを送ります。
+	Events []string `json:"events,omitempty"`
+}
+
+// defaultTeamsEvents は TeamsConfig.Events を省略したときに送るできごとです。
+var defaultTeamsEvents = []string{"created", "due_changed", "due_soon", "daily_digest", "reminder", "digest"}
+
+// teamsNotifier は通知をTeamsのチャンネルに Adaptive Card で投稿します。
+type teamsNotifier struct {
+	cfg  TeamsConfig
+	rest *restClient
+}
+
+func newTeamsNotifier(cfg TeamsConfig) *teamsNotifier {
+	if len(cfg.Events) == 0 {
+		cfg.Events = defaultTeamsEvents
+	}
+	return &teamsNotifier{cfg: cfg, rest: &restClient{name: "Teams", client: &http.Client{Timeout: webhookTimeout}}}
+}
+
+func (n *teamsNotifier) Name() string { return "teams" }
+
+func (n *teamsNotifier) accepts(typ string) bool { return acceptsEvent(n.cfg.Events, typ) }
+
+func (n *teamsNotifier) Notify(ctx context.Context, ev *Event) error {
+	if !n.accepts(ev.Type) {
+		return nil
+	}
+	url := n.destination(ev.Assignment)
+	if url == "" {
+		return errors.New("Teamsの投稿先がありません (notify.teams.webhookUrl)")
+	}
+	msg := map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     teamsCard(ev),
+		}},
+	}
+	return n.rest.do(ctx, http.MethodPost, url, msg, nil)
+}
+
+// destination は課題のコースに対応する Webhook の URL を返します。対応する送り先がない場合は WebhookURL です。
+func (n *teamsNotifier) destination(a *Assignment) string {
+	if a != nil {
+		keys := make([]string, 0, len(n.cfg.Channels))
+		for k := range n.cfg.Channels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if matchCourse([]string{k}, a) {
+				return n.cfg.Channels[k]
+			}
+		}
+	}
+	return n.cfg.WebhookURL
+}
+
+// teamsCard は通知を Adaptive Card にします。課題の通知ではコースと締切を表にし、課題を開くボタンを付けます。
+func teamsCard(ev *Event) map[string]any {
+	body := []map[string]any{
+		{"type": "TextBlock", "text": ev.Title, "weight": "Bolder", "size": "Medium", "wrap": true},
+	}
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+	}
+	a := ev.Assignment
+	if a == nil || ev.Templated || (ev.Type != "created" && ev.Type != "due_changed" && ev.Type != "due_soon") {
+		// Adaptive Card の TextBlock は1つの改行を無視するため、段落に分ける
+		body = append(body, map[string]any{"type": "TextBlock", "text": strings.ReplaceAll(ev.Body, "\n", "\n\n"), "wrap": true})
+	} else {
+		due := formatDue(a)
+		if t, ok := a.Due(); ok && t.After(ev.Time) {
+			due += " (" + formatRemaining(t.Sub(ev.Time)) + ")"
+		}
+		if ev.Type == "due_changed" && ev.Previous != nil {
+			due = formatDue(ev.Previous) + " → " + due
+		}
+		body = append(body, map[string]any{
+			"type": "FactSet",
+			"facts": []map[string]string{
+				{"title": "コース", "value": a.Course.Name},
+				{"title": "締切", "value": due},
+			},
+		})
+	}
+	if a != nil && a.CourseWork.AlternateLink != "" {
+		card["actions"] = []map[string]any{{"type": "Action.OpenUrl", "title": "Classroomで開く", "url": a.CourseWork.AlternateLink}}
+	}
+	card["body"] = body
+	return card
+}
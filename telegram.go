@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	telegramAPI = "https://api.telegram.org/bot"
+	// telegramMaxText はメッセージの最大の文字数です。
+	telegramMaxText = 4096
+	// telegramPollTimeout は getUpdates のロングポーリングで待つ時間です。
+	telegramPollTimeout = 50 * time.Second
+)
+
+// TelegramConfig はTelegramのボットでの通知とコマンドの設定です。
+type TelegramConfig struct {
+	// Token は @BotFather で作ったボットのトークンです。省略した場合は環境変数 TELEGRAM_BOT_TOKEN を使います。
+	Token string `json:"token,omitempty"`
+	// ChatID は通知を送り、コマンドを受け付けるチャットのIDです。ほかのチャットからのコマンドには答えません。
+	ChatID string `json:"chatId"`
+	// Events は送るできごとの種類です。省略した場合は新しい課題、締切の変更、締切が近い課題、毎日のまとめ、
+	// リマインダー、授業後のまとめを送ります。
+	Events []string `json:"events,omitempty"`
+}
+
+// defaultTelegramEvents は TelegramConfig.Events を省略したときに送るできごとです。
+var defaultTelegramEvents = []string{"created", "due_changed", "due_soon", "daily_digest", "reminder", "digest"}
+
+// telegramNotifier は通知をTelegramのボットで送ります。
+type telegramNotifier struct {
+	cfg  TelegramConfig
+	rest *restClient
+}
+
+func newTelegramNotifier(cfg TelegramConfig) *telegramNotifier {
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("TELEGRAM_BOT_TOKEN")
+	}
+	if len(cfg.Events) == 0 {
+		cfg.Events = defaultTelegramEvents
+	}
+	// getUpdates はロングポーリングで待つため、タイムアウトはその分長くする
+	client := &http.Client{Timeout: telegramPollTimeout + webhookTimeout}
+	return &telegramNotifier{cfg: cfg, rest: &restClient{name: "Telegram", base: telegramAPI + cfg.Token, client: client}}
+}
+
+func (n *telegramNotifier) Name() string { return "telegram" }
+
+func (n *telegramNotifier) accepts(typ string) bool { return acceptsEvent(n.cfg.Events, typ) }
+
+func (n *telegramNotifier) Notify(ctx context.Context, ev *Event) error {
+	if !n.accepts(ev.Type) {
+		return nil
+	}
+	return n.send(ctx, n.cfg.ChatID, ev.Title+"\n"+ev.Body)
+}
+
+// send はチャットにテキストのメッセージを送ります。
+func (n *telegramNotifier) send(ctx context.Context, chatID, text string) error {
+	if n.cfg.Token == "" {
+		return errors.New("Telegramのボットのトークンがありません (notify.telegram.token または環境変数 TELEGRAM_BOT_TOKEN)")
+	}
+	if chatID == "" {
+		return errors.New("Telegramのチャットがありません (notify.telegram.chatId)")
+	}
+	text = truncateRunes(text, telegramMaxText)
+	var res telegramResponse[struct{}]
+	body := map[string]any{"chat_id": chatID, "text": text, "disable_web_page_preview": true}
+	return n.call(ctx, "/sendMessage", body, &res)
+}
+
+// telegramResponse はBot APIの応答です。
+type telegramResponse[T any] struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Result      T      `json:"result"`
+}
+
+// telegramUpdate はボットが受け取った更新です。メッセージ以外の更新は Message が nil です。
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// call はBot APIのメソッドを呼び出します。URLにトークンが含まれるため、エラーメッセージからは取り除きます。
+func (n *telegramNotifier) call(ctx context.Context, method string, body any, res interface{ err() error }) error {
+	if err := n.rest.do(ctx, http.MethodPost, method, body, res); err != nil {
+		if n.cfg.Token == "" {
+			return err
+		}
+		return errors.New(strings.ReplaceAll(err.Error(), n.cfg.Token, "<token>"))
+	}
+	return res.err()
+}
+
+func (r *telegramResponse[T]) err() error {
+	if !r.OK {
+		return fmt.Errorf("Telegramに送れませんでした: %s", r.Description)
+	}
+	return nil
+}
+
+// runTelegram はTelegramのボットとしてコマンドに答え続けます。
+// 課題は sync で保存したキャッシュから読み取るため、APIを呼ばずにすぐに答えられます。
+func runTelegram(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("telegram", flag.ExitOnError)
+	fs.Parse(args)
+
+	if cfg.Notify.Telegram == nil {
+		log.Fatal("設定ファイルに notify.telegram がありません")
+	}
+	n := newTelegramNotifier(*cfg.Notify.Telegram)
+	if n.cfg.Token == "" || n.cfg.ChatID == "" {
+		log.Fatal("notify.telegram の token と chatId を設定してください")
+	}
+	log.Print("Telegramのコマンドを待っています")
+	var offset int64
+	for ctx.Err() == nil {
+		var res telegramResponse[[]telegramUpdate]
+		body := map[string]any{"offset": offset, "timeout": int(telegramPollTimeout / time.Second), "allowed_updates": []string{"message"}}
+		if err := n.call(ctx, "/getUpdates", body, &res); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Telegramから更新を受け取れませんでした: %v", err)
+				sleepContext(ctx, pushRetryInterval)
+			}
+			continue
+		}
+		for _, u := range res.Result {
+			offset = u.UpdateID + 1
+			m := u.Message
+			if m == nil || strconv.FormatInt(m.Chat.ID, 10) != n.cfg.ChatID {
+				continue
+			}
+			reply := telegramReply(ctx, cfg, m.Text, time.Now())
+			if reply == "" {
+				continue
+			}
+			if err := n.send(ctx, n.cfg.ChatID, reply); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
+
+// telegramReply はコマンドへの返事を作ります。コマンドでないメッセージには空文字列を返します。
+func telegramReply(ctx context.Context, cfg *Config, text string, now time.Time) string {
+	cmd, _, _ := strings.Cut(strings.TrimSpace(text), " ")
+	// グループでは "/pending@ボット名" の形式で送られる
+	cmd, _, _ = strings.Cut(cmd, "@")
+	var title string
+	var day *time.Time
+	switch cmd {
+	case "/pending":
+		title = "未提出の課題"
+	case "/today":
+		title = "今日が締切の未提出の課題"
+		day = &now
+	case "/tomorrow":
+		title = "明日が締切の未提出の課題"
+		t := now.AddDate(0, 0, 1)
+		day = &t
+	case "/start", "/help":
+		return "/pending 未提出の課題\n/today 今日が締切の未提出の課題\n/tomorrow 明日が締切の未提出の課題"
+	default:
+		return ""
+	}
+	db, err := openCache(cfg)
+	if err != nil {
+		return fmt.Sprintf("キャッシュを開けませんでした: %v", err)
+	}
+	defer db.Close()
+	_, items, synced, err := readCache(ctx, db)
+	if errors.Is(err, errCacheEmpty) {
+		return "キャッシュがありません。sync を実行してください。"
+	}
+	if err == nil {
+		err = applyCourseConfig(cfg, items)
+	}
+	if err != nil {
+		return fmt.Sprintf("キャッシュを読み取れませんでした: %v", err)
+	}
+	var lines []string
+	for _, a := range pendingAssignments(items, now) {
+		if day != nil && !dueOnDay(a, *day) {
+			continue
+		}
+		line := fmt.Sprintf("・%s「%s」締切: %s", a.Course.Name, a.CourseWork.Title, formatDue(a))
+		if due, ok := a.Due(); ok {
+			line += " (" + formatRemaining(due.Sub(now)) + ")"
+		}
+		lines = append(lines, line)
+	}
+	body := "ありません。"
+	if len(lines) > 0 {
+		body = strings.Join(lines, "\n")
+	}
+	return fmt.Sprintf("%s (%d件)\n%s\n\n最終同期 %s (%s前)", title, len(lines), body, formatTime(synced), formatDuration(now.Sub(synced)))
+}
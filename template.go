@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData はテンプレートに渡す課題のデータです。
+// フィールドの一覧は templateHelp にも記載しています。追加・変更した場合は両方を更新してください。
+type TemplateData struct {
+	// ID は課題のID、Slug は「コース別名/課題スラッグ」形式の識別子です。
+	ID   string
+	Slug string
+	// Title, Description, Link は課題のタイトル、説明、Classroom のページのURLです。
+	Title       string
+	Description string
+	Link        string
+	// Type は課題の種類 (ASSIGNMENT など)、Topic はトピック名です。
+	Type   string
+	Topic  string
+	Points float64
+	// External は練習セットやアドオンなど外部のツールで取り組む課題かどうかです。
+	External bool
+	// Due は締切です。締切がない場合はゼロ値で、HasDue が false になります。
+	Due    time.Time
+	HasDue bool
+	// DueText は締切を「7/3 23:59」の形式で表したものです。締切がない場合は「なし」です。
+	DueText string
+	// DueIn は締切までの残り時間を「あと6時間0分」の形式で表したものです。
+	DueIn      string
+	Course     TemplateCourse
+	Submission TemplateSubmission
+}
+
+// TemplateCourse はテンプレートに渡すコースのデータです。
+type TemplateCourse struct {
+	ID      string
+	Name    string
+	Section string
+	Alias   string
+	Link    string
+}
+
+// TemplateSubmission はテンプレートに渡す提出物のデータです。
+type TemplateSubmission struct {
+	State      string
+	StateLabel string
+	Late       bool
+	Grade      float64
+	Link       string
+}
+
+// newTemplateData は課題からテンプレートのデータを作ります。
+func newTemplateData(a *Assignment, now time.Time) *TemplateData {
+	c := a.CourseWork
+	alias, _, _ := strings.Cut(a.Slug, "/")
+	d := &TemplateData{
+		ID:          c.Id,
+		Slug:        a.Slug,
+		Title:       c.Title,
+		Description: c.Description,
+		Link:        c.AlternateLink,
+		Type:        c.WorkType,
+		Topic:       a.Topic,
+		Points:      c.MaxPoints,
+		External:    a.External(),
+		DueText:     "なし",
+		Course: TemplateCourse{
+			ID:      a.Course.Id,
+			Name:    a.Course.Name,
+			Section: a.Course.Section,
+			Alias:   alias,
+			Link:    a.Course.AlternateLink,
+		},
+	}
+	if due, ok := a.EffectiveDue(); ok {
+		d.Due, d.HasDue = due, true
+		d.DueText = due.Format("1/2 15:04")
+		d.DueIn = formatRemaining(due.Sub(now))
+	}
+	if s := a.Submission; s != nil {
+		d.Submission = TemplateSubmission{
+			State:      s.State,
+			StateLabel: submissionLabel(s),
+			Late:       s.Late,
+			Grade:      s.AssignedGrade,
+			Link:       s.AlternateLink,
+		}
+	}
+	return d
+}
+
+// templateHelp は -template help で表示するテンプレートのデータの説明です。
+const templateHelp = `テンプレートは Go の text/template の形式で、課題ごとに次のデータが渡されます。
+
+  .ID                    課題のID
+  .Slug                  「コース別名/課題スラッグ」形式の識別子
+  .Title                 タイトル
+  .Description           説明
+  .Link                  Classroom の課題のページのURL
+  .Type                  種類 (ASSIGNMENT, SHORT_ANSWER_QUESTION, MULTIPLE_CHOICE_QUESTION)
+  .Topic                 トピック名 (なければ空)
+  .Points                配点
+  .External              練習セットやアドオンなど外部のツールで取り組む課題かどうか
+  .Due                   締切 (time.Time、猶予期間を含む。{{.Due.Format "01/02"}} のように使えます)
+  .HasDue                締切があるかどうか
+  .DueText               締切 (「7/3 23:59」の形式、なければ「なし」)
+  .DueIn                 締切までの残り時間 (「あと6時間0分」の形式)
+  .Course.ID             コースのID
+  .Course.Name           コース名
+  .Course.Section        セクション
+  .Course.Alias          コースの別名
+  .Course.Link           Classroom のコースのページのURL
+  .Submission.State      提出物の状態 (NEW, CREATED, TURNED_IN, RETURNED, RECLAIMED_BY_STUDENT)
+  .Submission.StateLabel 提出物の状態の表示名 (「未提出」など)
+  .Submission.Late       遅れて提出したかどうか
+  .Submission.Grade      成績
+  .Submission.Link       提出物のページのURL
+
+例: classroom-api list -template '{{.Course.Name}}: {{.Title}} ({{.DueIn}})'
+`
+
+// parseTemplate はユーザーが指定したテンプレートを解析します。
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
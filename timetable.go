@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Lesson は時間割の1コマです。
+type Lesson struct {
+	// Course はコースID、コースの別名、またはコース名です。
+	Course string `json:"course"`
+	// Weekday は曜日です ("月" または "mon" の形式)。
+	Weekday string `json:"weekday"`
+	// Start と End は授業の開始・終了時刻です ("10:40" の形式)。
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"日": time.Sunday, "月": time.Monday, "火": time.Tuesday, "水": time.Wednesday,
+	"木": time.Thursday, "金": time.Friday, "土": time.Saturday,
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// matches は授業が課題のコースのものかどうかを返します。
+func (l *Lesson) matches(a *Assignment) bool {
+	alias, _, _ := strings.Cut(a.Slug, "/")
+	return l.Course == a.Course.Id || l.Course == alias || l.Course == a.Course.Name
+}
+
+// at は day と同じ週の同じ曜日の授業の開始・終了時刻を返します。
+func (l *Lesson) at(day time.Time) (start, end time.Time, err error) {
+	wd, ok := weekdayNames[strings.ToLower(strings.TrimSuffix(l.Weekday, "曜日"))]
+	if !ok {
+		return start, end, fmt.Errorf("曜日を解析できませんでした: %s", l.Weekday)
+	}
+	y, m, d := day.Date()
+	base := time.Date(y, m, d, 0, 0, 0, 0, day.Location()).AddDate(0, 0, int(wd-day.Weekday()))
+	parse := func(s string) (time.Time, error) {
+		t, err := time.Parse("15:04", s)
+		if err != nil {
+			return t, fmt.Errorf("時刻を解析できませんでした: %s", s)
+		}
+		return base.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute), nil
+	}
+	if start, err = parse(l.Start); err != nil {
+		return
+	}
+	end, err = parse(l.End)
+	return
+}
+
+// nextLesson は now より後で最初に始まる、課題のコースの授業の開始時刻を返します。
+func nextLesson(tt []Lesson, a *Assignment, now time.Time) (time.Time, bool) {
+	var next time.Time
+	for i := range tt {
+		l := &tt[i]
+		if !l.matches(a) {
+			continue
+		}
+		start, _, err := l.at(now)
+		if err != nil {
+			continue
+		}
+		if !start.After(now) {
+			start = start.AddDate(0, 0, 7)
+		}
+		if next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// lessonsEnded は (from, to] の間に終わった授業を返します。
+func lessonsEnded(tt []Lesson, from, to time.Time) []*Lesson {
+	var ended []*Lesson
+	for i := range tt {
+		l := &tt[i]
+		// 週をまたぐ場合に備えて前の週の授業も確認する
+		for _, day := range []time.Time{to.AddDate(0, 0, -7), to} {
+			_, end, err := l.at(day)
+			if err == nil && end.After(from) && !end.After(to) {
+				ended = append(ended, l)
+				break
+			}
+		}
+	}
+	return ended
+}
+
+// relativeDay は t を now から見た「今日」「明日」などの表現で返します。
+func relativeDay(t, now time.Time) string {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	days := int(t.Sub(today).Hours() / 24)
+	switch {
+	case t.Before(today):
+		return formatTime(t)
+	case days == 0:
+		return "今日 " + t.Format("15:04")
+	case days == 1:
+		return "明日 " + t.Format("15:04")
+	case days == 2:
+		return "明後日 " + t.Format("15:04")
+	case days < 7:
+		return fmt.Sprintf("%s曜日 %s", weekdays[t.Weekday()], t.Format("15:04"))
+	}
+	return formatTime(t)
+}
+
+// lessonReminder は次の授業を基準にしたリマインダーの文面を返します。
+func lessonReminder(tt []Lesson, a *Assignment, now time.Time) string {
+	title := a.CourseWork.Title
+	due, hasDue := a.Due()
+	next, ok := nextLesson(tt, a, now)
+	switch {
+	case ok && hasDue && due.Before(next):
+		return fmt.Sprintf("「%s」の締切 (%s) は次の%sの授業 (%s) より前です。まだ未提出です。", title, relativeDay(due, now), a.Course.Name, relativeDay(next, now))
+	case ok:
+		return fmt.Sprintf("次の%sの授業は%sです。「%s」はまだ未提出です。", a.Course.Name, relativeDay(next, now), title)
+	case hasDue:
+		return fmt.Sprintf("「%s」(%s) の締切は%sです。まだ未提出です。", title, a.Course.Name, relativeDay(due, now))
+	}
+	return fmt.Sprintf("「%s」(%s) はまだ未提出です。", title, a.Course.Name)
+}
+
+func runRemind(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("remind", flag.ExitOnError)
+	follow := fs.Bool("follow", false, "実行し続け、授業が終わるたびにそのコースの未提出課題をまとめて通知します")
+	fs.Parse(args)
+
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	notifiers := newNotifiers(cfg)
+	if !*follow {
+		items, err := loadAssignments(ctx, cfg, srv)
+		if err != nil {
+			log.Fatal(err)
+		}
+		now := time.Now()
+		pending := pendingAssignments(items, now)
+		var lines []string
+		for _, a := range pending {
+			lines = append(lines, lessonReminder(cfg.Timetable, a, now))
+		}
+		if len(lines) == 0 {
+			return
+		}
+		ev := &Event{Type: "reminder", Items: pending, Title: "未提出の課題があります", Body: strings.Join(lines, "\n"), Time: now}
+		if err := notifyAll(ctx, notifiers, ev); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(cfg.Timetable) == 0 {
+		log.Fatal("設定ファイルに timetable がありません")
+	}
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Minute):
+		}
+		now := time.Now()
+		ended := lessonsEnded(cfg.Timetable, last, now)
+		last = now
+		if len(ended) == 0 {
+			continue
+		}
+		items, err := loadAssignments(ctx, cfg, srv)
+		if err != nil {
+			log.Printf("課題を取得できませんでした: %v", err)
+			continue
+		}
+		pending := pendingAssignments(items, now)
+		for _, l := range ended {
+			if ev := lessonDigest(cfg.Timetable, l, pending, now); ev != nil {
+				notifyAll(ctx, notifiers, ev)
+			}
+		}
+	}
+}
+
+// lessonDigest は授業が終わった直後に送る、そのコースの未提出課題のまとめを作ります。
+func lessonDigest(tt []Lesson, l *Lesson, pending []*Assignment, now time.Time) *Event {
+	var lines []string
+	var items []*Assignment
+	course := l.Course
+	for _, a := range pending {
+		if !l.matches(a) {
+			continue
+		}
+		items = append(items, a)
+		course = a.Course.Name
+		lines = append(lines, "・"+lessonReminder(tt, a, now))
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return &Event{
+		Type:  "digest",
+		Items: items,
+		Title: fmt.Sprintf("%sの授業が終わりました (未提出 %d件)", course, len(lines)),
+		Body:  strings.Join(lines, "\n"),
+		Time:  now,
+	}
+}
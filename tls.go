@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/acme/autocert"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// autocertDir は Autocert で取得した証明書を保存するディレクトリです。
+const autocertDir = "autocert"
+
+// checkTLS は証明書の設定の組み合わせを確かめます。
+func (c *ServerConfig) checkTLS() error {
+	switch {
+	case (c.TLSCert == "") != (c.TLSKey == ""):
+		return errors.New("-tls-cert と -tls-key は両方指定してください")
+	case c.TLSCert != "" && len(c.Autocert) > 0:
+		return errors.New("-tls-cert と -autocert は同時に使えません")
+	}
+	return nil
+}
+
+// useTLS はHTTPSで待ち受けるかどうかを返します。
+func (c *ServerConfig) useTLS() bool {
+	return c.TLSCert != "" || len(c.Autocert) > 0
+}
+
+// listenAddr は待ち受けるアドレスです。
+func (c *ServerConfig) listenAddr() string {
+	switch {
+	case c.Listen != "":
+		return c.Listen
+	case len(c.Autocert) > 0:
+		return ":443"
+	}
+	return ":8000"
+}
+
+// baseURL はログに表示するサーバーのURLです。
+func (c *ServerConfig) baseURL() string {
+	scheme := "http"
+	if c.useTLS() {
+		scheme = "https"
+	}
+	host, port, err := net.SplitHostPort(c.listenAddr())
+	if err != nil {
+		return scheme + "://" + c.listenAddr()
+	}
+	switch {
+	case len(c.Autocert) > 0:
+		host = c.Autocert[0]
+	case host == "" || host == "0.0.0.0" || host == "::":
+		host = "localhost"
+	}
+	if (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		return scheme + "://" + host
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
+
+// serverTLSConfig は設定に応じたHTTPSの設定を返します。HTTPSを使わない場合は nil です。
+// HTTPとgRPCで同じ証明書を使います。
+func serverTLSConfig(cfg *Config) (*tls.Config, error) {
+	sc := &cfg.Server
+	switch {
+	case len(sc.Autocert) > 0:
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(sc.Autocert...),
+			Cache:      autocert.DirCache(cfg.dataPath(autocertDir)),
+		}
+		return m.TLSConfig(), nil
+	case sc.TLSCert != "":
+		cert, err := tls.LoadX509KeyPair(sc.TLSCert, sc.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("証明書を読み込めませんでした: %w", err)
+		}
+		return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+	}
+	return nil, nil
+}
+
+// serveHTTP は tlsConfig があればHTTPSで、なければHTTPで l の接続を処理します。hs.Shutdown で終了した場合は nil を返します。
+func serveHTTP(hs *http.Server, l net.Listener, cfg *Config, tlsConfig *tls.Config) error {
+	var err error
+	switch {
+	case tlsConfig != nil:
+		hs.TLSConfig = tlsConfig
+		err = hs.ServeTLS(l, "", "")
+	default:
+		if host, _, _ := net.SplitHostPort(l.Addr().String()); !isLoopback(host) && cfg.Server.MultiUser {
+			// 複数ユーザーモードのセッションのクッキーが平文で流れる
+			log.Printf("警告: HTTPSを使わずに %s で待ち受けます。-tls-cert か -autocert を指定してください", l.Addr())
+		}
+		err = hs.Serve(l)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// isLoopback は host がこのマシンからしか接続できないアドレスかどうかを返します。
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// TodoistConfig はTodoistへの書き出しの設定です。
+type TodoistConfig struct {
+	// Token はTodoistのAPIトークンです。環境変数 TODOIST_API_TOKEN でも指定できます。
+	Token string `json:"token,omitempty"`
+	// Project はタスクを追加するプロジェクトの名前です。なければ作成します。
+	Project string `json:"project,omitempty"`
+	// Labels はタスクに付けるラベルです。コースの別名のラベルは常に付けます。
+	Labels []string `json:"labels,omitempty"`
+}
+
+const (
+	todoistAPI            = "https://api.todoist.com/rest/v2"
+	defaultTodoistProject = "Classroom"
+	// todoistStateFile は課題と作成したTodoistのタスクの対応を記録するファイルです。
+	todoistStateFile = "todoist.json"
+)
+
+// todoistState は同期の状態です。
+type todoistState struct {
+	ProjectID string                 `json:"projectId"`
+	Tasks     map[string]*syncedTask `json:"tasks"`
+}
+
+// todoistTask はTodoist REST APIのタスクです。
+type todoistTask struct {
+	ID          string   `json:"id,omitempty"`
+	ProjectID   string   `json:"project_id,omitempty"`
+	Content     string   `json:"content"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels"`
+	DueDatetime string   `json:"due_datetime,omitempty"`
+	DueString   string   `json:"due_string,omitempty"`
+}
+
+type todoistProject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func runExportTodoist(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export todoist", flag.ExitOnError)
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	token := cfg.Todoist.Token
+	if token == "" {
+		token = os.Getenv("TODOIST_API_TOKEN")
+	}
+	if token == "" {
+		log.Fatal("TodoistのAPIトークンがありません (設定ファイルの todoist.token または環境変数 TODOIST_API_TOKEN)")
+	}
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tc := &restClient{name: "Todoist", base: todoistAPI, token: token}
+	if err := syncTodoist(ctx, cfg, tc, items, filter, time.Now()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// syncTodoist は未提出の課題をTodoistのタスクとして追加・更新し、提出済みになった課題のタスクを完了にします。
+// 課題とタスクの対応は todoist.json に記録し、繰り返し実行しても重複して追加しません。
+func syncTodoist(ctx context.Context, cfg *Config, tc *restClient, items []*Assignment, filter *Filter, now time.Time) error {
+	path := cfg.dataPath(todoistStateFile)
+	state := &todoistState{}
+	if err := readJSONFile(path, state); err != nil {
+		return fmt.Errorf("Todoistの同期の状態を読み取れませんでした: %w", err)
+	}
+	if state.Tasks == nil {
+		state.Tasks = map[string]*syncedTask{}
+	}
+	if state.ProjectID == "" {
+		name := cfg.Todoist.Project
+		if name == "" {
+			name = defaultTodoistProject
+		}
+		id, err := ensureTodoistProject(ctx, tc, name)
+		if err != nil {
+			return err
+		}
+		state.ProjectID = id
+	}
+
+	var added, updated, completed int
+	err := func() error {
+		for _, a := range items {
+			key := assignmentKey(a)
+			st, found := state.Tasks[key]
+			turnedIn := a.State() == "TURNED_IN" || a.State() == "RETURNED"
+			switch {
+			case turnedIn && found && !st.Completed:
+				if err := tc.do(ctx, http.MethodPost, "/tasks/"+st.ID+"/close", nil, nil); err != nil && !isRESTNotFound(err) {
+					return fmt.Errorf("タスクを完了にできませんでした (%s): %w", a.CourseWork.Title, err)
+				}
+				st.Completed = true
+				completed++
+			case !turnedIn && isCourseworkVisible(a, now) && filter.match(a):
+				fp := a.fingerprint()
+				if found && st.Fingerprint == fp {
+					continue
+				}
+				t := todoistTaskFromAssignment(cfg, a)
+				if found {
+					err := tc.do(ctx, http.MethodPost, "/tasks/"+st.ID, t, nil)
+					if err == nil && st.Completed {
+						err = tc.do(ctx, http.MethodPost, "/tasks/"+st.ID+"/reopen", nil, nil)
+					}
+					if err == nil {
+						st.Fingerprint, st.Completed = fp, false
+						updated++
+						continue
+					}
+					if !isRESTNotFound(err) {
+						return fmt.Errorf("タスクを更新できませんでした (%s): %w", a.CourseWork.Title, err)
+					}
+					// タスクが手動で削除されていた場合は追加し直す
+				}
+				t.ProjectID = state.ProjectID
+				var created todoistTask
+				if err := tc.do(ctx, http.MethodPost, "/tasks", t, &created); err != nil {
+					return fmt.Errorf("タスクを追加できませんでした (%s): %w", a.CourseWork.Title, err)
+				}
+				state.Tasks[key] = &syncedTask{ID: created.ID, Fingerprint: fp}
+				added++
+			}
+		}
+		return nil
+	}()
+	// 途中で失敗しても、それまでに作ったタスクは記録して重複を防ぐ
+	if werr := writeJSONFile(path, state); werr != nil && err == nil {
+		err = fmt.Errorf("Todoistの同期の状態を保存できませんでした: %w", werr)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Todoistに書き出しました (追加 %d件, 更新 %d件, 完了 %d件)", added, updated, completed)
+	return nil
+}
+
+// todoistTaskFromAssignment は課題のタスクを作ります。
+func todoistTaskFromAssignment(cfg *Config, a *Assignment) *todoistTask {
+	c := a.CourseWork
+	t := &todoistTask{
+		Content:     c.Title,
+		Description: icalDescription(a),
+		Labels:      append([]string{courseLabel(a)}, cfg.Todoist.Labels...),
+	}
+	if due, ok := a.Due(); ok {
+		t.DueDatetime = due.UTC().Format(time.RFC3339)
+	} else {
+		t.DueString = "no date"
+	}
+	return t
+}
+
+// courseLabel はコースを表すラベル (スラッグのコース部分) を返します。
+func courseLabel(a *Assignment) string {
+	alias, _, _ := strings.Cut(a.Slug, "/")
+	return alias
+}
+
+// ensureTodoistProject は名前が name のプロジェクトのIDを返します。なければ作成します。
+func ensureTodoistProject(ctx context.Context, tc *restClient, name string) (string, error) {
+	var projects []todoistProject
+	if err := tc.do(ctx, http.MethodGet, "/projects", nil, &projects); err != nil {
+		return "", fmt.Errorf("Todoistのプロジェクトを取得できませんでした: %w", err)
+	}
+	for _, p := range projects {
+		if p.Name == name {
+			return p.ID, nil
+		}
+	}
+	var p todoistProject
+	if err := tc.do(ctx, http.MethodPost, "/projects", map[string]string{"name": name}, &p); err != nil {
+		return "", fmt.Errorf("Todoistのプロジェクトを作成できませんでした: %w", err)
+	}
+	return p.ID, nil
+}
@@ -4,10 +4,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/classroom/v1"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"log"
 	"net/http"
@@ -69,23 +72,62 @@ func saveToken(path string, token *oauth2.Token) {
 	json.NewEncoder(f).Encode(token)
 }
 
+var (
+	pageSize  = flag.Int64("page-size", 0, "1回のリクエストで取得する課題の件数 (0 の場合はAPIの既定)")
+	itemLimit = flag.Int("limit", 0, "コースごとに取得する課題の最大の件数 (0 の場合はすべて)")
+)
+
+// courseWorkFields と submissionFields は一覧で返させるフィールドです (部分レスポンス)。
+// 説明や添付ファイルを返させないことで、応答が小さくなります。
+var (
+	courseWorkFields = []googleapi.Field{"nextPageToken", "courseWork(id,title,dueDate,dueTime,alternateLink)"}
+	submissionFields = []googleapi.Field{"nextPageToken", "studentSubmissions(courseWorkId,state)"}
+)
+
+// errItemLimit は -limit の件数を取得し終えたことを表します。
+var errItemLimit = errors.New("item limit reached")
+
+// listAllCourseWork はコースの課題をすべてのページから取得します。-limit を指定した場合はその件数までです。
+func listAllCourseWork(srv *classroom.Service, courseId string, ctx context.Context) ([]*classroom.CourseWork, error) {
+	// 公開されている課題だけを、表示に使うフィールドに絞って返させる
+	call := srv.Courses.CourseWork.List(courseId).CourseWorkStates("PUBLISHED").Fields(courseWorkFields...)
+	if *pageSize > 0 {
+		call = call.PageSize(*pageSize)
+	}
+	var items []*classroom.CourseWork
+	err := call.Pages(ctx, func(r *classroom.ListCourseWorkResponse) error {
+		defer trace.StartRegion(ctx, "listCourseWorkPage").End()
+		items = append(items, r.CourseWork...)
+		if *itemLimit > 0 && len(items) >= *itemLimit {
+			items = items[:*itemLimit]
+			return errItemLimit
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errItemLimit) {
+		return nil, err
+	}
+	return items, nil
+}
+
 func listCourseWorkFromCourseId(srv *classroom.Service, courseId string, ctx context.Context) {
 	defer trace.StartRegion(ctx, "list coursework from "+courseId).End()
-	r, err := srv.Courses.CourseWork.List(courseId).Do()
+	courseWork, err := listAllCourseWork(srv, courseId, ctx)
 	if err != nil {
 		log.Fatalf("課題を取得できませんでした: %v", err)
 	}
-	if len(r.CourseWork) <= 0 {
-		return
+	pending, err := listPending(srv, courseId, ctx)
+	if err != nil {
+		log.Fatalf("提出物を取得できませんでした: %v", err)
 	}
-	for _, c := range r.CourseWork {
-		if isVisible, err := isCourseworkVisible(srv, c, ctx); isVisible && err == nil {
+	for _, c := range courseWork {
+		if isVisible, err := isCourseworkVisible(c, pending, ctx); isVisible && err == nil {
 			fmt.Printf("%s (%s) link:%s\n", c.Title, c.Id, c.AlternateLink)
 		}
 	}
 }
 
-func isCourseworkVisible(srv *classroom.Service, c *classroom.CourseWork, ctx context.Context) (bool, error) {
+func isCourseworkVisible(c *classroom.CourseWork, pending map[string]bool, ctx context.Context) (bool, error) {
 	defer trace.StartRegion(ctx, "work").End()
 	var date string
 	if c.DueDate != nil {
@@ -105,19 +147,28 @@ func isCourseworkVisible(srv *classroom.Service, c *classroom.CourseWork, ctx co
 	if parsedDate.Before(currentDate) {
 		return false, nil
 	}
-	wr, err := srv.Courses.CourseWork.StudentSubmissions.List(c.CourseId, c.Id).Do()
-	if err != nil {
-		return false, err
-	}
-	for _, s := range wr.StudentSubmissions {
-		if s.State == "TURNED_IN" {
-			return false, nil
+	//課題の提出状況を確認して、まだ提出していない課題だけを表示する
+	return pending[c.Id], nil
+}
+
+// listPending はコースの課題のうち、自分がまだ提出していない課題のIDを返します。
+// 課題ごとに提出物を取得する代わりに、courseWorkId に "-" を指定してコースの提出物を1回の一覧で取得し、
+// 自分の未提出の提出物だけを返すようAPIに絞り込ませます。結果が複数ページになる場合もすべてのページを読みます。
+func listPending(srv *classroom.Service, courseId string, ctx context.Context) (map[string]bool, error) {
+	defer trace.StartRegion(ctx, "listSubmissions").End()
+	pending := map[string]bool{}
+	call := srv.Courses.CourseWork.StudentSubmissions.List(courseId, "-").UserId("me").States("CREATED", "NEW", "RECLAIMED_BY_STUDENT").Fields(submissionFields...)
+	err := call.Pages(ctx, func(r *classroom.ListStudentSubmissionsResponse) error {
+		for _, s := range r.StudentSubmissions {
+			pending[s.CourseWorkId] = true
 		}
-	}
-	return true, nil
+		return nil
+	})
+	return pending, err
 }
 
 func main() {
+	flag.Parse()
 	f, err := os.Create("trace.out")
 	if err != nil {
 		log.Fatalln("Error:", err)
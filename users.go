@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/oauth2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// usersFile は複数ユーザーモードのユーザーごとのトークンとセッションを記録するファイルです。
+const usersFile = "users.json"
+
+// sessionCookie は複数ユーザーモードでログインしたユーザーを表すクッキーです。
+const sessionCookie = "classroom_api_session"
+
+// sessionTTL はログインしたセッションの有効期間です。
+const sessionTTL = 30 * 24 * time.Hour
+
+// serverUser はサーバーにログインしたGoogleアカウントです。
+type serverUser struct {
+	// ID はGoogleアカウントのID (IDトークンの sub) です。
+	ID    string        `json:"id"`
+	Email string        `json:"email"`
+	Token *oauth2.Token `json:"token"`
+}
+
+// userSession はログインしたセッションです。
+type userSession struct {
+	User    string    `json:"user"`
+	Expires time.Time `json:"expires"`
+}
+
+// userStore は users.json に保存したユーザーとセッションです。セッションはIDのハッシュで保存します。
+type userStore struct {
+	path     string
+	mu       sync.Mutex
+	Users    map[string]*serverUser  `json:"users"`
+	Sessions map[string]*userSession `json:"sessions"`
+}
+
+func loadUserStore(cfg *Config) (*userStore, error) {
+	s := &userStore{path: cfg.dataPath(usersFile)}
+	if err := readJSONFile(s.path, s); err != nil {
+		return nil, fmt.Errorf("ユーザーを読み込めませんでした: %w", err)
+	}
+	if s.Users == nil {
+		s.Users = map[string]*serverUser{}
+	}
+	if s.Sessions == nil {
+		s.Sessions = map[string]*userSession{}
+	}
+	return s, nil
+}
+
+func (s *userStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.Users)
+}
+
+// find はIDまたはメールアドレスが一致するユーザーを返します。
+func (s *userStore) find(idOrEmail string) *serverUser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.Users[idOrEmail]; ok {
+		return u
+	}
+	for _, u := range s.Users {
+		if strings.EqualFold(u.Email, idOrEmail) {
+			return u
+		}
+	}
+	return nil
+}
+
+// token はユーザーの保存済みのトークンを返します。
+func (s *userStore) token(id string) *oauth2.Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.Users[id]; ok {
+		return u.Token
+	}
+	return nil
+}
+
+// setToken はユーザーのトークンを更新して保存します。
+func (s *userStore) setToken(id string, tok *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.Users[id]
+	if !ok {
+		return fmt.Errorf("ユーザーが見つかりません: %s", id)
+	}
+	u.Token = tok
+	return writeJSONFile(s.path, s)
+}
+
+// login はユーザーを登録または更新し、新しいセッションのIDを返します。期限切れのセッションは削除します。
+func (s *userStore) login(u *serverUser, now time.Time) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Users[u.ID] = u
+	for h, ss := range s.Sessions {
+		if !now.Before(ss.Expires) {
+			delete(s.Sessions, h)
+		}
+	}
+	s.Sessions[hashSessionID(id)] = &userSession{User: u.ID, Expires: now.Add(sessionTTL)}
+	if err := writeJSONFile(s.path, s); err != nil {
+		return "", fmt.Errorf("ユーザーを保存できませんでした: %w", err)
+	}
+	return id, nil
+}
+
+// logout はセッションを削除します。
+func (s *userStore) logout(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Sessions, hashSessionID(id))
+	return writeJSONFile(s.path, s)
+}
+
+// session はセッションIDのユーザーを返します。無効または期限切れの場合は nil を返します。
+func (s *userStore) session(id string, now time.Time) *serverUser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ss, ok := s.Sessions[hashSessionID(id)]
+	if !ok || !now.Before(ss.Expires) {
+		return nil
+	}
+	return s.Users[ss.User]
+}
+
+func hashSessionID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionUser はリクエストのセッションのクッキーのユーザーを返します。
+func (s *server) sessionUser(r *http.Request) *serverUser {
+	if s.users == nil {
+		return nil
+	}
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	return s.users.session(c.Value, time.Now())
+}
+
+// idTokenUser はトークンに含まれるIDトークンからGoogleアカウントを取り出します。
+// IDトークンはGoogleのトークンエンドポイントからTLSで直接受け取ったものなので、署名は確認しません。
+func idTokenUser(tok *oauth2.Token) (*serverUser, error) {
+	raw, _ := tok.Extra("id_token").(string)
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("IDトークンがありません")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("IDトークンを解析できませんでした: %w", err)
+	}
+	var claims struct {
+		Sub   string `json:"sub"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, fmt.Errorf("IDトークンを解析できませんでした: %w", err)
+	}
+	if claims.Sub == "" {
+		return nil, fmt.Errorf("IDトークンにアカウントのIDがありません")
+	}
+	return &serverUser{ID: claims.Sub, Email: claims.Email, Token: tok}, nil
+}
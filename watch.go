@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	clearScreen = "\033[H\033[2J"
+	highlightOn = "\033[1;7m"
+	colorReset  = "\033[0m"
+	// maxWatchNotices は一覧の上に残しておくお知らせの件数です。古いものから消します。
+	maxWatchNotices = 10
+)
+
+// addNotice はお知らせを追加し、maxWatchNotices を超えた古いお知らせを消します。
+func addNotice(notices []string, now time.Time, text string) []string {
+	notices = append(notices, now.Format("01/02 15:04 ")+text)
+	if over := len(notices) - maxWatchNotices; over > 0 {
+		notices = append(notices[:0], notices[over:]...)
+	}
+	return notices
+}
+
+// watchList は課題を繰り返し再取得して一覧を描き直します。
+// 前回の取得から新しく現れた課題や変更された課題は強調表示します。
+// interval が 0 の場合はコースごとに締切の近さと時間帯に応じて間隔を調整します。
+func watchList(ctx context.Context, cfg *Config, srv *classroom.Service, filter *Filter, opts *outputOptions, interval time.Duration) {
+	mode := syncMode{Reason: "間隔が指定されています"}
+	// changed はプッシュ通知で課題の変更を知らされたコースのIDです。プッシュ通知を使わない場合は nil のままです。
+	var changed chan string
+	var regs *pushRegistrations
+	if interval == 0 {
+		courses, err := listCourses(ctx, srv, cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var psrv *pubsub.Service
+		mode, regs, psrv = setupSyncMode(ctx, srv, cfg, courses)
+		if regs != nil {
+			defer regs.close()
+			changed = make(chan string, 16)
+			go receivePush(ctx, psrv, cfg.Push.Subscription, func(courseID string) {
+				select {
+				case changed <- courseID:
+				case <-ctx.Done():
+				}
+			})
+		}
+	}
+	w, err := newWatcher(ctx, cfg, srv, newCourseScheduler(&cfg.Polling, mode, interval))
+	if err != nil {
+		log.Fatal(err)
+	}
+	w.regs = regs
+	fetch := func(ctx context.Context, courses, due []*classroom.Course) ([]*Assignment, error) {
+		return loadCourseAssignments(ctx, cfg, srv, due)
+	}
+	var prev map[string]string
+	for {
+		items, synced, err := w.tick(ctx, fetch, time.Now())
+		now := time.Now()
+		opts.now = now
+		var pending []*Assignment
+		if err == nil {
+			pending = filter.apply(pendingAssignments(items, now))
+		}
+		next := w.sched.wait(now)
+		saveHealth(cfg, newHealth(mode, now, err, next))
+		if err != nil {
+			log.Printf("課題を取得できませんでした: %v", err)
+		} else {
+			cur := make(map[string]string, len(pending))
+			fmt.Print(clearScreen)
+			fmt.Printf("未提出の課題: %d件 (%s に%dコースを更新, 次回は %s 後, %s)\n", countSubmittable(pending), now.Format("15:04:05"), synced, next.Round(time.Second), mode)
+			for _, n := range w.notices {
+				fmt.Println("! " + n)
+			}
+			fmt.Println()
+			for _, a := range pending {
+				key := assignmentKey(a)
+				cur[key] = a.fingerprint()
+				if old, ok := prev[key]; prev != nil && (!ok || old != cur[key]) {
+					fmt.Print(highlightOn + "* ")
+					printAssignment(os.Stdout, a, opts)
+					fmt.Print(colorReset)
+					continue
+				}
+				fmt.Print("  ")
+				printAssignment(os.Stdout, a, opts)
+			}
+			prev = cur
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next):
+		case id := <-changed:
+			// 通知のあったコースをすぐに取得し直す
+			w.sched.expire(id)
+		}
+	}
+}
+
+// watcher は watch と daemon が課題を取得するたびに行う通知と同期の状態です。
+// 課題の取得の方法だけが異なり、watch はAPIから直接、daemon はキャッシュを同期して取得します。
+type watcher struct {
+	cfg       *Config
+	srv       *classroom.Service
+	notifiers []Notifier
+	alerts    *alerter
+	sched     *courseScheduler
+	// regs はプッシュ通知の登録です。プッシュ通知を使わない場合は nil です。
+	regs *pushRegistrations
+	// metaDrive はメタデータを同期するDriveのクライアントです。meta.driveSync が false の場合は nil です。
+	metaDrive *drive.Service
+	// seen は新しい課題と締切の変更を通知するための前回の未提出の課題です。最初の取得の前は nil です。
+	seen    map[string]*Assignment
+	states  stateTracker
+	notices []string // 画面を描き直しても消えないように残しておくお知らせ
+}
+
+// fetchFunc は取得の時刻になったコース due の課題を取得します。courses は在籍中のすべてのコースです。
+type fetchFunc func(ctx context.Context, courses, due []*classroom.Course) ([]*Assignment, error)
+
+// newWatcher は通知の送信先、リマインダー、メタデータを同期するDriveのクライアントを用意します。
+func newWatcher(ctx context.Context, cfg *Config, srv *classroom.Service, sched *courseScheduler) (*watcher, error) {
+	alerts, err := newAlerter(cfg, remindersStateFile)
+	if err != nil {
+		return nil, err
+	}
+	w := &watcher{cfg: cfg, srv: srv, notifiers: newNotifiers(cfg), alerts: alerts, sched: sched}
+	if cfg.Meta.DriveSync {
+		if w.metaDrive, err = drive.NewService(ctx, option.WithHTTPClient(newHTTPClient(cfg))); err != nil {
+			return nil, fmt.Errorf("Driveクライアントを作成できませんでした: %w", err)
+		}
+	}
+	return w, nil
+}
+
+// tick は取得の時刻になったコースの課題を fetch で取得し、新しい課題・変更・リマインダー・お祝いを通知します。
+// 覚えているすべてのコースの課題と、取得したコースの数を返します。
+func (w *watcher) tick(ctx context.Context, fetch fetchFunc, now time.Time) ([]*Assignment, int, error) {
+	if w.metaDrive != nil {
+		if err := syncMetaDrive(ctx, w.cfg, w.metaDrive); err != nil {
+			log.Print(err)
+		}
+	}
+	// 静かな時間にためた通知は取得に失敗しても送る
+	defer flushQuiet(ctx, w.notifiers, now)
+	courses, err := listCourses(ctx, w.srv, w.cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	if w.regs != nil {
+		// 期限が近い登録を更新し、増えたコースを登録して、なくなったコースの登録を削除する
+		if err := w.regs.sync(ctx, courses, now); err != nil {
+			log.Print(err)
+		}
+	}
+	events, err := notifyEnrollmentChanges(ctx, w.cfg, w.notifiers, courses, now)
+	if err != nil {
+		log.Print(err)
+	}
+	for _, ev := range events {
+		w.notices = addNotice(w.notices, now, ev.Title)
+	}
+	// 取得の時刻になったコースだけを取得し、ほかのコースは前回の結果を使う
+	due := w.sched.due(courses, now)
+	if len(due) > 0 {
+		fetched, err := fetch(ctx, courses, due)
+		if err != nil {
+			w.sched.retry(due, now)
+			return nil, 0, err
+		}
+		w.sched.store(due, fetched, now)
+	}
+	items := w.sched.assignments(courses)
+
+	// 最初の取得ではすべてが新しい課題になるため、2回目の取得から通知する
+	changes, cur := diffPending(w.seen, items, now)
+	if w.seen != nil {
+		notifyChanges(ctx, w.notifiers, changes, loadSnoozes(w.cfg))
+	}
+	w.seen = cur
+	if w.alerts != nil {
+		w.alerts.deliver(ctx, w.notifiers, items, now)
+	}
+	transitions := w.states.update(items)
+	if cleared := weekCleared(items, transitions, now); w.cfg.Notify.Celebrate.Enabled && cleared != nil {
+		if err := celebrate(ctx, w.cfg, w.notifiers, cleared, now); err != nil {
+			log.Print(err)
+		}
+		w.notices = addNotice(w.notices, now, "今週締切の課題をすべて提出しました")
+	}
+	return items, len(due), nil
+}
+
+// assignmentKey は課題を一意に識別するキーを返します。
+func assignmentKey(a *Assignment) string {
+	return a.CourseWork.CourseId + "/" + a.CourseWork.Id
+}
+
+// fingerprint は課題の変更を検出するための値を返します。
+func (a *Assignment) fingerprint() string {
+	return a.CourseWork.UpdateTime + "|" + a.State()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/api/classroom/v1"
+	"google.golang.org/api/option"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAddNotice(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	var notices []string
+	for i := 0; i < maxWatchNotices+5; i++ {
+		notices = addNotice(notices, now, fmt.Sprint(i))
+	}
+	if len(notices) != maxWatchNotices {
+		t.Fatalf("お知らせ = %d件, want %d件", len(notices), maxWatchNotices)
+	}
+	// 新しいお知らせが残る
+	if want := "10/16 12:00 5"; notices[0] != want {
+		t.Errorf("最も古いお知らせ = %q, want %q", notices[0], want)
+	}
+	if want := fmt.Sprintf("10/16 12:00 %d", maxWatchNotices+4); notices[len(notices)-1] != want {
+		t.Errorf("最も新しいお知らせ = %q, want %q", notices[len(notices)-1], want)
+	}
+}
+
+func TestWatcherTick(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"courses": [{"id": "c1", "name": "数学"}]}`)
+	}))
+	defer api.Close()
+	ctx := context.Background()
+	srv, err := classroom.NewService(ctx, option.WithEndpoint(api.URL), option.WithHTTPClient(api.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{DataDir: t.TempDir()}
+	cfg.Polling.setDefaults()
+	w, err := newWatcher(ctx, cfg, srv, newCourseScheduler(&cfg.Polling, syncMode{}, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &recordingNotifier{}
+	w.notifiers = []Notifier{rec}
+
+	var works []*classroom.CourseWork
+	var fetches int
+	fetch := func(ctx context.Context, courses, due []*classroom.Course) ([]*Assignment, error) {
+		fetches++
+		var items []*Assignment
+		for _, c := range works {
+			items = append(items, &Assignment{Course: due[0], CourseWork: c})
+		}
+		return items, nil
+	}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	if _, synced, err := w.tick(ctx, fetch, now); err != nil || synced != 1 {
+		t.Fatalf("tick = %d, %v", synced, err)
+	}
+	// 取得の時刻になっていないコースは取得しない
+	works = []*classroom.CourseWork{{Id: "w1", CourseId: "c1", Title: "課題", WorkType: "ASSIGNMENT"}}
+	if _, synced, err := w.tick(ctx, fetch, now.Add(time.Minute)); err != nil || synced != 0 || fetches != 1 {
+		t.Fatalf("tick = %d, %v (取得 %d回)", synced, err, fetches)
+	}
+	items, _, err := w.tick(ctx, fetch, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("課題 = %d件, want 1件", len(items))
+	}
+	// 最初の取得の後に現れた課題を通知する
+	if got := eventTitles(rec.events); len(got) != 1 || got[0] != "新しい課題: 課題" {
+		t.Errorf("通知 = %v, want [新しい課題: 課題]", got)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WebhookConfig は通知をJSONでPOSTする送信先の設定です。
+type WebhookConfig struct {
+	URL string `json:"url"`
+	// Secret を設定した場合は、X-Classroom-Timestamp の値と本文を "." でつないだものの HMAC-SHA256 を
+	// X-Classroom-Signature: sha256=<16進数> として付けます。受け取る側はこれで送り主と改ざんの有無を確かめられます。
+	Secret string `json:"secret,omitempty"`
+	// Events は送るできごとの種類です。省略した場合は新しい課題 (created) と締切の変更 (due_changed) だけを送ります。
+	Events []string `json:"events,omitempty"`
+	// Retries は接続の失敗と 429・5xx の応答を再試行する回数です。省略した場合は3回で、0 にすると再試行しません。
+	Retries *int `json:"retries,omitempty"`
+}
+
+// defaultWebhookEvents は WebhookConfig.Events を省略したときに送るできごとです。
+var defaultWebhookEvents = []string{"created", "due_changed"}
+
+const (
+	defaultWebhookRetries = 3
+	webhookTimeout        = 10 * time.Second
+)
+
+// webhookPayload はWebhookで送る本文です。
+type webhookPayload struct {
+	Event string    `json:"event"`
+	Title string    `json:"title"`
+	Body  string    `json:"body"`
+	Time  time.Time `json:"time"`
+	// CourseWork は課題とそのコースです (list -template のデータと同じ)。課題に関係しないできごとでは省略します。
+	CourseWork *TemplateData `json:"courseWork,omitempty"`
+	// PreviousDue は due_changed の場合の変更前の締切です。締切がなかった場合は省略します。
+	PreviousDue *time.Time `json:"previousDue,omitempty"`
+}
+
+// webhookNotifier は通知をJSONでPOSTします。
+type webhookNotifier struct {
+	cfg     WebhookConfig
+	client  *http.Client
+	retries int
+}
+
+func newWebhookNotifier(cfg WebhookConfig) *webhookNotifier {
+	if len(cfg.Events) == 0 {
+		cfg.Events = defaultWebhookEvents
+	}
+	retries := defaultWebhookRetries
+	if cfg.Retries != nil {
+		retries = max(*cfg.Retries, 0)
+	}
+	return &webhookNotifier{cfg: cfg, client: &http.Client{Timeout: webhookTimeout}, retries: retries}
+}
+
+func (n *webhookNotifier) Name() string { return "webhook" }
+
+func (n *webhookNotifier) accepts(typ string) bool { return acceptsEvent(n.cfg.Events, typ) }
+
+func (n *webhookNotifier) Notify(ctx context.Context, ev *Event) error {
+	// doctor のテストの通知は送り先を確かめるためのものなので、種類の指定にかかわらず送る
+	if !n.accepts(ev.Type) {
+		return nil
+	}
+	p := &webhookPayload{Event: ev.Type, Title: ev.Title, Body: ev.Body, Time: ev.Time}
+	if ev.Assignment != nil {
+		p.CourseWork = newTemplateData(ev.Assignment, ev.Time)
+	}
+	if ev.Previous != nil {
+		if due, ok := ev.Previous.Due(); ok {
+			p.PreviousDue = &due
+		}
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	wait := time.Second
+	for attempt := 0; ; attempt++ {
+		retryable, err := n.post(ctx, body)
+		if err == nil || !retryable || attempt >= n.retries {
+			return err
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+		wait *= 2
+	}
+}
+
+// post は本文を1回送ります。2xx 以外の応答はエラーにします。
+// retryable は送り直せば届く見込みのある失敗 (接続の失敗と 429・5xx の応答) かどうかです。
+// 4xx の応答は送り直しても同じ結果になるため再試行しません。
+func (n *webhookNotifier) post(ctx context.Context, body []byte) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "classroom-api")
+	if n.cfg.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Classroom-Timestamp", ts)
+		req.Header.Set("X-Classroom-Signature", "sha256="+webhookSignature(n.cfg.Secret, ts, body))
+	}
+	res, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		retryable := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+		return retryable, fmt.Errorf("%s が %d を返しました: %s", n.cfg.URL, res.StatusCode, b)
+	}
+	return false, nil
+}
+
+// webhookSignature は timestamp と本文の HMAC-SHA256 を16進数で返します。
+func webhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
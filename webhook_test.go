@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookRetries(t *testing.T) {
+	zero, one := 0, 1
+	tests := []struct {
+		name    string
+		status  int
+		retries *int
+		want    int
+	}{
+		// 4xx は送り直しても同じ結果になるため再試行しない
+		{"400", http.StatusBadRequest, nil, 1},
+		{"retries 0", http.StatusServiceUnavailable, &zero, 1},
+		{"503", http.StatusServiceUnavailable, &one, 2},
+		{"429", http.StatusTooManyRequests, &one, 2},
+	}
+	for _, tt := range tests {
+		calls := 0
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(tt.status)
+		}))
+		n := newWebhookNotifier(WebhookConfig{URL: hook.URL, Retries: tt.retries})
+		err := n.Notify(context.Background(), &Event{Type: "created", Title: "新しい課題", Time: time.Now()})
+		hook.Close()
+		if err == nil {
+			t.Errorf("%s: エラーになりませんでした", tt.name)
+		}
+		if calls != tt.want {
+			t.Errorf("%s: %d回送りました, want %d", tt.name, calls, tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsxSheet はワークシート1枚分のセルです。
+type xlsxSheet struct {
+	name string
+	rows [][]xlsxCell
+}
+
+// xlsxCell はセルの値と書式です。
+type xlsxCell struct {
+	value string
+	// number が true の場合は数値として書き込みます。
+	number bool
+	style  int
+}
+
+// セルの書式 (styles.xml の cellXfs の番号) です。
+const (
+	xlsxStyleNormal = iota
+	xlsxStyleHeader
+	xlsxStyleOverdue
+)
+
+func runExportXLSX(ctx context.Context, cfg *Config, args []string) {
+	fs := flag.NewFlagSet("export xlsx", flag.ExitOnError)
+	out := fs.String("out", "", "出力するExcelファイル (必須)")
+	filter := addFilterFlags(fs)
+	fs.Parse(args)
+
+	if *out == "" {
+		log.Fatal("-out で出力するファイルを指定してください")
+	}
+	srv := newClassroomService(ctx, newHTTPClient(cfg))
+	items, err := loadAssignments(ctx, cfg, srv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("出力ファイルを作成できませんでした: %v", err)
+	}
+	defer f.Close()
+	if err := writeXLSX(f, xlsxSheets(cfg, filter.apply(items), time.Now())); err != nil {
+		log.Fatalf("Excelファイルを書き込めませんでした: %v", err)
+	}
+}
+
+// xlsxSheets は集計のシートとコースごとのシートを作ります。締切を過ぎた未提出の課題は赤く塗ります。
+func xlsxSheets(cfg *Config, items []*Assignment, now time.Time) []*xlsxSheet {
+	header := func(titles ...string) []xlsxCell {
+		row := make([]xlsxCell, len(titles))
+		for i, t := range titles {
+			row[i] = xlsxCell{value: t, style: xlsxStyleHeader}
+		}
+		return row
+	}
+	summary := &xlsxSheet{name: "集計", rows: [][]xlsxCell{header("コース", "課題数", "未提出", "締切切れ")}}
+	sheets := []*xlsxSheet{summary}
+	byCourse := map[string]*xlsxSheet{}
+	counts := map[string]*[3]int{}
+	for _, a := range items {
+		id := a.Course.Id
+		sh, ok := byCourse[id]
+		if !ok {
+			sh = &xlsxSheet{name: sheetTitle(cfg.courseAlias(id, a.Course.Name)), rows: [][]xlsxCell{header(csvHeader...)}}
+			byCourse[id] = sh
+			counts[id] = &[3]int{}
+			sheets = append(sheets, sh)
+		}
+		done := a.State() == "TURNED_IN" || a.State() == "RETURNED"
+		due, hasDue := a.EffectiveDue()
+		overdue := !done && hasDue && due.Before(now)
+		c := counts[id]
+		c[0]++
+		if !done {
+			c[1]++
+		}
+		style := xlsxStyleNormal
+		if overdue {
+			c[2]++
+			style = xlsxStyleOverdue
+		}
+		record := csvRecord(a)
+		row := make([]xlsxCell, len(record))
+		for i, v := range record {
+			// 配点と点数は数値として書き込み、Excelで集計できるようにする
+			numeric := csvHeader[i] == "配点" || csvHeader[i] == "点数"
+			row[i] = xlsxCell{value: v, number: numeric, style: style}
+		}
+		sh.rows = append(sh.rows, row)
+	}
+	for _, a := range items {
+		id := a.Course.Id
+		c, ok := counts[id]
+		if !ok {
+			continue
+		}
+		summary.rows = append(summary.rows, []xlsxCell{
+			{value: a.Course.Name},
+			{value: strconv.Itoa(c[0]), number: true},
+			{value: strconv.Itoa(c[1]), number: true},
+			{value: strconv.Itoa(c[2]), number: true},
+		})
+		delete(counts, id)
+	}
+	uniqueSheetNames(sheets)
+	return sheets
+}
+
+// uniqueSheetNames はシート名が重複しないように番号を付けます (Excelは大文字小文字を区別しません)。
+func uniqueSheetNames(sheets []*xlsxSheet) {
+	used := map[string]bool{}
+	for _, sh := range sheets {
+		name := []rune(sh.name)
+		if len(name) > 31 {
+			name = name[:31]
+		}
+		candidate := string(name)
+		for i := 2; used[strings.ToLower(candidate)] || candidate == ""; i++ {
+			suffix := fmt.Sprintf(" (%d)", i)
+			base := name
+			if len(base)+len(suffix) > 31 {
+				base = base[:31-len(suffix)]
+			}
+			candidate = string(base) + suffix
+		}
+		used[strings.ToLower(candidate)] = true
+		sh.name = candidate
+	}
+}
+
+// writeXLSX はシートをOffice Open XML (SpreadsheetML) 形式で書き出します。
+// 文字列はインライン文字列として書き込むため、共有文字列テーブルは作りません。
+func writeXLSX(w io.Writer, sheets []*xlsxSheet) error {
+	zw := zip.NewWriter(w)
+	write := func(name, content string) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, xml.Header+content)
+		return err
+	}
+
+	var overrides, wbSheets, rels strings.Builder
+	for i := range sheets {
+		n := i + 1
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&wbSheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheets[i].name), n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			overrides.String() + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + wbSheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() + `</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, p := range parts {
+		if err := write(p.name, p.content); err != nil {
+			return err
+		}
+	}
+	for i, sh := range sheets {
+		if err := write(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), xlsxSheetXML(sh)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// xlsxStyles は通常、見出し (太字・灰色)、締切切れ (薄い赤) の3つの書式です。
+const xlsxStyles = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="4"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFDDDDDD"/></patternFill></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFFDE2E2"/></patternFill></fill></fills>` +
+	`<borders count="1"><border/></borders>` +
+	`<cellStyleXfs count="1"><xf/></cellStyleXfs>` +
+	`<cellXfs count="3"><xf/><xf fontId="1" fillId="2" applyFont="1" applyFill="1"/><xf fillId="3" applyFill="1"/></cellXfs>` +
+	`</styleSheet>`
+
+func xlsxSheetXML(sh *xlsxSheet) string {
+	var b strings.Builder
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" state="frozen"/></sheetView></sheetViews>`)
+	b.WriteString(`<sheetData>`)
+	for r, row := range sh.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := xlsxColumn(c) + strconv.Itoa(r+1)
+			switch {
+			case cell.value == "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d"/>`, ref, cell.style)
+			case cell.number:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.style, cell.value)
+			default:
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, cell.style, xmlEscape(cell.value))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// xlsxColumn は0から始まる列番号を A, B, ..., Z, AA の形式にします。
+func xlsxColumn(n int) string {
+	s := ""
+	for n++; n > 0; n = (n - 1) / 26 {
+		s = string(rune('A'+(n-1)%26)) + s
+	}
+	return s
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}